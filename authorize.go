@@ -0,0 +1,147 @@
+package pkgsyms
+
+// Authorizer decides whether caller may use sym by returning nil or the
+// reason it may not.  The caller is whatever the host passes to LookupAs or
+// CallAs to identify who's asking, like a plugin's name or a network peer's
+// credentials; it's nil for Lookup and the functions built on it.
+type Authorizer func(sym Symbol, caller interface{}) error
+
+// SetAuthorizer installs the Authorizer consulted when a symbol is looked up
+// in the package by name, so that hosts exposing the package to semi-trusted
+// plugins or network endpoints can enforce per-symbol permissions.  A nil
+// Authorizer allows everything, which is the default.
+//
+// Only Lookup, LookupAs, LookupMany, CallAs, RangeAs and the functions built
+// on them are guarded.  Range and the views of the package's Symbols, like
+// Funcs and Vars, are for the host itself and see every symbol, so adapters
+// exposing a package must use the guarded methods.
+func (p *Package) SetAuthorizer(a Authorizer) {
+	p.authorizer.Store(a)
+}
+
+// Authorize reports whether the package's Authorizer allows caller to use
+// sym by returning nil or an Unauthorized error.  Adapters that describe
+// symbols they didn't look up with LookupAs, like listings, use it to decide
+// what to reveal.
+func (p *Package) Authorize(sym Symbol, caller interface{}) error {
+	return p.authorize(sym, caller)
+}
+
+// authorize sym for caller with the package's Authorizer, if any.
+func (p *Package) authorize(sym Symbol, caller interface{}) error {
+	a, _ := p.authorizer.Load().(Authorizer)
+	if a == nil {
+		return nil
+	}
+	if err := a(sym, caller); err != nil {
+		return Unauthorized{Pkg: p.Name, Sym: sym.Name(), Err: err}
+	}
+	return nil
+}
+
+// LookupAs looks up a symbol in the package like Lookup, on behalf of caller.
+// It returns an Unauthorized error if the package's Authorizer denies the
+// caller the symbol.
+func (p *Package) LookupAs(caller interface{}, name string) (Symbol, error) {
+	s, err := p.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.authorize(s, caller); err != nil {
+		return nil, err
+	}
+	return p.found(s), nil
+}
+
+// RangeAs calls f with each symbol in the package like Range, but skips the
+// symbols that the package's Authorizer denies caller.
+func (p *Package) RangeAs(caller interface{}, f func(s Symbol) bool) {
+	p.Range(func(s Symbol) bool {
+		if p.authorize(s, caller) != nil {
+			return true
+		}
+		return f(s)
+	})
+}
+
+// found prepares a symbol that was looked up and authorized to be returned.
+func (p *Package) found(s Symbol) Symbol {
+	s = p.countLookup(s)
+	switch sym := s.(type) {
+	case Func:
+		// Calls are logged with the package's name.
+		sym.pkg = p.Name
+		sym.strictNil = p.strictNil()
+		s = sym
+	case Var:
+		if p.strictNil() {
+			sym.pkg = p.Name
+			sym.strictNil = true
+			s = sym
+		}
+	}
+	return s
+}
+
+// LookupMany looks up several symbols in the package like Lookup, but locks
+// its symbols only once for all of them.  Providers are consulted at most once
+// for all of the names that aren't found.  Names of symbols that aren't found
+// or that the package's Authorizer denies are returned in missing, in the
+// order they were given.
+func (p *Package) LookupMany(names ...string) (found map[string]Symbol, missing []string) {
+	found, missing = p.Symbols.LookupMany(names...)
+	if len(missing) > 0 {
+		if consulted, err := p.provide(); err == nil && consulted {
+			var more map[string]Symbol
+			more, missing = p.Symbols.LookupMany(missing...)
+			for name, s := range more {
+				found[name] = s
+			}
+		}
+	}
+	denied := false
+	for _, name := range names {
+		s, ok := found[name]
+		if !ok {
+			continue
+		}
+		if err := p.authorize(s, nil); err != nil {
+			delete(found, name)
+			denied = true
+			continue
+		}
+		logEvent(EventLookupHit, p.Name, name)
+		found[name] = p.found(s)
+	}
+	if denied {
+		// Keep missing in the given order.
+		missing = missing[:0]
+		for _, name := range names {
+			if _, ok := found[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+	}
+	for _, name := range missing {
+		logEvent(EventLookupMiss, p.Name, name)
+	}
+	return found, missing
+}
+
+// CallAs looks up a Func, or a Callable variable, in the package on behalf of
+// caller and calls it with args.
+func (p *Package) CallAs(caller interface{}, name string, args ...interface{}) ([]interface{}, error) {
+	s, err := p.LookupAs(caller, name)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := AsFunc(s)
+	if !ok {
+		return nil, WrongKind{Pkg: p.Name, Sym: name, Want: FuncKind, Got: KindOf(s)}
+	}
+	if _, isVar := s.(Var); isVar {
+		f.pkg = p.Name
+		f.usage = p.counter(name)
+	}
+	return f.Call(args...)
+}
@@ -0,0 +1,163 @@
+package pkgsyms_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"plugin"
+	"sync"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/internal/testsyms"
+)
+
+var (
+	benchSym    pkgsyms.Symbol
+	benchResult interface{}
+	benchInt    int
+)
+
+func BenchmarkDirectCall(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchInt = testsyms.Add(i, 1)
+	}
+}
+
+func BenchmarkDirectVar(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		testsyms.Counter = i
+		benchInt = testsyms.Counter
+	}
+}
+
+func BenchmarkLookup(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		benchSym, _ = testsyms.Pkg.Lookup("Add")
+	}
+}
+
+func BenchmarkFrozenLookup(b *testing.B) {
+	frozen := testsyms.Pkg.Frozen()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s, _ := frozen.Lookup("Add")
+			_ = s
+		}
+	})
+}
+
+func BenchmarkLookupParallel(b *testing.B) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s, _ := testsyms.Pkg.Lookup("Add")
+			_ = s
+		}
+	})
+}
+
+func BenchmarkGet(b *testing.B) {
+	s, err := testsyms.Pkg.Lookup("Add")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		benchResult = s.Get()
+	}
+}
+
+func BenchmarkFuncCall(b *testing.B) {
+	f, err := testsyms.Pkg.LookupFunc("Add")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		benchResult, _ = f.Call(i, 1)
+	}
+}
+
+func BenchmarkFuncAssertedCall(b *testing.B) {
+	f, err := testsyms.Pkg.LookupFunc("Add")
+	if err != nil {
+		b.Fatal(err)
+	}
+	add := f.Get().(func(int, int) int)
+	for i := 0; i < b.N; i++ {
+		benchInt = add(i, 1)
+	}
+}
+
+func BenchmarkVarSetGet(b *testing.B) {
+	v, err := testsyms.Pkg.LookupVar("Counter")
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < b.N; i++ {
+		v.Set(i)
+		benchResult = v.Get()
+	}
+}
+
+var testPlugin struct {
+	once sync.Once
+	dir  string
+	p    *plugin.Plugin
+	err  error
+}
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if testPlugin.dir != "" {
+		os.RemoveAll(testPlugin.dir)
+	}
+	os.Exit(code)
+}
+
+// openTestPlugin builds and opens the testsyms plugin or skips the benchmark
+// if plugins aren't supported.  A plugin can only be opened once per
+// process, so it's shared by all the benchmarks.
+func openTestPlugin(b *testing.B) *plugin.Plugin {
+	b.Helper()
+	testPlugin.once.Do(func() {
+		testPlugin.dir, testPlugin.err = os.MkdirTemp("", "pkgsyms")
+		if testPlugin.err != nil {
+			return
+		}
+		filename := filepath.Join(testPlugin.dir, "testsyms.so")
+		cmd := exec.Command(
+			"go", "build", "-buildmode=plugin", "-o", filename,
+			"./internal/testsyms/plugin")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			testPlugin.err = fmt.Errorf(
+				"failed to build plugin: %w\n%s", err, out)
+			return
+		}
+		testPlugin.p, testPlugin.err = plugin.Open(filename)
+	})
+	if testPlugin.err != nil {
+		b.Skip(testPlugin.err)
+	}
+	return testPlugin.p
+}
+
+func BenchmarkPluginLookup(b *testing.B) {
+	p := openTestPlugin(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchResult, _ = p.Lookup("Add")
+	}
+}
+
+func BenchmarkPluginCall(b *testing.B) {
+	p := openTestPlugin(b)
+	s, err := p.Lookup("Add")
+	if err != nil {
+		b.Fatal(err)
+	}
+	add := s.(func(int, int) int)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchInt = add(i, 1)
+	}
+}
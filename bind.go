@@ -0,0 +1,172 @@
+package pkgsyms
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// BindFlags defines a flag in fs for each Var in p whose type is a bool,
+// number, string or time.Duration, that isn't read-only and that is accepted
+// by all of the filters.  The flag is named after the Var and the Var's
+// current value is the flag's default.  The usage string is the Var's
+// documentation, if it has any.  Vars without a variable to point to are
+// skipped.  Vars whose names are already defined in fs are skipped too and
+// returned in an error after the other flags are defined.
+func BindFlags(fs *flag.FlagSet, p *Package, filters ...func(v Var) bool) error {
+	var dups []string
+	p.Range(func(s Symbol) bool {
+		v, ok := bindable(s)
+		if !ok {
+			return true
+		}
+		for _, f := range filters {
+			if !f(v) {
+				return true
+			}
+		}
+		usage := fmt.Sprintf("sets %s.%s", p.Name, v.name)
+		if doc := Doc(s); doc != "" {
+			usage = doc
+		}
+		if fs.Lookup(v.name) != nil {
+			dups = append(dups, v.name)
+			return true
+		}
+		fs.Var(flagValue{v}, v.name, usage)
+		return true
+	})
+	if len(dups) > 0 {
+		return fmt.Errorf(
+			"package %q: flags already defined: %s", p.Name, strings.Join(dups, ", "))
+	}
+	return nil
+}
+
+// BindEnv sets each Var in p that isn't read-only and whose type is a bool,
+// number, string or time.Duration from the environment variable named
+// PREFIX_NAME, where PREFIX and NAME are the upper-cased prefix and Var name.
+// If prefix is empty, the environment variable is just NAME.  Unset
+// variables and Vars without a variable to point to are left alone.  Values
+// that can't be parsed are collected and returned as EnvErrors after all of
+// the other Vars have been set.
+func BindEnv(p *Package, prefix string) error {
+	var errs EnvErrors
+	p.Range(func(s Symbol) bool {
+		v, ok := bindable(s)
+		if !ok {
+			return true
+		}
+		name := strings.ToUpper(v.name)
+		if prefix != "" {
+			name = strings.ToUpper(prefix) + "_" + name
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return true
+		}
+		if err := parseInto(v.elem(), value); err != nil {
+			errs = append(errs, EnvError{Name: name, Value: value, Err: err})
+		}
+		return true
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// bindable gets s as a Var if it can be bound to a flag or environment
+// variable: It isn't read-only, it points to a variable and the variable's
+// type is basic.
+func bindable(s Symbol) (Var, bool) {
+	v, ok := s.(Var)
+	if !ok || v.readOnly || v.addr == nil || reflect.ValueOf(v.addr).IsNil() {
+		return Var{}, false
+	}
+	return v, isBasic(v.elem().Type())
+}
+
+// flagValue adapts a Var to the flag.Value interface.
+type flagValue struct {
+	v Var
+}
+
+func (f flagValue) String() string {
+	if f.v.addr == nil {
+		return ""
+	}
+	return fmt.Sprint(f.v.Get())
+}
+
+func (f flagValue) Set(s string) error { return parseInto(f.v.elem(), s) }
+
+func (f flagValue) IsBoolFlag() bool {
+	return f.v.elem().Kind() == reflect.Bool
+}
+
+// elem gets the addressable reflect.Value of the variable.
+func (v Var) elem() reflect.Value { return reflect.ValueOf(v.addr).Elem() }
+
+// isBasic reports whether values of t can be parsed from strings by
+// parseInto.
+func isBasic(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// parseInto parses s according to the kind of rv and stores the result in rv.
+func parseInto(rv reflect.Value, s string) error {
+	t := rv.Type()
+	if t == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		rv.SetInt(int64(d))
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, t.Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 0, t.Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	default:
+		return fmt.Errorf("cannot parse %v from a string", t)
+	}
+	return nil
+}
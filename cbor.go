@@ -0,0 +1,224 @@
+package pkgsyms
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// CBOR major types used by manifests.
+const (
+	cborUint  = 0
+	cborText  = 3
+	cborArray = 4
+	cborMap   = 5
+)
+
+// MarshalCBOR encodes the manifest in CBOR (RFC 8949), which is more compact
+// than JSON for shipping manifests to constrained devices.  The encoding has
+// the same structure and keys as the JSON encoding and is deterministic: Map
+// keys are sorted.
+func (m Manifest) MarshalCBOR() ([]byte, error) {
+	var e cborEncoder
+	n := 0
+	if len(m.Consts) > 0 {
+		n++
+	}
+	if len(m.Docs) > 0 {
+		n++
+	}
+	e.head(cborMap, n)
+	if len(m.Consts) > 0 {
+		e.text("consts")
+		e.head(cborArray, len(m.Consts))
+		for _, mc := range m.Consts {
+			n := 3
+			if mc.Group != "" {
+				n++
+			}
+			e.head(cborMap, n)
+			e.text("name")
+			e.text(mc.Name)
+			e.text("type")
+			e.text(mc.Type)
+			e.text("value")
+			e.text(mc.Value)
+			if mc.Group != "" {
+				e.text("group")
+				e.text(mc.Group)
+			}
+		}
+	}
+	if len(m.Docs) > 0 {
+		names := make([]string, 0, len(m.Docs))
+		for name := range m.Docs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		e.text("docs")
+		e.head(cborMap, len(names))
+		for _, name := range names {
+			e.text(name)
+			e.text(m.Docs[name])
+		}
+	}
+	return e.buf, nil
+}
+
+// UnmarshalCBOR decodes a manifest encoded by MarshalCBOR.  Unknown keys are
+// ignored like they are by encoding/json.
+func (m *Manifest) UnmarshalCBOR(data []byte) error {
+	d := cborDecoder{data: data}
+	v, err := d.value()
+	if err != nil {
+		return err
+	}
+	if d.pos != len(data) {
+		return errors.New("cbor: trailing data after manifest")
+	}
+	root, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("cbor: manifest is a %T, not a map", v)
+	}
+	*m = Manifest{}
+	if consts, ok := root["consts"].([]interface{}); ok {
+		for _, c := range consts {
+			fields, ok := c.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("cbor: manifest constant is a %T, not a map", c)
+			}
+			var mc ManifestConst
+			mc.Name, _ = fields["name"].(string)
+			mc.Type, _ = fields["type"].(string)
+			mc.Value, _ = fields["value"].(string)
+			mc.Group, _ = fields["group"].(string)
+			m.Consts = append(m.Consts, mc)
+		}
+	}
+	if docs, ok := root["docs"].(map[string]interface{}); ok {
+		m.Docs = make(map[string]string, len(docs))
+		for name, doc := range docs {
+			m.Docs[name], _ = doc.(string)
+		}
+	}
+	return nil
+}
+
+// isCBORMap reports whether data starts with a CBOR map, which tells CBOR
+// manifests from JSON ones.
+func isCBORMap(data []byte) bool {
+	return len(data) > 0 && data[0]>>5 == cborMap
+}
+
+type cborEncoder struct {
+	buf []byte
+}
+
+// head appends the initial bytes of a data item of the major type with the
+// argument n.
+func (e *cborEncoder) head(major byte, n int) {
+	m := major << 5
+	switch u := uint64(n); {
+	case u < 24:
+		e.buf = append(e.buf, m|byte(u))
+	case u <= 0xff:
+		e.buf = append(e.buf, m|24, byte(u))
+	case u <= 0xffff:
+		e.buf = binary.BigEndian.AppendUint16(append(e.buf, m|25), uint16(u))
+	case u <= 0xffffffff:
+		e.buf = binary.BigEndian.AppendUint32(append(e.buf, m|26), uint32(u))
+	default:
+		e.buf = binary.BigEndian.AppendUint64(append(e.buf, m|27), u)
+	}
+}
+
+func (e *cborEncoder) text(s string) {
+	e.head(cborText, len(s))
+	e.buf = append(e.buf, s...)
+}
+
+// cborDecoder decodes the subset of CBOR that manifests use: unsigned
+// integers, text strings, arrays and maps with text keys, all with definite
+// lengths.
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) head() (major byte, n uint64, err error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, errors.New("cbor: unexpected end of data")
+	}
+	b := d.data[d.pos]
+	d.pos++
+	major, info := b>>5, b&0x1f
+	size := 0
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("cbor: unsupported additional information %d", info)
+	}
+	if len(d.data)-d.pos < size {
+		return 0, 0, errors.New("cbor: unexpected end of data")
+	}
+	for _, c := range d.data[d.pos : d.pos+size] {
+		n = n<<8 | uint64(c)
+	}
+	d.pos += size
+	return major, n, nil
+}
+
+func (d *cborDecoder) value() (interface{}, error) {
+	major, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	// Every item takes at least a byte, so longer lengths are corrupt
+	// and would only make the decoder allocate too much.
+	if major != cborUint && n > uint64(len(d.data)-d.pos) {
+		return nil, errors.New("cbor: length exceeds the data")
+	}
+	switch major {
+	case cborUint:
+		return n, nil
+	case cborText:
+		s := string(d.data[d.pos : d.pos+int(n)])
+		d.pos += int(n)
+		return s, nil
+	case cborArray:
+		a := make([]interface{}, n)
+		for i := range a {
+			if a[i], err = d.value(); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case cborMap:
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("cbor: map key is a %T, not a string", k)
+			}
+			if m[key], err = d.value(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported major type %d", major)
+}
@@ -0,0 +1,42 @@
+package pkgsyms
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// Checksum computes a hash of the kinds and names of the symbols registered
+// in the package, except for those added with AddConditional.  The pkgsyms
+// command records the same hash in the files it generates so that hosts can
+// detect when a package was rebuilt with a different set of symbols than it
+// was generated with.
+func (p *Package) Checksum() string {
+	var lines []string
+	p.Range(func(s Symbol) bool {
+		if p.isConditional(s.Name()) {
+			return true
+		}
+		lines = append(lines, KindOf(s).String()+" "+s.Name())
+		return true
+	})
+	return ChecksumOf(lines)
+}
+
+// ChecksumOf computes the checksum of symbols described by lines of the form
+// "Kind Name".  The order of the lines doesn't matter.
+func ChecksumOf(lines []string) string {
+	sorted := append([]string(nil), lines...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify that the package's checksum is the expected checksum.
+func (p *Package) Verify(expected string) error {
+	if got := p.Checksum(); got != expected {
+		return ChecksumMismatch{Pkg: p.Name, Want: expected, Got: got}
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package pkgsyms
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+// UnmarshalTyped looks up the Type named typeName in the package, creates a
+// new value of that type and unmarshals the JSON data into it.  The result is
+// a pointer to the new value so that it satisfies interfaces implemented with
+// pointer receivers.
+func (p *Package) UnmarshalTyped(typeName string, data []byte) (interface{}, error) {
+	t, err := p.LookupType(typeName)
+	if err != nil {
+		return nil, err
+	}
+	pv := reflect.New(t.rtyp)
+	if err := json.Unmarshal(data, pv.Interface()); err != nil {
+		return nil, fmt.Errorf(
+			"package %q: failed to unmarshal %q: %w",
+			p.Name, typeName, err)
+	}
+	return pv.Interface(), nil
+}
+
+// errorType is the type of error.
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// CallJSON calls the function with arguments unmarshaled from a JSON array
+// into the function's parameter types and marshals its results into a JSON
+// array.  If the function's last result is an error, it isn't marshaled but
+// returned if it isn't nil, so that functions can be exposed over HTTP or
+// RPC as-is.  Arguments that the function can't be called with are reported
+// with an ArgumentError.
+func (f Func) CallJSON(argsJSON []byte) ([]byte, error) {
+	ft := reflect.TypeOf(f.fval)
+	if ft == nil || ft.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s: cannot call %T", f.name, f.fval)
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(argsJSON, &raw); err != nil {
+		return nil, ArgumentError{Sym: f.name, Err: fmt.Errorf(
+			"arguments must be a JSON array: %w", err)}
+	}
+	if err := checkArity(f.name, ft, len(raw)); err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, len(raw))
+	for i, r := range raw {
+		pv := reflect.New(paramType(ft, i))
+		if err := json.Unmarshal(r, pv.Interface()); err != nil {
+			return nil, ArgumentError{Sym: f.name, Err: fmt.Errorf(
+				"argument %d: %w", i, err)}
+		}
+		args[i] = pv.Elem().Interface()
+	}
+	results, err := f.Call(args...)
+	if err != nil {
+		return nil, err
+	}
+	if k := ft.NumOut(); k > 0 && ft.Out(k-1) == errorType {
+		if err, _ := results[k-1].(error); err != nil {
+			return nil, err
+		}
+		results = results[:k-1]
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to marshal results: %w", f.name, err)
+	}
+	return data, nil
+}
+
+// SaveState writes the values of the package's variables to w as a JSON
+// object mapping their names to their values, so that a host exposing
+// tweakable variables can persist operators' changes across restarts with
+// LoadState.  Read-only variables and variables whose values don't survive
+// being encoded in JSON and decoded again, like functions, interfaces and
+// structs without exported fields such as *log.Logger, aren't saved.
+func (p *Package) SaveState(w io.Writer) error {
+	state, err := p.state()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(state)
+}
+
+// state gets the values of the package's variables for SaveState.
+func (p *Package) state() (map[string]json.RawMessage, error) {
+	state := make(map[string]json.RawMessage)
+	for _, s := range p.snapshot() {
+		v, ok := s.(Var)
+		if !ok || v.readOnly || !roundTrips(v.Type(), nil) {
+			continue
+		}
+		data, err := json.Marshal(v.Get())
+		if err != nil {
+			var ute *json.UnsupportedTypeError
+			if errors.As(err, &ute) {
+				continue
+			}
+			return nil, fmt.Errorf("%s: %w", ID(p.Name, v.name), err)
+		}
+		state[v.name] = data
+	}
+	return state, nil
+}
+
+// LoadState sets the package's variables to the values written by
+// SaveState.  Values of variables that no longer exist or that SaveState
+// doesn't save, like read-only ones, are ignored so that state saved by an
+// older build can still be loaded.  Every value is decoded before any variable is set, so the
+// variables are left unchanged if the state can't be loaded.
+func (p *Package) LoadState(r io.Reader) error {
+	var state map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf("package %q: failed to load state: %w", p.Name, err)
+	}
+	values, err := p.decodeState(state)
+	if err != nil {
+		return err
+	}
+	return setState(values)
+}
+
+// stateValue is a decoded value of a variable in a saved state.
+type stateValue struct {
+	v   Var
+	val interface{}
+}
+
+// decodeState decodes the values of the package's variables in state, in
+// order of their names.
+func (p *Package) decodeState(state map[string]json.RawMessage) ([]stateValue, error) {
+	names := make([]string, 0, len(state))
+	for name := range state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var values []stateValue
+	for _, name := range names {
+		s, err := p.Symbols.Lookup(name)
+		if err != nil {
+			continue
+		}
+		v, ok := s.(Var)
+		if !ok || v.readOnly || !roundTrips(v.Type(), nil) {
+			continue
+		}
+		pv := reflect.New(v.Type())
+		if err := json.Unmarshal(state[name], pv.Interface()); err != nil {
+			return nil, fmt.Errorf("%s: %w", ID(p.Name, name), err)
+		}
+		values = append(values, stateValue{v, pv.Elem().Interface()})
+	}
+	return values, nil
+}
+
+// setState sets the variables to their decoded values.
+func setState(values []stateValue) error {
+	for _, sv := range values {
+		if err := sv.v.SetE(sv.val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// roundTrips reports whether values of t can be encoded in JSON and decoded
+// again into an equivalent value.  seen holds the struct types being checked
+// so that recursive types terminate.
+func roundTrips(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if t == timeType || reflect.PointerTo(t).Implements(jsonUnmarshalerType) ||
+		reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return roundTrips(t.Elem(), seen)
+	case reflect.Map:
+		return roundTrips(t.Key(), seen) && roundTrips(t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			return true
+		}
+		if seen == nil {
+			seen = make(map[reflect.Type]bool)
+		}
+		seen[t] = true
+		exported := false
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("json") == "-" {
+				continue
+			}
+			if !roundTrips(f.Type, seen) {
+				return false
+			}
+			exported = true
+		}
+		return exported || t.NumField() == 0
+	}
+	return false
+}
+
+// SaveState writes the values of the variables of every registered package
+// to w as a JSON object mapping package names to the objects written by
+// (*Package).SaveState.  Packages without variables to save are left out.
+func SaveState(w io.Writer) error {
+	state := make(map[string]map[string]json.RawMessage)
+	for _, p := range Packages() {
+		ps, err := p.state()
+		if err != nil {
+			return err
+		}
+		if len(ps) > 0 {
+			state[p.Name] = ps
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(state)
+}
+
+// LoadState sets the variables of the registered packages to the values
+// written by SaveState.  Packages that aren't registered are ignored.  Like
+// (*Package).LoadState, no variable is set unless all of them can be.
+func LoadState(r io.Reader) error {
+	var state map[string]map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	var values []stateValue
+	for _, p := range Packages() {
+		if ps, ok := state[p.Name]; ok {
+			pv, err := p.decodeState(ps)
+			if err != nil {
+				return err
+			}
+			values = append(values, pv...)
+		}
+	}
+	return setState(values)
+}
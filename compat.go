@@ -0,0 +1,15 @@
+package pkgsyms
+
+// This file keeps what files generated for older API versions use, so that
+// they keep compiling without being regenerated.  See MinAPIVersion.
+//
+// No API that generated files call has changed yet:  Files generated before
+// APIVersion 1 only call Of, (*Package).Add and the Make functions, whose
+// signatures are the same, so the only thing kept here is the version
+// marker that files generated since reference.  Shims for changed APIs go
+// here when there are any.
+
+// SupportsAPIVersion1 is referenced by files generated for APIVersion 1 so
+// that they fail to compile against versions of this package that predate
+// it instead of failing at run time.
+const SupportsAPIVersion1 = true
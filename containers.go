@@ -0,0 +1,192 @@
+package pkgsyms
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Guarded returns a copy of a variable of map or channel type whose value is
+// guarded by a lock, so that it can be used concurrently through its MapVar
+// or ChanVar while it's set.  Get returns a copy of a guarded map.  The
+// pkgsyms command's -safe-containers flag registers variables of map and
+// channel types guarded.
+func (v Var) Guarded() Var {
+	switch v.Type().Kind() {
+	case reflect.Map, reflect.Chan:
+	default:
+		panic(fmt.Errorf("%s: cannot guard %v", v.name, v.Type()))
+	}
+	v.guard = new(sync.RWMutex)
+	return v
+}
+
+// load gets the value of the variable, under its guard if it has one.
+func (v Var) load() interface{} {
+	if v.guard == nil {
+		return getVar(v.addr)
+	}
+	v.guard.RLock()
+	defer v.guard.RUnlock()
+	m := v.elem()
+	if m.Kind() != reflect.Map || m.IsNil() {
+		return m.Interface()
+	}
+	c := reflect.MakeMapWithSize(m.Type(), m.Len())
+	for it := m.MapRange(); it.Next(); {
+		c.SetMapIndex(it.Key(), it.Value())
+	}
+	return c.Interface()
+}
+
+// valueOf converts x to a reflect.Value of type t.  nil is t's zero value.
+func valueOf(x interface{}, t reflect.Type) (reflect.Value, error) {
+	if x == nil {
+		return reflect.Zero(t), nil
+	}
+	return convertValue(reflect.ValueOf(x), t)
+}
+
+// MapVar accesses the entries of a Guarded variable of map type under its
+// lock.  The lock only guards access through the Var and its MapVar; code
+// in the variable's own package that uses the map directly isn't guarded.
+type MapVar struct {
+	v Var
+}
+
+// Map gets the MapVar of a Guarded variable of map type.
+func (v Var) Map() (MapVar, bool) {
+	if v.guard == nil || v.Type().Kind() != reflect.Map {
+		return MapVar{}, false
+	}
+	return MapVar{v}, true
+}
+
+// Get the value of the map's entry with the key.
+func (m MapVar) Get(key interface{}) (interface{}, bool) {
+	m.v.guard.RLock()
+	defer m.v.guard.RUnlock()
+	k, err := valueOf(key, m.v.Type().Key())
+	if err != nil {
+		return nil, false
+	}
+	e := m.v.elem().MapIndex(k)
+	if !e.IsValid() {
+		return nil, false
+	}
+	return e.Interface(), true
+}
+
+// Put sets the map's entry with the key to value.  The map is made if it's
+// nil.  Read-only variables return a ReadOnlyError.
+func (m MapVar) Put(key, value interface{}) error {
+	if m.v.readOnly {
+		return ReadOnlyError{Sym: m.v.name}
+	}
+	t := m.v.Type()
+	k, err := valueOf(key, t.Key())
+	if err != nil {
+		return fmt.Errorf("%s: key: %w", m.v.name, err)
+	}
+	e, err := valueOf(value, t.Elem())
+	if err != nil {
+		return fmt.Errorf("%s: value: %w", m.v.name, err)
+	}
+	m.v.guard.Lock()
+	defer m.v.guard.Unlock()
+	mv := m.v.elem()
+	if mv.IsNil() {
+		mv.Set(reflect.MakeMap(t))
+	}
+	mv.SetMapIndex(k, e)
+	return nil
+}
+
+// Delete the map's entry with the key.  Read-only variables return a
+// ReadOnlyError.
+func (m MapVar) Delete(key interface{}) error {
+	if m.v.readOnly {
+		return ReadOnlyError{Sym: m.v.name}
+	}
+	k, err := valueOf(key, m.v.Type().Key())
+	if err != nil {
+		return fmt.Errorf("%s: key: %w", m.v.name, err)
+	}
+	m.v.guard.Lock()
+	defer m.v.guard.Unlock()
+	if mv := m.v.elem(); !mv.IsNil() {
+		mv.SetMapIndex(k, reflect.Value{})
+	}
+	return nil
+}
+
+// Len gets the number of entries in the map.
+func (m MapVar) Len() int {
+	m.v.guard.RLock()
+	defer m.v.guard.RUnlock()
+	return m.v.elem().Len()
+}
+
+// ChanVar sends to and receives from the channel of a Guarded variable of
+// channel type.  The channel is read under the variable's lock, so sends and
+// receives see either the old or the new channel while the variable is set,
+// but the lock isn't held while they wait.  Code in the variable's own
+// package that sets the variable directly isn't guarded.
+type ChanVar struct {
+	v Var
+}
+
+// Chan gets the ChanVar of a Guarded variable of channel type.
+func (v Var) Chan() (ChanVar, bool) {
+	if v.guard == nil || v.Type().Kind() != reflect.Chan {
+		return ChanVar{}, false
+	}
+	return ChanVar{v}, true
+}
+
+// channel gets the variable's current channel.
+func (c ChanVar) channel() reflect.Value {
+	c.v.guard.RLock()
+	defer c.v.guard.RUnlock()
+	return c.v.elem()
+}
+
+// Send value on the channel, waiting until it's received or ctx is done.
+// Sending on a closed channel returns an InvokeError.
+func (c ChanVar) Send(ctx context.Context, value interface{}) (err error) {
+	ch := c.channel()
+	if ch.Type().ChanDir()&reflect.SendDir == 0 {
+		return fmt.Errorf("%s: cannot send on %v", c.v.name, ch.Type())
+	}
+	e, err := valueOf(value, ch.Type().Elem())
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.v.name, err)
+	}
+	defer recoverInvoke(c.v.name, &err)
+	chosen, _, _ := reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: ch, Send: e},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	})
+	if chosen == 1 {
+		return fmt.Errorf("%s: %w", c.v.name, ctx.Err())
+	}
+	return nil
+}
+
+// Recv receives a value from the channel, waiting until one is sent or ctx
+// is done.  ok is false if the channel is closed.
+func (c ChanVar) Recv(ctx context.Context) (value interface{}, ok bool, err error) {
+	ch := c.channel()
+	if ch.Type().ChanDir()&reflect.RecvDir == 0 {
+		return nil, false, fmt.Errorf("%s: cannot receive from %v", c.v.name, ch.Type())
+	}
+	chosen, v, ok := reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	})
+	if chosen == 1 {
+		return nil, false, fmt.Errorf("%s: %w", c.v.name, ctx.Err())
+	}
+	return v.Interface(), ok, nil
+}
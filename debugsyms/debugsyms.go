@@ -0,0 +1,165 @@
+// Package debugsyms provides a pkgsyms.Provider that reads the running
+// binary's symbol table to find functions that were never registered with
+// pkgsyms.  The lookups are best-effort: binaries built with -ldflags=-s have
+// no symbol table, functions the linker eliminated can't be found and,
+// because the symbol table doesn't record signatures, the functions can't be
+// called.  So that they aren't mistaken for callable Funcs, the functions are
+// provided as read-only Var symbols holding FuncInfo values describing them.
+package debugsyms
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// FuncInfo describes a function found in the symbol table.
+type FuncInfo struct {
+	// Name is the fully qualified name of the function, e.g.
+	// "github.com/skillian/pkgsyms.Of".
+	Name string
+
+	// Entry is the address of the function's entry point in the running
+	// binary.
+	Entry uintptr
+}
+
+// Func gets the runtime's information about the function.
+func (fi FuncInfo) Func() *runtime.Func { return runtime.FuncForPC(fi.Entry) }
+
+// Provider provides FuncInfo Var symbols from the symbol table of the running
+// binary.
+// The symbol table is read the first time a package is provided.
+type Provider struct {
+	once  sync.Once
+	funcs map[string][]pkgsyms.Symbol
+	err   error
+}
+
+// New creates a Provider.  Use pkgsyms.RegisterProvider to install it.
+func New() *Provider { return &Provider{} }
+
+// Provide the exported functions of the package from the symbol table.  If
+// the symbol table can't be read, no symbols are provided; Err reports why.
+func (pr *Provider) Provide(pkg string) ([]pkgsyms.Symbol, error) {
+	pr.once.Do(pr.load)
+	return pr.funcs[pkg], nil
+}
+
+// Err reports why the symbol table couldn't be read, if it couldn't.
+func (pr *Provider) Err() error {
+	pr.once.Do(pr.load)
+	return pr.err
+}
+
+// anchor is looked up in the symbol table to compute how far the binary was
+// relocated when it was loaded.
+func anchor() {}
+
+func (pr *Provider) load() {
+	pr.funcs = make(map[string][]pkgsyms.Symbol)
+	exe, err := os.Executable()
+	if err != nil {
+		pr.err = err
+		return
+	}
+	syms, err := readSymbols(exe)
+	if err != nil {
+		pr.err = fmt.Errorf("failed to read symbols of %q: %w", exe, err)
+		return
+	}
+	anchorName := reflect.TypeOf(FuncInfo{}).PkgPath() + ".anchor"
+	anchorAddr, ok := syms[anchorName]
+	if !ok {
+		pr.err = fmt.Errorf("%q has no symbol table", exe)
+		return
+	}
+	slide := reflect.ValueOf(anchor).Pointer() - anchorAddr
+	for name, addr := range syms {
+		pkg, fn, ok := splitFuncName(name)
+		if !ok {
+			continue
+		}
+		fi := &FuncInfo{Name: name, Entry: addr + slide}
+		pr.funcs[pkg] = append(pr.funcs[pkg], pkgsyms.MakeVar(fn, fi).ReadOnly())
+	}
+}
+
+// splitFuncName splits a symbol name like "example.com/pkg.Func" into its
+// package path and function name.  Methods, closures, generic
+// instantiations and unexported functions are rejected.
+func splitFuncName(name string) (pkg, fn string, ok bool) {
+	i := strings.LastIndexByte(name, '/')
+	j := strings.IndexByte(name[i+1:], '.')
+	if j < 0 {
+		return "", "", false
+	}
+	j += i + 1
+	pkg, fn = name[:j], name[j+1:]
+	if strings.ContainsAny(fn, ".[]()*") || strings.Contains(pkg, "..") {
+		return "", "", false
+	}
+	r, _ := utf8.DecodeRuneInString(fn)
+	if !unicode.IsUpper(r) {
+		return "", "", false
+	}
+	return pkg, fn, true
+}
+
+// readSymbols reads the function symbols from an ELF, Mach-O or PE file.
+func readSymbols(filename string) (map[string]uintptr, error) {
+	syms := make(map[string]uintptr)
+	if f, err := elf.Open(filename); err == nil {
+		defer f.Close()
+		ss, err := f.Symbols()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range ss {
+			if elf.ST_TYPE(s.Info) == elf.STT_FUNC {
+				syms[s.Name] = uintptr(s.Value)
+			}
+		}
+		return syms, nil
+	}
+	if f, err := macho.Open(filename); err == nil {
+		defer f.Close()
+		if f.Symtab == nil {
+			return nil, fmt.Errorf("no symbol table")
+		}
+		for _, s := range f.Symtab.Syms {
+			syms[s.Name] = uintptr(s.Value)
+		}
+		return syms, nil
+	}
+	f, err := pe.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("unrecognized executable format")
+	}
+	defer f.Close()
+	var imageBase uint64
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		imageBase = uint64(oh.ImageBase)
+	case *pe.OptionalHeader64:
+		imageBase = oh.ImageBase
+	}
+	for _, s := range f.Symbols {
+		if s.SectionNumber <= 0 || int(s.SectionNumber) > len(f.Sections) {
+			continue
+		}
+		sect := f.Sections[s.SectionNumber-1]
+		syms[s.Name] = uintptr(imageBase + uint64(sect.VirtualAddress) + uint64(s.Value))
+	}
+	return syms, nil
+}
@@ -0,0 +1,34 @@
+package debugsyms_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/debugsyms"
+)
+
+func TestProvider(t *testing.T) {
+	pr := debugsyms.New()
+	if err := pr.Err(); err != nil {
+		t.Skip(err)
+	}
+	pkgsyms.RegisterProvider(pr)
+	p, err := pkgsyms.Lookup("strings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.LookupFunc("Join"); !errors.Is(err, pkgsyms.ErrWrongKind) {
+		t.Fatalf("expected Join not to be a Func, got %v", err)
+	}
+	v, err := p.LookupVar("Join")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fi := v.Get().(debugsyms.FuncInfo)
+	if want := reflect.ValueOf(strings.Join).Pointer(); fi.Entry != want {
+		t.Fatalf("expected entry %#x, not %#x", want, fi.Entry)
+	}
+}
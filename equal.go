@@ -0,0 +1,96 @@
+package pkgsyms
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Equal reports whether a and b are the same symbol:  They have the same name
+// and kind and refer to the same thing.  Consts are equal if their values
+// are, Vars if they point to the same variable, Funcs if they hold the same
+// function, Types if they're the same type and Generics and Constraints if
+// their type parameters or definitions are.  Documentation and other
+// recorded metadata don't matter.  Funcs holding closures, like function
+// literals or method values, are only equal if they're copies of the same
+// Func made by MakeFunc, because closures of the same code can capture
+// different variables.  Symbols of other kinds are equal if they're ==.
+//
+// Merge and Replace use Equal to tell a symbol registered again, which is
+// harmless, from a different symbol registered under the same name.
+func Equal(a, b Symbol) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if a.Name() != b.Name() || KindOf(a) != KindOf(b) {
+		return false
+	}
+	switch a := a.(type) {
+	case Const:
+		return sameValue(a.value, b.(Const).value)
+	case Var:
+		return a.addr == b.(Var).addr
+	case Func:
+		return sameFunc(a, b.(Func))
+	case Type:
+		return a.rtyp == b.(Type).rtyp
+	case Generic:
+		return reflect.DeepEqual(a.params, b.(Generic).params)
+	case Constraint:
+		return a.def == b.(Constraint).def
+	}
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && sameValue(a, b)
+}
+
+// sameValue reports whether a and b are values of the same type that are
+// ==, or deeply equal if they aren't comparable.
+func sameValue(a, b interface{}) (same bool) {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
+	}
+	defer func() {
+		// Comparable types can still hold incomparable values in
+		// interfaces.
+		if recover() != nil {
+			same = reflect.DeepEqual(a, b)
+		}
+	}()
+	return a == b
+}
+
+// sameFunc reports whether a and b hold the same function.
+func sameFunc(a, b Func) bool {
+	if reflect.TypeOf(a.fval) != reflect.TypeOf(b.fval) {
+		return false
+	}
+	va, vb := reflect.ValueOf(a.fval), reflect.ValueOf(b.fval)
+	if va.Kind() != reflect.Func {
+		return sameValue(a.fval, b.fval)
+	}
+	if va.IsNil() || vb.IsNil() {
+		return va.IsNil() && vb.IsNil()
+	}
+	if va.Pointer() != vb.Pointer() {
+		return false
+	}
+	return a.id != 0 && a.id == b.id || !isClosure(va.Pointer())
+}
+
+// isClosure reports whether the function whose code is at pc may be a
+// closure, whose values can differ even though their code is the same.  The
+// code of function literals, method values and functions made by
+// reflect.MakeFunc is a closure's, and so is any code that can't be named.
+func isClosure(pc uintptr) bool {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return true
+	}
+	name := fn.Name()
+	if strings.HasPrefix(name, "reflect.") || strings.HasSuffix(name, "-fm") {
+		return true
+	}
+	// Function literals are named after their enclosing function, like
+	// pkg.F.func1 or pkg.F.func1.2.
+	name = strings.TrimRight(name, "0123456789.")
+	return strings.HasSuffix(name, ".func")
+}
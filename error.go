@@ -2,6 +2,8 @@ package pkgsyms
 
 import (
 	"fmt"
+	"runtime/debug"
+	"strconv"
 	"strings"
 )
 
@@ -9,6 +11,39 @@ type notFoundBase struct{}
 
 func (notFoundBase) Error() string { return "symbol not found" }
 
+type wrongKindBase struct{}
+
+func (wrongKindBase) Error() string { return "symbol is the wrong kind" }
+
+type readOnlyBase struct{}
+
+func (readOnlyBase) Error() string { return "variable is read-only" }
+
+type unauthorizedBase struct{}
+
+func (unauthorizedBase) Error() string { return "symbol access denied" }
+
+type nilBase struct{}
+
+func (nilBase) Error() string { return "symbol is nil" }
+
+var (
+	// ErrNotFound matches every NotFound error with errors.Is.
+	ErrNotFound error = notFoundBase{}
+
+	// ErrWrongKind matches every WrongKind error with errors.Is.
+	ErrWrongKind error = wrongKindBase{}
+
+	// ErrReadOnly matches every ReadOnlyError with errors.Is.
+	ErrReadOnly error = readOnlyBase{}
+
+	// ErrUnauthorized matches every Unauthorized error with errors.Is.
+	ErrUnauthorized error = unauthorizedBase{}
+
+	// ErrNil matches every NilSymbol error with errors.Is.
+	ErrNil error = nilBase{}
+)
+
 // NotFound is returned when a symbol is not found in a package.
 type NotFound struct {
 	Pkg string
@@ -16,11 +51,218 @@ type NotFound struct {
 }
 
 func (nf NotFound) Error() string {
-	if len(nf.Pkg) > 0 {
-		nf.Pkg = fmt.Sprintf("package %q: ", nf.Pkg)
+	switch {
+	case nf.Pkg != "" && nf.Sym != "":
+		return ID(nf.Pkg, nf.Sym) + " not found"
+	case nf.Pkg != "":
+		return fmt.Sprintf("package %q not found", nf.Pkg)
+	case nf.Sym != "":
+		return fmt.Sprintf("symbol %q not found", nf.Sym)
+	}
+	return "not found"
+}
+
+// Is reports whether target is ErrNotFound.
+func (nf NotFound) Is(target error) bool { return target == ErrNotFound }
+
+// Unauthorized is returned when a package's Authorizer denies a caller a
+// symbol.  Err is the Authorizer's reason.
+type Unauthorized struct {
+	Pkg string
+	Sym string
+	Err error
+}
+
+func (e Unauthorized) Error() string {
+	return fmt.Sprintf("%s: access denied: %v", ID(e.Pkg, e.Sym), e.Err)
+}
+
+// Is reports whether target is ErrUnauthorized.
+func (e Unauthorized) Is(target error) bool { return target == ErrUnauthorized }
+
+func (e Unauthorized) Unwrap() error { return e.Err }
+
+// InvokeError is returned when a function called with (Func).Call or a
+// variable set with (Var).SetE panics.  Recovered is the value passed to
+// panic and Stack is the stack trace of the panicking goroutine.
+type InvokeError struct {
+	Sym       string
+	Recovered interface{}
+	Stack     []byte
+}
+
+func (e InvokeError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Sym, e.Recovered)
+}
+
+// Unwrap gets the recovered value if it's an error, like a runtime.Error.
+func (e InvokeError) Unwrap() error {
+	err, _ := e.Recovered.(error)
+	return err
+}
+
+// recoverInvoke recovers a panic while invoking the symbol with the given
+// name into *err as an InvokeError.  It must be deferred.
+func recoverInvoke(sym string, err *error) {
+	if v := recover(); v != nil {
+		*err = InvokeError{Sym: sym, Recovered: v, Stack: debug.Stack()}
+	}
+}
+
+// ArgumentError is returned when a function can't be called with the
+// arguments it was given, like when there are too few of them or they have
+// the wrong types, so that callers can tell their own mistakes from the
+// function's errors.
+type ArgumentError struct {
+	Sym string
+	Err error
+}
+
+func (e ArgumentError) Error() string { return e.Sym + ": " + e.Err.Error() }
+
+func (e ArgumentError) Unwrap() error { return e.Err }
+
+// EnvError describes an environment variable whose value couldn't be parsed
+// into its Var.
+type EnvError struct {
+	Name  string
+	Value string
+	Err   error
+}
+
+func (e EnvError) Error() string {
+	return fmt.Sprintf("environment variable %s=%q: %v", e.Name, e.Value, e.Err)
+}
+
+func (e EnvError) Unwrap() error { return e.Err }
+
+// EnvErrors is returned by BindEnv when one or more environment variables
+// couldn't be parsed.
+type EnvErrors []EnvError
+
+func (errs EnvErrors) Error() string {
+	strs := make([]string, len(errs))
+	for i, e := range errs {
+		strs[i] = e.Error()
+	}
+	return strings.Join(strs, "; ")
+}
+
+// ChecksumMismatch is returned by (*Package).Verify when the symbols
+// registered in a package don't match the expected checksum.
+type ChecksumMismatch struct {
+	Pkg  string
+	Want string
+	Got  string
+}
+
+func (cm ChecksumMismatch) Error() string {
+	return fmt.Sprintf(
+		"package %q: checksum mismatch: expected %s, got %s",
+		cm.Pkg, cm.Want, cm.Got)
+}
+
+// WrongKind is returned by the typed lookup functions like
+// (*Package).LookupFunc when the symbol exists but is a different Kind.
+type WrongKind struct {
+	Pkg  string
+	Sym  string
+	Want Kind
+	Got  Kind
+}
+
+func (wk WrongKind) Error() string {
+	return fmt.Sprintf(
+		"%s: expected %v, not %v", ID(wk.Pkg, wk.Sym), wk.Want, wk.Got)
+}
+
+// Is reports whether target is ErrWrongKind.
+func (wk WrongKind) Is(target error) bool { return target == ErrWrongKind }
+
+// ReadOnlyError is returned when setting a Var that was made read-only.
+type ReadOnlyError struct {
+	Sym string
+}
+
+func (ro ReadOnlyError) Error() string {
+	return fmt.Sprintf("variable %q is read-only", ro.Sym)
+}
+
+// Is reports whether target is ErrReadOnly.
+func (ro ReadOnlyError) Is(target error) bool { return target == ErrReadOnly }
+
+// NilSymbol is returned by GetE in a registry with strict nil checks (see
+// (*Registry).SetStrictNil) for a Var whose pointer is nil or a Func that
+// holds a nil function.
+type NilSymbol struct {
+	Pkg  string
+	Sym  string
+	Kind Kind
+}
+
+func (ns NilSymbol) Error() string {
+	return fmt.Sprintf("%s: %v is nil", ID(ns.Pkg, ns.Sym), ns.Kind)
+}
+
+// Is reports whether target is ErrNil.
+func (ns NilSymbol) Is(target error) bool { return target == ErrNil }
+
+// SchemaError describes a field of input that doesn't match a Type's schema.
+type SchemaError struct {
+	Type    string
+	Field   string
+	Problem string
+}
+
+func (e SchemaError) Error() string {
+	return fmt.Sprintf("%s: field %q %s", e.Type, e.Field, e.Problem)
+}
+
+// SchemaErrors is returned by (Type).Validate when input doesn't match the
+// type's schema.
+type SchemaErrors []SchemaError
+
+func (errs SchemaErrors) Error() string {
+	strs := make([]string, len(errs))
+	for i, e := range errs {
+		strs[i] = e.Error()
 	}
-	if len(nf.Sym) > 0 {
-		nf.Sym = fmt.Sprintf("symbol %q: ", nf.Sym)
+	return strings.Join(strs, "; ")
+}
+
+// Collision describes a name defined more than once when merging packages
+// with (*Symbols).Merge.
+type Collision struct {
+	Name string
+
+	// Pkgs are the names of the packages that define Name.  An empty
+	// string stands for the set that the packages were merged into.
+	Pkgs []string
+}
+
+func (c Collision) String() string {
+	if len(c.Pkgs) == 0 {
+		return fmt.Sprintf("%q is defined more than once", c.Name)
+	}
+	pkgs := make([]string, len(c.Pkgs))
+	for i, p := range c.Pkgs {
+		if p == "" {
+			pkgs[i] = "the set"
+		} else {
+			pkgs[i] = strconv.Quote(p)
+		}
+	}
+	return fmt.Sprintf("%q is defined by %s", c.Name, strings.Join(pkgs, ", "))
+}
+
+// CollisionError is returned by (*Symbols).Merge with MergeError when names
+// collide.
+type CollisionError []Collision
+
+func (errs CollisionError) Error() string {
+	strs := make([]string, len(errs))
+	for i, c := range errs {
+		strs[i] = c.String()
 	}
-	return strings.Join([]string{nf.Pkg, nf.Sym, "not found"}, "")
+	return "name collisions: " + strings.Join(strs, "; ")
 }
@@ -0,0 +1,133 @@
+// Package factory creates values from constructors registered with pkgsyms,
+// so that "create the implementation named in the configuration" is a single
+// call, much like opening a database/sql driver by name.
+package factory
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/skillian/pkgsyms"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// New calls the constructor Func in p named name, or "New" followed by name,
+// with args and returns the value it creates.  The constructor's first result
+// must be assignable to T and it may have a second, error result which New
+// returns.
+func New[T any](p *pkgsyms.Package, name string, args ...interface{}) (T, error) {
+	var zero T
+	f, err := p.LookupFunc(name)
+	if errors.Is(err, pkgsyms.ErrNotFound) {
+		var nerr error
+		if f, nerr = p.LookupFunc("New" + name); !errors.Is(nerr, pkgsyms.ErrNotFound) {
+			err = nerr
+		}
+	}
+	if err != nil {
+		return zero, err
+	}
+	want := reflect.TypeOf((*T)(nil)).Elem()
+	ft := reflect.TypeOf(f.Get())
+	switch {
+	case ft == nil || ft.Kind() != reflect.Func:
+		return zero, fmt.Errorf(
+			"%s: constructor %s holds %#v, not a function",
+			p.Name, f.Name(), f.Get())
+	case ft.NumOut() == 0 || ft.NumOut() > 2:
+		return zero, fmt.Errorf(
+			"%s: constructor %s must return a %v and optionally an error",
+			p.Name, f.Name(), want)
+	case !ft.Out(0).AssignableTo(want):
+		return zero, fmt.Errorf(
+			"%s: constructor %s creates %v, not %v",
+			p.Name, f.Name(), ft.Out(0), want)
+	case ft.NumOut() == 2 && ft.Out(1) != errorType:
+		return zero, fmt.Errorf(
+			"%s: constructor %s's second result must be an error, not %v",
+			p.Name, f.Name(), ft.Out(1))
+	}
+	res, err := f.Call(args...)
+	if err != nil {
+		return zero, err
+	}
+	if len(res) == 2 && res[1] != nil {
+		return zero, res[1].(error)
+	}
+	if res[0] == nil {
+		return zero, nil
+	}
+	return res[0].(T), nil
+}
+
+// TypeKey is the key of the name of the type to create in the configuration
+// passed to BuildFromConfig.
+const TypeKey = "type"
+
+// BuildFromConfig creates a value of the Type in p named by cfg's "type" key
+// and sets its fields from the rest of cfg, for the common "polymorphic
+// configuration section" pattern:
+//
+//	{"type": "FileStore", "dir": "/var/lib/app", "maxSize": 1048576}
+//
+// Fields are matched to keys like encoding/json does, using their json tags,
+// and keys that don't match a field are an error.  If the type has a schema
+// (see pkgsyms.Type.WithSchema), cfg is validated against it first.  The
+// result is a pointer to the new value so that it satisfies interfaces
+// implemented with pointer receivers.
+func BuildFromConfig(p *pkgsyms.Package, cfg map[string]interface{}) (interface{}, error) {
+	name, ok := cfg[TypeKey].(string)
+	if !ok {
+		return nil, fmt.Errorf("%s: configuration needs a %q string", p.Name, TypeKey)
+	}
+	t, err := p.LookupType(name)
+	if err != nil {
+		return nil, err
+	}
+	rest := make(map[string]interface{}, len(cfg)-1)
+	for k, v := range cfg {
+		if k != TypeKey {
+			rest[k] = v
+		}
+	}
+	if len(t.Schema()) > 0 {
+		if err := t.Validate(rest); err != nil {
+			return nil, err
+		}
+	}
+	pv := reflect.New(t.Type())
+	if len(rest) == 0 {
+		return pv.Interface(), nil
+	}
+	data, err := json.Marshal(rest)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", pkgsyms.ID(p.Name, name), err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(pv.Interface()); err != nil {
+		return nil, fmt.Errorf("%s: %w", pkgsyms.ID(p.Name, name), err)
+	}
+	return pv.Interface(), nil
+}
+
+// Build creates a value from a configuration with BuildFromConfig and
+// returns it as a T.  The value is a pointer to the type named in cfg.
+func Build[T any](p *pkgsyms.Package, cfg map[string]interface{}) (T, error) {
+	var zero T
+	v, err := BuildFromConfig(p, cfg)
+	if err != nil {
+		return zero, err
+	}
+	t, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf(
+			"%s: %T is not a %v",
+			p.Name, v, reflect.TypeOf((*T)(nil)).Elem())
+	}
+	return t, nil
+}
@@ -0,0 +1,92 @@
+package factory_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/factory"
+)
+
+func TestNew(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/factory_test")
+	p.Add(
+		pkgsyms.MakeFunc("NewReader", func(s string) io.Reader { return strings.NewReader(s) }),
+		pkgsyms.MakeFunc("Buffer", func() (*bytes.Buffer, error) { return new(bytes.Buffer), nil }),
+		pkgsyms.MakeFunc("NewBroken", func() (io.Reader, error) { return nil, errors.New("broken") }),
+		pkgsyms.MakeFunc("NewNil", nil),
+		pkgsyms.MakeFunc("NewString", "not a function"),
+	)
+	r, err := factory.New[io.Reader](p, "Reader", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "hello" {
+		t.Fatalf("expected hello but got %q", data)
+	}
+	if _, err := factory.New[io.Writer](p, "Buffer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := factory.New[io.Writer](p, "Reader", "hello"); err == nil {
+		t.Fatal("expected error creating a Writer from a Reader constructor")
+	}
+	if _, err := factory.New[io.Reader](p, "Broken"); err == nil || err.Error() != "broken" {
+		t.Fatalf("expected constructor's error, got %v", err)
+	}
+	for _, name := range []string{"Nil", "String"} {
+		if _, err := factory.New[io.Reader](p, name); err == nil {
+			t.Fatalf("expected error creating a Reader from %s", name)
+		}
+	}
+	if _, err := factory.New[io.Reader](p, "Missing"); !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected %v, got %v", pkgsyms.ErrNotFound, err)
+	}
+}
+
+type fileStore struct {
+	Dir     string `json:"dir"`
+	MaxSize int64  `json:"maxSize"`
+	Tags    []string
+}
+
+func (fs *fileStore) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestBuildFromConfig(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms/factory_test")
+	p.Add(
+		pkgsyms.MakeType("FileStore", (*fileStore)(nil)),
+		pkgsyms.MakeType("Strict", (*fileStore)(nil)).WithSchema(
+			pkgsyms.Field{Name: "dir", GoName: "Dir", Kind: "string", Type: "string", Required: true},
+		),
+	)
+	w, err := factory.Build[io.Writer](p, map[string]interface{}{
+		"type":    "FileStore",
+		"dir":     "/var/lib/app",
+		"maxSize": 1048576.0,
+		"tags":    []interface{}{"a"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := w.(*fileStore)
+	if fs.Dir != "/var/lib/app" || fs.MaxSize != 1048576 || len(fs.Tags) != 1 {
+		t.Fatalf("unexpected configuration %+v", fs)
+	}
+	for _, cfg := range []map[string]interface{}{
+		{"dir": "x"},
+		{"type": "Missing"},
+		{"type": "FileStore", "bogus": 1},
+		{"type": "FileStore", "maxSize": "big"},
+		{"type": "Strict"},
+	} {
+		if _, err := factory.BuildFromConfig(p, cfg); err == nil {
+			t.Errorf("expected an error building %v", cfg)
+		}
+	}
+	if _, err := factory.Build[io.Reader](p, map[string]interface{}{"type": "FileStore"}); err == nil {
+		t.Fatal("expected an error building a Reader from a FileStore")
+	}
+}
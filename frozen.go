@@ -0,0 +1,44 @@
+package pkgsyms
+
+// FrozenSymbols is an immutable copy of a package's symbols for hot paths
+// that look symbols up on every request.  Lookups don't lock anything, so
+// they don't contend with each other or with registration.  The zero value
+// is empty.
+type FrozenSymbols struct {
+	pkg   string
+	names map[string]Symbol
+	slice []Symbol
+}
+
+// Frozen gets a FrozenSymbols of the symbols currently in the package.
+// Symbols added afterwards, including by Providers, aren't in it, so it
+// should be taken once the package is ready.
+func (p *Package) Frozen() FrozenSymbols {
+	ss := p.snapshot()
+	names := make(map[string]Symbol, len(ss))
+	for _, s := range ss {
+		names[s.Name()] = s
+	}
+	return FrozenSymbols{pkg: p.Name, names: names, slice: ss}
+}
+
+// Lookup a symbol by name.
+func (fs FrozenSymbols) Lookup(name string) (Symbol, error) {
+	if s, ok := fs.names[name]; ok {
+		return s, nil
+	}
+	return nil, NotFound{Pkg: fs.pkg, Sym: name}
+}
+
+// Range calls f with each symbol in the order they were added until f
+// returns false.
+func (fs FrozenSymbols) Range(f func(s Symbol) bool) {
+	for _, s := range fs.slice {
+		if !f(s) {
+			return
+		}
+	}
+}
+
+// Len gets the number of symbols.
+func (fs FrozenSymbols) Len() int { return len(fs.slice) }
@@ -0,0 +1,94 @@
+package pkgsyms_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// FuzzSymbols interprets each byte of the input as an operation on a shared
+// set of symbols and runs the operations from several goroutines at once.
+// Run it with the race detector to check the set's locking:
+//
+//	go test -race -fuzz=FuzzSymbols
+func FuzzSymbols(f *testing.F) {
+	f.Add([]byte{0, 1, 2, 3, 4, 5, 6, 7})
+	f.Add([]byte{0x10, 0x21, 0x32, 0x43, 0x10, 0x21})
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		var syms pkgsyms.Symbols
+		const workers = 4
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < len(ops); i += workers {
+					applyOp(t, &syms, ops[i])
+				}
+			}(w)
+		}
+		wg.Wait()
+		checkSymbols(t, &syms)
+	})
+}
+
+// applyOp applies an operation to syms.  The low 2 bits of op select the
+// operation and the rest select the symbol name.  It runs in its own
+// goroutine, so it reports failures with t.Error instead of t.Fatal.
+func applyOp(t *testing.T, syms *pkgsyms.Symbols, op byte) {
+	name := fmt.Sprint("S", op>>2)
+	switch op & 3 {
+	case 0:
+		syms.Add(pkgsyms.MakeConst(name, int(op>>2)))
+	case 1:
+		if s, err := syms.Lookup(name); err == nil && s.Name() != name {
+			t.Errorf("looked up %q but got %q", name, s.Name())
+		}
+	case 2:
+		syms.Remove(name)
+	case 3:
+		syms.Range(func(s pkgsyms.Symbol) bool {
+			if s == nil {
+				t.Error("Range yielded a nil symbol")
+			}
+			return true
+		})
+	}
+}
+
+// checkSymbols checks that every symbol yielded by Range can be looked up and
+// that no name is yielded twice.
+func checkSymbols(t *testing.T, syms *pkgsyms.Symbols) {
+	seen := make(map[string]bool)
+	syms.Range(func(s pkgsyms.Symbol) bool {
+		if seen[s.Name()] {
+			t.Fatalf("duplicate symbol %q", s.Name())
+		}
+		seen[s.Name()] = true
+		if _, err := syms.Lookup(s.Name()); err != nil {
+			t.Fatal(err)
+		}
+		return true
+	})
+	if n := syms.Len(); n != len(seen) {
+		t.Fatalf("Len is %d but Range yielded %d symbols", n, len(seen))
+	}
+}
+
+func TestPackagesConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p := pkgsyms.Of(fmt.Sprint("github.com/skillian/pkgsyms_test/stress", i%2))
+			for j := 0; j < 100; j++ {
+				applyOp(t, &p.Symbols, byte(i*100+j))
+				pkgsyms.Packages()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
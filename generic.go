@@ -0,0 +1,111 @@
+package pkgsyms
+
+import "strings"
+
+// TypeParam describes a type parameter of a generic type or function.
+type TypeParam struct {
+	// Name of the type parameter, e.g. "T".
+	Name string
+
+	// Constraint of the type parameter as written in the package, e.g.
+	// "any", "comparable", "fmt.Stringer" or "Number".  Exported
+	// constraints defined in the same package are registered as symbols
+	// too.
+	Constraint string
+}
+
+// Generic describes a generic type or function.  Generic types and functions
+// can't be referenced without being instantiated, so only their type
+// parameters are registered.
+type Generic struct {
+	name   string
+	params []TypeParam
+	doc    string
+}
+
+// MakeGeneric creates a Generic symbol.
+func MakeGeneric(name string, params ...TypeParam) Generic {
+	return Generic{name: name, params: params}
+}
+
+// Name of the generic type or function.
+func (g Generic) Name() string { return g.name }
+
+// Get the type parameters as a []TypeParam.
+func (g Generic) Get() interface{} { return g.Params() }
+
+// Params gets a copy of the type parameters.
+func (g Generic) Params() []TypeParam {
+	return append([]TypeParam(nil), g.params...)
+}
+
+// Doc gets the documentation, if any was recorded.
+func (g Generic) Doc() string { return g.doc }
+
+// WithDoc returns a copy of the generic with its documentation set to doc.
+func (g Generic) WithDoc(doc string) Generic {
+	g.doc = doc
+	return g
+}
+
+func (g Generic) String() string {
+	strs := make([]string, len(g.params))
+	for i, p := range g.params {
+		strs[i] = p.Name + " " + p.Constraint
+	}
+	return g.name + "[" + strings.Join(strs, ", ") + "]"
+}
+
+// Constraint is an interface that can only be used as a type parameter
+// constraint because it has a type set, like
+//
+//	type Number interface{ ~int | ~float64 }
+//
+// Such interfaces can't be registered as Types, so their definition is
+// registered instead.
+type Constraint struct {
+	name string
+	def  string
+	doc  string
+}
+
+// MakeConstraint creates a Constraint symbol from the constraint's name and
+// its definition as written in the package.
+func MakeConstraint(name, def string) Constraint {
+	return Constraint{name: name, def: def}
+}
+
+// Name of the constraint.
+func (c Constraint) Name() string { return c.name }
+
+// Get the definition of the constraint as a string.
+func (c Constraint) Get() interface{} { return c.def }
+
+// Doc gets the documentation, if any was recorded.
+func (c Constraint) Doc() string { return c.doc }
+
+// WithDoc returns a copy of the constraint with its documentation set to doc.
+func (c Constraint) WithDoc(doc string) Constraint {
+	c.doc = doc
+	return c
+}
+
+// ConstraintUsers gets the generic types and functions in the package with a
+// type parameter constrained by the named constraint.
+func (p *Package) ConstraintUsers(constraint string) []Generic {
+	var gs []Generic
+	p.Range(func(s Symbol) bool {
+		g, ok := s.(Generic)
+		if !ok {
+			return true
+		}
+		for _, tp := range g.params {
+			if tp.Constraint == constraint {
+				gs = append(gs, g)
+				break
+			}
+		}
+		return true
+	})
+	return gs
+}
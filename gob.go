@@ -0,0 +1,45 @@
+//go:build !tinygo
+
+package pkgsyms
+
+import (
+	"encoding/gob"
+	"fmt"
+	"reflect"
+)
+
+// RegisterGob registers every concrete Type symbol in p with gob.RegisterName
+// using the stable name "pkgpath.TypeName" so that values of those types can
+// be decoded from interface-valued gob streams.  Interface, function and
+// channel types are skipped because gob can't transmit them.
+//
+// gob.RegisterName panics if a type or name is registered twice with
+// different counterparts; RegisterGob reports that as an error instead.
+func RegisterGob(p *Package) (err error) {
+	p.Range(func(s Symbol) bool {
+		t, ok := s.(Type)
+		if !ok {
+			return true
+		}
+		switch t.rtyp.Kind() {
+		case reflect.Interface, reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			return true
+		}
+		name := p.Name + "." + t.name
+		if err = registerGob(name, reflect.Zero(t.rtyp).Interface()); err != nil {
+			return false
+		}
+		return true
+	})
+	return
+}
+
+func registerGob(name string, value interface{}) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("failed to register %q with gob: %v", name, v)
+		}
+	}()
+	gob.RegisterName(name, value)
+	return nil
+}
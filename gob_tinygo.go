@@ -0,0 +1,11 @@
+//go:build tinygo
+
+package pkgsyms
+
+import "fmt"
+
+// RegisterGob is not supported when built with TinyGo because encoding/gob
+// depends on parts of the reflect package that TinyGo doesn't implement.
+func RegisterGob(p *Package) error {
+	return fmt.Errorf("package %q: RegisterGob is not supported by TinyGo", p.Name)
+}
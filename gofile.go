@@ -0,0 +1,184 @@
+package pkgsyms
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GoOptions configures WriteGo.
+type GoOptions struct {
+	// Package is the name of the Go package of the file.  It's the last
+	// element of the registered package's path if it's empty.
+	Package string
+
+	// VarName is the name of the variable holding the *Package.  It's
+	// "Pkg", like the pkgsyms command's default, if it's empty.
+	VarName string
+
+	// Qualifier is prepended to the identifiers of the symbols, like
+	// "basic.", when the file isn't in the package that declares them.
+	// That package has to be in Imports.
+	Qualifier string
+
+	// Imports are the paths of packages that the file imports besides
+	// pkgsyms.
+	Imports []string
+
+	// Docs records the symbols' documentation.
+	Docs bool
+}
+
+// WriteGo writes a Go file that registers the symbols of p like a file
+// generated by the pkgsyms command, so that registries built or filtered at
+// run time can be emitted as source again.  Symbols are referenced by their
+// names, so they must be declared under those names in the Go package, and
+// Vars must still be variables.  Symbols added with AddConditional are left
+// out because their build constraints aren't known.  Symbols of kinds that
+// this package doesn't implement can't be written.
+func WriteGo(w io.Writer, p *Package, opts GoOptions) error {
+	if opts.Package == "" {
+		opts.Package = path.Base(p.Name)
+	}
+	if opts.VarName == "" {
+		opts.VarName = "Pkg"
+	}
+	var syms bytes.Buffer
+	var lines []string
+	var err error
+	p.Range(func(s Symbol) bool {
+		if p.isConditional(s.Name()) {
+			return true
+		}
+		var expr string
+		if expr, err = goExpr(s, opts); err != nil {
+			err = fmt.Errorf("%s: %w", ID(p.Name, s.Name()), err)
+			return false
+		}
+		fmt.Fprintf(&syms, "\t\t%s,\n", expr)
+		lines = append(lines, KindOf(s).String()+" "+s.Name())
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"pkgsyms.WriteGo\"; DO NOT EDIT.\n\npackage %s\n\nimport (\n", opts.Package)
+	for _, imp := range append([]string{"github.com/skillian/pkgsyms"}, opts.Imports...) {
+		fmt.Fprintf(&buf, "\t%q\n", imp)
+	}
+	fmt.Fprintf(&buf, ")\n\n// Fails to compile against versions of pkgsyms that don't support the\n"+
+		"// generated code.\nconst _ = pkgsyms.SupportsAPIVersion%d\n\n", APIVersion)
+	fmt.Fprintf(&buf, "var %s = pkgsyms.Of(%q)\n\n", opts.VarName, p.Name)
+	r, n := utf8.DecodeRuneInString(opts.VarName)
+	checksumName := string(unicode.ToLower(r)) + opts.VarName[n:] + "Checksum"
+	fmt.Fprintf(&buf, "// %s is the checksum of the symbols registered by this file.  See\n"+
+		"// (*pkgsyms.Package).Verify.\nconst %[1]s = %q\n\n", checksumName, ChecksumOf(lines))
+	fmt.Fprintf(&buf, "func init() {\n")
+	if version, api := p.Generator(); version != "" {
+		fmt.Fprintf(&buf, "\t%s.SetGenerator(%q, %d)\n", opts.VarName, version, api)
+	}
+	if doc := p.Doc(); opts.Docs && doc != "" {
+		fmt.Fprintf(&buf, "\t%s.SetDoc(%q)\n", opts.VarName, doc)
+	}
+	fmt.Fprintf(&buf, "\t%s.Add(\n%s\t)\n\t%[1]s.MarkReady()\n}\n", opts.VarName, syms.Bytes())
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("package %q: %w", p.Name, err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// goExpr gets the Go expression that creates s in a file written by WriteGo.
+func goExpr(s Symbol, opts GoOptions) (string, error) {
+	name := s.Name()
+	ident := opts.Qualifier + name
+	var expr string
+	switch s := s.(type) {
+	case Const:
+		expr = fmt.Sprintf("pkgsyms.MakeConst(%q, %s)", name, ident)
+		if s.group != "" {
+			expr += fmt.Sprintf(".WithGroup(%q)", s.group)
+		}
+	case Func:
+		expr = fmt.Sprintf("pkgsyms.MakeFunc(%q, %s)", name, ident)
+	case Var:
+		expr = fmt.Sprintf("pkgsyms.MakeVar(%q, &%s)", name, ident)
+		if s.fval != nil {
+			expr += ".Callable()"
+		}
+		if s.guard != nil {
+			expr += ".Guarded()"
+		}
+		if s.readOnly {
+			expr += ".ReadOnly()"
+		}
+	case Type:
+		expr = fmt.Sprintf("pkgsyms.MakeType(%q, (*%s)(nil))", name, ident)
+		if s.underlying != "" {
+			expr += fmt.Sprintf(".WithUnderlying(%q)", s.underlying)
+		}
+		if len(s.implements) > 0 {
+			names := make([]string, len(s.implements))
+			for i, name := range s.implements {
+				names[i] = strconv.Quote(name)
+			}
+			expr += fmt.Sprintf(".WithImplements(%s)", strings.Join(names, ", "))
+		}
+		if len(s.methods) > 0 {
+			ms := make([]string, len(s.methods))
+			for i, m := range s.methods {
+				ptr := ""
+				if m.Pointer {
+					ptr = ", Pointer: true"
+				}
+				ms[i] = fmt.Sprintf("pkgsyms.Method{Name: %q, Signature: %q%s}", m.Name, m.Signature, ptr)
+			}
+			expr += fmt.Sprintf(".WithMethods(%s)", strings.Join(ms, ", "))
+		}
+		if len(s.schema) > 0 {
+			fs := make([]string, len(s.schema))
+			for i, f := range s.schema {
+				req := ""
+				if f.Required {
+					req = ", Required: true"
+				}
+				fs[i] = fmt.Sprintf(
+					"pkgsyms.Field{Name: %q, GoName: %q, Kind: %q, Type: %q%s}",
+					f.Name, f.GoName, f.Kind, f.Type, req)
+			}
+			expr += fmt.Sprintf(".WithSchema(%s)", strings.Join(fs, ", "))
+		}
+	case Generic:
+		var params strings.Builder
+		for _, tp := range s.params {
+			fmt.Fprintf(&params, ", pkgsyms.TypeParam{Name: %q, Constraint: %q}", tp.Name, tp.Constraint)
+		}
+		return withGoDoc(fmt.Sprintf("pkgsyms.MakeGeneric(%q%s)", name, params.String()), s, opts), nil
+	case Constraint:
+		return withGoDoc(fmt.Sprintf("pkgsyms.MakeConstraint(%q, %q)", name, s.def), s, opts), nil
+	default:
+		return "", fmt.Errorf("can't write a %T as Go", s)
+	}
+	if !token.IsIdentifier(name) {
+		return "", fmt.Errorf("%q isn't a Go identifier", name)
+	}
+	return withGoDoc(expr, s, opts), nil
+}
+
+// withGoDoc appends a WithDoc call to expr if opts records the documentation
+// of s.
+func withGoDoc(expr string, s Symbol, opts GoOptions) string {
+	if doc := Doc(s); opts.Docs && doc != "" {
+		expr += fmt.Sprintf(".WithDoc(%q)", doc)
+	}
+	return expr
+}
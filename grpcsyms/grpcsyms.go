@@ -0,0 +1,301 @@
+// Package grpcsyms serves the pkgsyms registry over gRPC, as a strongly typed
+// alternative to httpsyms.  Server implements the Registry service defined in
+// grpcsyms.proto and Client looks up proxies of the symbols it serves.
+// Arguments and results of calls and the values of constants and variables
+// are encoded as JSON, like with pkgsyms.Func.CallJSON.
+package grpcsyms
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative grpcsyms.proto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/skillian/pkgsyms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Server serves a registry with the Registry service.  Symbols are looked up
+// with (*pkgsyms.Package).LookupAs on behalf of the request's *peer.Peer, so
+// packages' Authorizers can decide what each client may see and call.
+type Server struct {
+	UnimplementedRegistryServer
+
+	// Registry to serve.  The global registry is served if it's nil.
+	Registry *pkgsyms.Registry
+}
+
+func (s *Server) packages() []*pkgsyms.Package {
+	if s.Registry == nil {
+		return pkgsyms.Packages()
+	}
+	return s.Registry.Packages()
+}
+
+func (s *Server) lookup(name string) (*pkgsyms.Package, error) {
+	if s.Registry == nil {
+		return pkgsyms.Lookup(name)
+	}
+	return s.Registry.Lookup(name)
+}
+
+// lookupSymbol looks up a symbol on behalf of the caller of ctx.
+func (s *Server) lookupSymbol(ctx context.Context, pkgName, name string) (*pkgsyms.Package, pkgsyms.Symbol, error) {
+	p, err := s.lookup(pkgName)
+	if err != nil {
+		return nil, nil, statusOf(err)
+	}
+	sym, err := p.LookupAs(callerOf(ctx), name)
+	if err != nil {
+		return nil, nil, statusOf(err)
+	}
+	return p, sym, nil
+}
+
+// callerOf gets the *peer.Peer of ctx, or nil if it has none.
+func callerOf(ctx context.Context) interface{} {
+	if pr, ok := peer.FromContext(ctx); ok {
+		return pr
+	}
+	return nil
+}
+
+// ListPackages lists the names of the registered packages.
+func (s *Server) ListPackages(ctx context.Context, req *ListPackagesRequest) (*ListPackagesResponse, error) {
+	var res ListPackagesResponse
+	for _, p := range s.packages() {
+		res.Names = append(res.Names, p.Name)
+	}
+	return &res, nil
+}
+
+// ListSymbols describes the symbols in a package that the package's
+// Authorizer allows the request's peer.
+func (s *Server) ListSymbols(ctx context.Context, req *ListSymbolsRequest) (*ListSymbolsResponse, error) {
+	p, err := s.lookup(req.GetPackage())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	res := ListSymbolsResponse{Doc: p.Doc()}
+	p.RangeAs(callerOf(ctx), func(sym pkgsyms.Symbol) bool {
+		res.Symbols = append(res.Symbols, infoOf(p.Name, sym))
+		return true
+	})
+	return &res, nil
+}
+
+// GetSymbol describes a symbol.
+func (s *Server) GetSymbol(ctx context.Context, req *GetSymbolRequest) (*SymbolInfo, error) {
+	p, sym, err := s.lookupSymbol(ctx, req.GetPackage(), req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	return infoOf(p.Name, sym), nil
+}
+
+// CallFunc calls a Func, or a callable Var, with (pkgsyms.Func).CallJSON.
+func (s *Server) CallFunc(ctx context.Context, req *CallFuncRequest) (*CallFuncResponse, error) {
+	p, sym, err := s.lookupSymbol(ctx, req.GetPackage(), req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	f, ok := pkgsyms.AsFunc(sym)
+	if !ok {
+		return nil, statusOf(pkgsyms.WrongKind{
+			Pkg: p.Name, Sym: sym.Name(),
+			Want: pkgsyms.FuncKind, Got: pkgsyms.KindOf(sym)})
+	}
+	results, err := f.CallJSON(req.GetArgs())
+	if err != nil {
+		return nil, statusOf(err)
+	}
+	return &CallFuncResponse{Results: results}, nil
+}
+
+// statusOf converts pkgsyms errors into gRPC status errors.
+func statusOf(err error) error {
+	code := codes.Unknown
+	var ie pkgsyms.InvokeError
+	switch {
+	case errors.Is(err, pkgsyms.ErrNotFound):
+		code = codes.NotFound
+	case errors.Is(err, pkgsyms.ErrUnauthorized):
+		code = codes.PermissionDenied
+	case errors.Is(err, pkgsyms.ErrWrongKind):
+		code = codes.FailedPrecondition
+	case errors.As(err, &ie):
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+func infoOf(pkg string, s pkgsyms.Symbol) *SymbolInfo {
+	info := &SymbolInfo{
+		Package:   pkg,
+		Name:      s.Name(),
+		Kind:      pkgsyms.KindOf(s).String(),
+		Signature: signature(s),
+		Doc:       pkgsyms.Doc(s),
+	}
+	switch s.(type) {
+	case pkgsyms.Const, pkgsyms.Var:
+		if v, err := pkgsyms.GetValue(s); err == nil {
+			info.Value, _ = json.Marshal(v)
+		}
+	}
+	return info
+}
+
+// signature describes the type of a symbol like httpsyms does.
+func signature(s pkgsyms.Symbol) string {
+	switch s := s.(type) {
+	case pkgsyms.Type:
+		return s.Underlying()
+	case pkgsyms.Var:
+		return s.Type().String()
+	case pkgsyms.Generic:
+		return s.String()
+	case pkgsyms.Constraint:
+		return s.Get().(string)
+	}
+	if v := s.Get(); v != nil {
+		return reflect.TypeOf(v).String()
+	}
+	return "nil"
+}
+
+// Client looks up symbols in a registry served by a Server.
+type Client struct {
+	rc RegistryClient
+}
+
+// NewClient creates a Client of the Registry service on cc.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{rc: NewRegistryClient(cc)}
+}
+
+// Packages lists the names of the packages in the remote registry.
+func (c *Client) Packages(ctx context.Context) ([]string, error) {
+	res, err := c.rc.ListPackages(ctx, &ListPackagesRequest{})
+	if err != nil {
+		return nil, errorOf(err, "", "")
+	}
+	return res.GetNames(), nil
+}
+
+// Symbols gets proxies of every symbol in a remote package.
+func (c *Client) Symbols(ctx context.Context, pkg string) ([]*Symbol, error) {
+	res, err := c.rc.ListSymbols(ctx, &ListSymbolsRequest{Package: pkg})
+	if err != nil {
+		return nil, errorOf(err, pkg, "")
+	}
+	syms := make([]*Symbol, len(res.GetSymbols()))
+	for i, info := range res.GetSymbols() {
+		syms[i] = &Symbol{client: c, info: info}
+	}
+	return syms, nil
+}
+
+// Lookup gets a proxy of a symbol in a remote package.  It returns a
+// pkgsyms.NotFound error if the symbol doesn't exist.
+func (c *Client) Lookup(ctx context.Context, pkg, name string) (*Symbol, error) {
+	info, err := c.rc.GetSymbol(ctx, &GetSymbolRequest{Package: pkg, Name: name})
+	if err != nil {
+		return nil, errorOf(err, pkg, name)
+	}
+	return &Symbol{client: c, info: info}, nil
+}
+
+// errorOf converts NotFound status errors back into pkgsyms.NotFound errors.
+func errorOf(err error, pkg, name string) error {
+	if status.Code(err) == codes.NotFound {
+		return pkgsyms.NotFound{Pkg: pkg, Sym: name}
+	}
+	return err
+}
+
+// Symbol is a proxy of a symbol in a remote registry.  It implements
+// pkgsyms.Symbol and pkgsyms.GetterE, so it can be added to a local package.
+// Its value is the one the symbol had when it was looked up, decoded from
+// JSON into an interface{}.
+type Symbol struct {
+	client *Client
+	info   *SymbolInfo
+}
+
+// Name of the symbol.
+func (s *Symbol) Name() string { return s.info.GetName() }
+
+// Get the symbol's value, or nil if it has none.
+func (s *Symbol) Get() interface{} {
+	v, _ := s.GetE()
+	return v
+}
+
+// GetE gets the symbol's value.  Only constants and variables whose values
+// can be encoded in JSON have values.
+func (s *Symbol) GetE() (interface{}, error) {
+	if len(s.info.GetValue()) == 0 {
+		return nil, fmt.Errorf("%s %s has no value", s.Kind(), s.ID())
+	}
+	var v interface{}
+	if err := json.Unmarshal(s.info.GetValue(), &v); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.ID(), err)
+	}
+	return v, nil
+}
+
+// Package is the name of the symbol's package.
+func (s *Symbol) Package() string { return s.info.GetPackage() }
+
+// ID gets the symbol's identity string (see pkgsyms.ID).
+func (s *Symbol) ID() string { return pkgsyms.ID(s.Package(), s.Name()) }
+
+// Kind of the symbol, like "Func".
+func (s *Symbol) Kind() string { return s.info.GetKind() }
+
+// Signature is the symbol's Go type, or a type's underlying type.
+func (s *Symbol) Signature() string { return s.info.GetSignature() }
+
+// Doc gets the symbol's documentation.
+func (s *Symbol) Doc() string { return s.info.GetDoc() }
+
+// Call the remote function with args encoded as JSON and return its results
+// decoded from JSON.
+func (s *Symbol) Call(ctx context.Context, args ...interface{}) ([]interface{}, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.ID(), err)
+	}
+	if data, err = s.CallJSON(ctx, data); err != nil {
+		return nil, err
+	}
+	var results []interface{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.ID(), err)
+	}
+	return results, nil
+}
+
+// CallJSON calls the remote function with a JSON array of arguments and
+// returns the JSON array of its results, like (pkgsyms.Func).CallJSON.
+func (s *Symbol) CallJSON(ctx context.Context, args []byte) ([]byte, error) {
+	res, err := s.client.rc.CallFunc(ctx, &CallFuncRequest{
+		Package: s.Package(),
+		Name:    s.Name(),
+		Args:    args,
+	})
+	if err != nil {
+		return nil, errorOf(err, s.Package(), s.Name())
+	}
+	return res.GetResults(), nil
+}
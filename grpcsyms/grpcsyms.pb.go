@@ -0,0 +1,545 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.29.3
+// source: grpcsyms.proto
+
+package grpcsyms
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListPackagesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPackagesRequest) Reset() {
+	*x = ListPackagesRequest{}
+	mi := &file_grpcsyms_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPackagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPackagesRequest) ProtoMessage() {}
+
+func (x *ListPackagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcsyms_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPackagesRequest.ProtoReflect.Descriptor instead.
+func (*ListPackagesRequest) Descriptor() ([]byte, []int) {
+	return file_grpcsyms_proto_rawDescGZIP(), []int{0}
+}
+
+type ListPackagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Names         []string               `protobuf:"bytes,1,rep,name=names,proto3" json:"names,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPackagesResponse) Reset() {
+	*x = ListPackagesResponse{}
+	mi := &file_grpcsyms_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPackagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPackagesResponse) ProtoMessage() {}
+
+func (x *ListPackagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcsyms_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPackagesResponse.ProtoReflect.Descriptor instead.
+func (*ListPackagesResponse) Descriptor() ([]byte, []int) {
+	return file_grpcsyms_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListPackagesResponse) GetNames() []string {
+	if x != nil {
+		return x.Names
+	}
+	return nil
+}
+
+type ListSymbolsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Package       string                 `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSymbolsRequest) Reset() {
+	*x = ListSymbolsRequest{}
+	mi := &file_grpcsyms_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSymbolsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSymbolsRequest) ProtoMessage() {}
+
+func (x *ListSymbolsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcsyms_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSymbolsRequest.ProtoReflect.Descriptor instead.
+func (*ListSymbolsRequest) Descriptor() ([]byte, []int) {
+	return file_grpcsyms_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListSymbolsRequest) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+type ListSymbolsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Doc           string                 `protobuf:"bytes,1,opt,name=doc,proto3" json:"doc,omitempty"`
+	Symbols       []*SymbolInfo          `protobuf:"bytes,2,rep,name=symbols,proto3" json:"symbols,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSymbolsResponse) Reset() {
+	*x = ListSymbolsResponse{}
+	mi := &file_grpcsyms_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSymbolsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSymbolsResponse) ProtoMessage() {}
+
+func (x *ListSymbolsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcsyms_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSymbolsResponse.ProtoReflect.Descriptor instead.
+func (*ListSymbolsResponse) Descriptor() ([]byte, []int) {
+	return file_grpcsyms_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListSymbolsResponse) GetDoc() string {
+	if x != nil {
+		return x.Doc
+	}
+	return ""
+}
+
+func (x *ListSymbolsResponse) GetSymbols() []*SymbolInfo {
+	if x != nil {
+		return x.Symbols
+	}
+	return nil
+}
+
+type GetSymbolRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Package       string                 `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetSymbolRequest) Reset() {
+	*x = GetSymbolRequest{}
+	mi := &file_grpcsyms_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetSymbolRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetSymbolRequest) ProtoMessage() {}
+
+func (x *GetSymbolRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcsyms_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetSymbolRequest.ProtoReflect.Descriptor instead.
+func (*GetSymbolRequest) Descriptor() ([]byte, []int) {
+	return file_grpcsyms_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetSymbolRequest) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *GetSymbolRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// SymbolInfo describes a symbol.
+type SymbolInfo struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Package string                 `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
+	Name    string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// Kind is the pkgsyms.Kind, like "Func".
+	Kind string `protobuf:"bytes,3,opt,name=kind,proto3" json:"kind,omitempty"`
+	// Signature is the symbol's Go type, or a type's underlying type.
+	Signature string `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+	Doc       string `protobuf:"bytes,5,opt,name=doc,proto3" json:"doc,omitempty"`
+	// Value is the JSON encoding of a constant's or variable's value.  It's
+	// empty for other symbols and values that can't be encoded.
+	Value         []byte `protobuf:"bytes,6,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SymbolInfo) Reset() {
+	*x = SymbolInfo{}
+	mi := &file_grpcsyms_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SymbolInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SymbolInfo) ProtoMessage() {}
+
+func (x *SymbolInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcsyms_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SymbolInfo.ProtoReflect.Descriptor instead.
+func (*SymbolInfo) Descriptor() ([]byte, []int) {
+	return file_grpcsyms_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SymbolInfo) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *SymbolInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SymbolInfo) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *SymbolInfo) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *SymbolInfo) GetDoc() string {
+	if x != nil {
+		return x.Doc
+	}
+	return ""
+}
+
+func (x *SymbolInfo) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type CallFuncRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Package string                 `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
+	Name    string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// Args is a JSON array of the arguments.
+	Args          []byte `protobuf:"bytes,3,opt,name=args,proto3" json:"args,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallFuncRequest) Reset() {
+	*x = CallFuncRequest{}
+	mi := &file_grpcsyms_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallFuncRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallFuncRequest) ProtoMessage() {}
+
+func (x *CallFuncRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcsyms_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallFuncRequest.ProtoReflect.Descriptor instead.
+func (*CallFuncRequest) Descriptor() ([]byte, []int) {
+	return file_grpcsyms_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CallFuncRequest) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *CallFuncRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CallFuncRequest) GetArgs() []byte {
+	if x != nil {
+		return x.Args
+	}
+	return nil
+}
+
+type CallFuncResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Results is a JSON array of the results.
+	Results       []byte `protobuf:"bytes,1,opt,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CallFuncResponse) Reset() {
+	*x = CallFuncResponse{}
+	mi := &file_grpcsyms_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CallFuncResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallFuncResponse) ProtoMessage() {}
+
+func (x *CallFuncResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_grpcsyms_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallFuncResponse.ProtoReflect.Descriptor instead.
+func (*CallFuncResponse) Descriptor() ([]byte, []int) {
+	return file_grpcsyms_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *CallFuncResponse) GetResults() []byte {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_grpcsyms_proto protoreflect.FileDescriptor
+
+const file_grpcsyms_proto_rawDesc = "" +
+	"\n" +
+	"\x0egrpcsyms.proto\x12\x10pkgsyms.grpcsyms\"\x15\n" +
+	"\x13ListPackagesRequest\",\n" +
+	"\x14ListPackagesResponse\x12\x14\n" +
+	"\x05names\x18\x01 \x03(\tR\x05names\".\n" +
+	"\x12ListSymbolsRequest\x12\x18\n" +
+	"\apackage\x18\x01 \x01(\tR\apackage\"_\n" +
+	"\x13ListSymbolsResponse\x12\x10\n" +
+	"\x03doc\x18\x01 \x01(\tR\x03doc\x126\n" +
+	"\asymbols\x18\x02 \x03(\v2\x1c.pkgsyms.grpcsyms.SymbolInfoR\asymbols\"@\n" +
+	"\x10GetSymbolRequest\x12\x18\n" +
+	"\apackage\x18\x01 \x01(\tR\apackage\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"\x94\x01\n" +
+	"\n" +
+	"SymbolInfo\x12\x18\n" +
+	"\apackage\x18\x01 \x01(\tR\apackage\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04kind\x18\x03 \x01(\tR\x04kind\x12\x1c\n" +
+	"\tsignature\x18\x04 \x01(\tR\tsignature\x12\x10\n" +
+	"\x03doc\x18\x05 \x01(\tR\x03doc\x12\x14\n" +
+	"\x05value\x18\x06 \x01(\fR\x05value\"S\n" +
+	"\x0fCallFuncRequest\x12\x18\n" +
+	"\apackage\x18\x01 \x01(\tR\apackage\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04args\x18\x03 \x01(\fR\x04args\",\n" +
+	"\x10CallFuncResponse\x12\x18\n" +
+	"\aresults\x18\x01 \x01(\fR\aresults2\xe7\x02\n" +
+	"\bRegistry\x12]\n" +
+	"\fListPackages\x12%.pkgsyms.grpcsyms.ListPackagesRequest\x1a&.pkgsyms.grpcsyms.ListPackagesResponse\x12Z\n" +
+	"\vListSymbols\x12$.pkgsyms.grpcsyms.ListSymbolsRequest\x1a%.pkgsyms.grpcsyms.ListSymbolsResponse\x12M\n" +
+	"\tGetSymbol\x12\".pkgsyms.grpcsyms.GetSymbolRequest\x1a\x1c.pkgsyms.grpcsyms.SymbolInfo\x12Q\n" +
+	"\bCallFunc\x12!.pkgsyms.grpcsyms.CallFuncRequest\x1a\".pkgsyms.grpcsyms.CallFuncResponseB&Z$github.com/skillian/pkgsyms/grpcsymsb\x06proto3"
+
+var (
+	file_grpcsyms_proto_rawDescOnce sync.Once
+	file_grpcsyms_proto_rawDescData []byte
+)
+
+func file_grpcsyms_proto_rawDescGZIP() []byte {
+	file_grpcsyms_proto_rawDescOnce.Do(func() {
+		file_grpcsyms_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_grpcsyms_proto_rawDesc), len(file_grpcsyms_proto_rawDesc)))
+	})
+	return file_grpcsyms_proto_rawDescData
+}
+
+var file_grpcsyms_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_grpcsyms_proto_goTypes = []any{
+	(*ListPackagesRequest)(nil),  // 0: pkgsyms.grpcsyms.ListPackagesRequest
+	(*ListPackagesResponse)(nil), // 1: pkgsyms.grpcsyms.ListPackagesResponse
+	(*ListSymbolsRequest)(nil),   // 2: pkgsyms.grpcsyms.ListSymbolsRequest
+	(*ListSymbolsResponse)(nil),  // 3: pkgsyms.grpcsyms.ListSymbolsResponse
+	(*GetSymbolRequest)(nil),     // 4: pkgsyms.grpcsyms.GetSymbolRequest
+	(*SymbolInfo)(nil),           // 5: pkgsyms.grpcsyms.SymbolInfo
+	(*CallFuncRequest)(nil),      // 6: pkgsyms.grpcsyms.CallFuncRequest
+	(*CallFuncResponse)(nil),     // 7: pkgsyms.grpcsyms.CallFuncResponse
+}
+var file_grpcsyms_proto_depIdxs = []int32{
+	5, // 0: pkgsyms.grpcsyms.ListSymbolsResponse.symbols:type_name -> pkgsyms.grpcsyms.SymbolInfo
+	0, // 1: pkgsyms.grpcsyms.Registry.ListPackages:input_type -> pkgsyms.grpcsyms.ListPackagesRequest
+	2, // 2: pkgsyms.grpcsyms.Registry.ListSymbols:input_type -> pkgsyms.grpcsyms.ListSymbolsRequest
+	4, // 3: pkgsyms.grpcsyms.Registry.GetSymbol:input_type -> pkgsyms.grpcsyms.GetSymbolRequest
+	6, // 4: pkgsyms.grpcsyms.Registry.CallFunc:input_type -> pkgsyms.grpcsyms.CallFuncRequest
+	1, // 5: pkgsyms.grpcsyms.Registry.ListPackages:output_type -> pkgsyms.grpcsyms.ListPackagesResponse
+	3, // 6: pkgsyms.grpcsyms.Registry.ListSymbols:output_type -> pkgsyms.grpcsyms.ListSymbolsResponse
+	5, // 7: pkgsyms.grpcsyms.Registry.GetSymbol:output_type -> pkgsyms.grpcsyms.SymbolInfo
+	7, // 8: pkgsyms.grpcsyms.Registry.CallFunc:output_type -> pkgsyms.grpcsyms.CallFuncResponse
+	5, // [5:9] is the sub-list for method output_type
+	1, // [1:5] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_grpcsyms_proto_init() }
+func file_grpcsyms_proto_init() {
+	if File_grpcsyms_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_grpcsyms_proto_rawDesc), len(file_grpcsyms_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_grpcsyms_proto_goTypes,
+		DependencyIndexes: file_grpcsyms_proto_depIdxs,
+		MessageInfos:      file_grpcsyms_proto_msgTypes,
+	}.Build()
+	File_grpcsyms_proto = out.File
+	file_grpcsyms_proto_goTypes = nil
+	file_grpcsyms_proto_depIdxs = nil
+}
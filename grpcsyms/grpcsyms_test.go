@@ -0,0 +1,112 @@
+package grpcsyms_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/grpcsyms"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const pkgName = "github.com/skillian/pkgsyms/grpcsyms_test"
+
+// serve reg over an in-memory connection and get a client of it.
+func serve(t *testing.T, reg *pkgsyms.Registry) *grpcsyms.Client {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	grpcsyms.RegisterRegistryServer(s, &grpcsyms.Server{Registry: reg})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	cc, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cc.Close() })
+	return grpcsyms.NewClient(cc)
+}
+
+func TestClient(t *testing.T) {
+	reg := pkgsyms.NewRegistry()
+	p := reg.Of(pkgName)
+	p.Add(
+		pkgsyms.MakeFunc("Join", strings.Join).WithDoc("Join joins strings."),
+		pkgsyms.MakeConst("Answer", 42),
+	)
+	c := serve(t, reg)
+	ctx := context.Background()
+
+	names, err := c.Packages(ctx)
+	if err != nil || len(names) != 1 || names[0] != pkgName {
+		t.Fatalf("expected [%s] but got %v, %v", pkgName, names, err)
+	}
+	syms, err := c.Symbols(ctx, pkgName)
+	if err != nil || len(syms) != 2 {
+		t.Fatalf("expected 2 symbols but got %v, %v", syms, err)
+	}
+	join, err := c.Lookup(ctx, pkgName, "Join")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if join.Kind() != "Func" || join.Doc() != "Join joins strings." || join.Signature() != "func([]string, string) string" {
+		t.Fatalf("unexpected description of Join: %s %s %q", join.Kind(), join.Signature(), join.Doc())
+	}
+	res, err := join.Call(ctx, []string{"a", "b"}, "-")
+	if err != nil || len(res) != 1 || res[0] != "a-b" {
+		t.Fatalf("expected [a-b] but got %v, %v", res, err)
+	}
+	answer, err := c.Lookup(ctx, pkgName, "Answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := answer.Get(); v != 42.0 {
+		t.Fatalf("expected 42 but got %#v", v)
+	}
+	if _, err := c.Lookup(ctx, pkgName, "Missing"); !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected NotFound but got %v", err)
+	}
+	if _, err := answer.Call(ctx); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected calling a Const to fail but got %v", err)
+	}
+}
+
+func TestServerAuthorizer(t *testing.T) {
+	reg := pkgsyms.NewRegistry()
+	p := reg.Of(pkgName)
+	p.Add(
+		pkgsyms.MakeFunc("Join", strings.Join),
+		pkgsyms.MakeConst("Secret", "hunter2"),
+		pkgsyms.MakeConst("Answer", 42),
+	)
+	p.SetAuthorizer(func(sym pkgsyms.Symbol, caller interface{}) error {
+		if caller == nil {
+			return errors.New("no peer")
+		}
+		if sym.Name() == "Answer" {
+			return nil
+		}
+		return errors.New("denied")
+	})
+	c := serve(t, reg)
+	if _, err := c.Lookup(context.Background(), pkgName, "Join"); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied but got %v", err)
+	}
+	syms, err := c.Symbols(context.Background(), pkgName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(syms) != 1 || syms[0].Name() != "Answer" {
+		t.Fatalf("expected only Answer to be listed but got %v", syms)
+	}
+}
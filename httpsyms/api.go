@@ -0,0 +1,49 @@
+package httpsyms
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// openAPIName is the name that API serves the OpenAPI document at.
+const openAPIName = "openapi.json"
+
+// API serves the functions of p as the JSON API that
+// (*pkgsyms.Package).OpenAPI describes:  POSTing a JSON array of arguments
+// to prefix + "/" + a function's name calls it like Handler does and GETting
+// prefix + "/openapi.json" serves the document.  It's mounted at the prefix:
+//
+//	mux.Handle("/api/", httpsyms.API(p, "/api"))
+func API(p *pkgsyms.Package, prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, prefix+"/")
+		if name == r.URL.Path || name == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if name == openAPIName && r.Method == http.MethodGet {
+			doc, err := p.OpenAPI(prefix)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(doc)
+			return
+		}
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "functions are called with POST", http.StatusMethodNotAllowed)
+			return
+		}
+		s, err := p.Lookup(name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		serveCall(w, r, s)
+	})
+}
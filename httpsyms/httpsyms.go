@@ -0,0 +1,265 @@
+// Package httpsyms serves the pkgsyms registry over HTTP so that the packages
+// and symbols linked into a running binary can be browsed like godoc.
+package httpsyms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// Handler serves a browsable HTML index of the registry.  Without any query
+// parameters, it lists every registered package.  The "pkg" query parameter
+// selects a package to list the symbols of and adding a "sym" parameter
+// shows a single symbol.  Instead of "pkg" and "sym", an "id" parameter can
+// select a package or symbol by its identity string (see pkgsyms.ID).
+// Large packages can be listed a page at a time with the "offset" and "limit"
+// parameters, and in the order given by a "sort" parameter of "added" (the
+// default), "name" or "kind" (see (*pkgsyms.Symbols).Page).
+//
+// Adding "format=json" to the query (or requesting application/json in the
+// Accept header) serves the same information as JSON.  POSTing a JSON array
+// of arguments to a Func symbol calls the function with
+// (pkgsyms.Func).CallJSON and responds with a JSON array of its results.  An
+// error returned by the function fails the request.
+//
+// The pkgsyms command's query subcommand expects the handler to be mounted at
+// /debug/pkgsyms/:
+//
+//	http.Handle("/debug/pkgsyms/", httpsyms.Handler())
+func Handler() http.Handler {
+	return http.HandlerFunc(serve)
+}
+
+func serve(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	pkgName, symName := q.Get("pkg"), q.Get("sym")
+	if id := q.Get("id"); id != "" {
+		var err error
+		if pkgName, symName, err = pkgsyms.ParseID(id); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	asJSON := q.Get("format") == "json" ||
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+	if pkgName == "" {
+		var pkgs []packageInfo
+		for _, p := range pkgsyms.Packages() {
+			pkgs = append(pkgs, packageInfo{Name: p.Name})
+		}
+		render(w, asJSON, indexTemplate, pkgs)
+		return
+	}
+	p, err := pkgsyms.Lookup(pkgName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if symName == "" {
+		offset, limit, order, err := pageOf(q)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		info := packageInfo{Name: p.Name, Doc: p.Doc()}
+		for _, s := range p.Page(offset, limit, order) {
+			// Only reveal the values of the symbols that Lookup
+			// would.
+			allowed := p.Authorize(s, nil) == nil
+			info.Symbols = append(info.Symbols, infoOf(p.Name, s, allowed))
+		}
+		render(w, asJSON, packageTemplate, info)
+		return
+	}
+	s, err := p.Lookup(symName)
+	if err != nil {
+		http.Error(w, pkgsyms.NotFound{Pkg: pkgName, Sym: symName}.Error(), http.StatusNotFound)
+		return
+	}
+	if r.Method == http.MethodPost {
+		serveCall(w, r, s)
+		return
+	}
+	render(w, asJSON, symbolTemplate, struct {
+		Package string `json:"package"`
+		symbolInfo
+	}{p.Name, infoOf(p.Name, s, true)})
+}
+
+// pageOf gets the page of symbols selected by the query.
+func pageOf(q url.Values) (offset, limit int, order pkgsyms.SortOrder, err error) {
+	for _, param := range []struct {
+		name string
+		n    *int
+	}{{"offset", &offset}, {"limit", &limit}} {
+		if s := q.Get(param.name); s != "" {
+			if *param.n, err = strconv.Atoi(s); err != nil {
+				return 0, 0, 0, fmt.Errorf("invalid %s: %w", param.name, err)
+			}
+		}
+	}
+	if s := q.Get("sort"); s != "" {
+		if order, err = pkgsyms.ParseSortOrder(s); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+	return offset, limit, order, nil
+}
+
+func render(w http.ResponseWriter, asJSON bool, t *template.Template, data interface{}) {
+	if asJSON {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serveCall calls a Func symbol with the JSON array of arguments in the
+// request body using (pkgsyms.Func).CallJSON.  Arguments that the function
+// can't be called with fail the call with http.StatusBadRequest and any other
+// error, including one returned by the function, with
+// http.StatusInternalServerError.
+func serveCall(w http.ResponseWriter, r *http.Request, s pkgsyms.Symbol) {
+	f, ok := pkgsyms.AsFunc(s)
+	if !ok {
+		http.Error(w, fmt.Sprintf(
+			"cannot call %s %q", pkgsyms.KindOf(s), s.Name()),
+			http.StatusMethodNotAllowed)
+		return
+	}
+	args, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(bytes.TrimSpace(args)) == 0 {
+		args = []byte("[]")
+	}
+	results, err := f.CallJSON(args)
+	if err != nil {
+		code := http.StatusInternalServerError
+		var ae pkgsyms.ArgumentError
+		if errors.As(err, &ae) {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(results, '\n'))
+}
+
+type packageInfo struct {
+	Name    string       `json:"name"`
+	Doc     string       `json:"doc,omitempty"`
+	Symbols []symbolInfo `json:"symbols,omitempty"`
+}
+
+// symbolInfo describes a symbol for display.
+type symbolInfo struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Signature string `json:"signature"`
+	Doc       string `json:"doc,omitempty"`
+
+	// Group is a constant's Group.
+	Group string `json:"group,omitempty"`
+
+	// Value is the JSON encoding of a constant's or variable's value.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// infoOf describes a symbol, including the value of a constant or variable if
+// withValue is true.
+func infoOf(pkg string, s pkgsyms.Symbol, withValue bool) symbolInfo {
+	info := symbolInfo{
+		ID:        pkgsyms.ID(pkg, s.Name()),
+		Name:      s.Name(),
+		Kind:      pkgsyms.KindOf(s).String(),
+		Signature: signature(s),
+		Doc:       pkgsyms.Doc(s),
+	}
+	if c, ok := s.(pkgsyms.Const); ok {
+		info.Group = c.Group()
+	}
+	switch s.(type) {
+	case pkgsyms.Const, pkgsyms.Var:
+		if !withValue {
+			break
+		}
+		if v, err := pkgsyms.GetValue(s); err == nil {
+			info.Value, _ = json.Marshal(v)
+		}
+	}
+	return info
+}
+
+// signature describes the type of a symbol.  Types are described by their
+// underlying type because their name is already the symbol's name.
+func signature(s pkgsyms.Symbol) string {
+	switch s := s.(type) {
+	case pkgsyms.Type:
+		return s.Underlying()
+	case pkgsyms.Var:
+		return s.Type().String()
+	case pkgsyms.Generic:
+		return s.String()
+	case pkgsyms.Constraint:
+		return s.Get().(string)
+	}
+	if v := s.Get(); v != nil {
+		return reflect.TypeOf(v).String()
+	}
+	return "nil"
+}
+
+const header = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{template "title" .}}</title></head><body>
+`
+
+var (
+	indexTemplate = template.Must(template.New("index").Parse(header + `
+{{define "title"}}Packages{{end}}
+<h1>Packages</h1>
+<ul>
+{{range .}}<li><a href="?pkg={{.Name}}">{{.Name}}</a></li>
+{{end}}</ul>
+</body></html>`))
+
+	packageTemplate = template.Must(template.New("package").Parse(header + `
+{{define "title"}}{{.Name}}{{end}}
+<p><a href="?">Packages</a></p>
+<h1>package {{.Name}}</h1>
+{{with .Doc}}<p>{{.}}</p>
+{{end}}<dl>
+{{$pkg := .Name}}{{range .Symbols}}<dt>{{.Kind}} <a href="?pkg={{$pkg}}&amp;sym={{.Name}}">{{.Name}}</a> <code>{{.Signature}}</code></dt>
+<dd>{{.Doc}}</dd>
+{{end}}</dl>
+</body></html>`))
+
+	symbolTemplate = template.Must(template.New("symbol").Parse(header + `
+{{define "title"}}{{.Package}}.{{.Name}}{{end}}
+<p><a href="?">Packages</a> / <a href="?pkg={{.Package}}">{{.Package}}</a></p>
+<h1>{{.Kind}} {{.Name}}</h1>
+<pre>{{.Signature}}</pre>
+<p>{{.Doc}}</p>
+</body></html>`))
+)
@@ -0,0 +1,221 @@
+package httpsyms_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/httpsyms"
+)
+
+func TestHandler(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/httpsyms_test")
+	p.Add(pkgsyms.MakeFunc("Join", strings.Join).WithDoc("Join joins strings."))
+	rec := httptest.NewRecorder()
+	httpsyms.Handler().ServeHTTP(rec, httptest.NewRequest(
+		"GET", "/?pkg=github.com/skillian/pkgsyms/httpsyms_test", nil))
+	body := rec.Body.String()
+	for _, want := range []string{"Join joins strings.", "func([]string, string) string"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestHandlerPage(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/httpsyms_test/page")
+	p.Add(
+		pkgsyms.MakeConst("C", 3),
+		pkgsyms.MakeConst("A", 1),
+		pkgsyms.MakeConst("B", 2),
+	)
+	rec := httptest.NewRecorder()
+	httpsyms.Handler().ServeHTTP(rec, httptest.NewRequest(
+		"GET", "/?format=json&sort=name&offset=1&limit=1&pkg="+url.QueryEscape(p.Name), nil))
+	var info struct {
+		Symbols []struct{ Name string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Symbols) != 1 || info.Symbols[0].Name != "B" {
+		t.Fatalf("expected only B but got %+v", info.Symbols)
+	}
+	rec = httptest.NewRecorder()
+	httpsyms.Handler().ServeHTTP(rec, httptest.NewRequest(
+		"GET", "/?sort=size&pkg="+url.QueryEscape(p.Name), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected an unknown sort order to be a bad request, got %d", rec.Code)
+	}
+}
+
+func TestHandlerListAuthorized(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/httpsyms_test/authorized")
+	secret, answer := "hunter2", 42
+	p.Add(pkgsyms.MakeVar("Secret", &secret), pkgsyms.MakeVar("Answer", &answer))
+	p.SetAuthorizer(func(sym pkgsyms.Symbol, caller interface{}) error {
+		if sym.Name() == "Secret" {
+			return errors.New("denied")
+		}
+		return nil
+	})
+	rec := httptest.NewRecorder()
+	httpsyms.Handler().ServeHTTP(rec, httptest.NewRequest(
+		"GET", "/?format=json&pkg="+url.QueryEscape(p.Name), nil))
+	body := rec.Body.String()
+	if strings.Contains(body, secret) || !strings.Contains(body, `"value":42`) {
+		t.Fatalf("expected only the value of Answer in:\n%s", body)
+	}
+}
+
+func TestHandlerCall(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/httpsyms_test")
+	p.Add(pkgsyms.MakeFunc("Join", strings.Join))
+	rec := httptest.NewRecorder()
+	httpsyms.Handler().ServeHTTP(rec, httptest.NewRequest(
+		"POST", "/?pkg=github.com/skillian/pkgsyms/httpsyms_test&sym=Join",
+		strings.NewReader(`[["a", "b"], "-"]`)))
+	if body := strings.TrimSpace(rec.Body.String()); body != `["a-b"]` {
+		t.Fatalf("expected %q but got %q", `["a-b"]`, body)
+	}
+}
+
+func TestHandlerCallError(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/httpsyms_test/callerror")
+	p.Add(pkgsyms.MakeFunc("Atoi", strconv.Atoi))
+	for _, tc := range []struct {
+		args string
+		code int
+	}{
+		{`["42"]`, http.StatusOK},
+		{`["x"]`, http.StatusInternalServerError},
+		{`[42]`, http.StatusBadRequest},
+		{`["1", "2"]`, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		httpsyms.Handler().ServeHTTP(rec, httptest.NewRequest(
+			"POST", "/?pkg="+url.QueryEscape(p.Name)+"&sym=Atoi", strings.NewReader(tc.args)))
+		if rec.Code != tc.code {
+			t.Errorf("%s: expected %d but got %d: %s", tc.args, tc.code, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestAPI(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms/httpsyms_test/api")
+	p.Add(pkgsyms.MakeFunc("Atoi", strconv.Atoi))
+	api := httpsyms.API(p, "/api/")
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	var doc struct {
+		Paths map[string]struct {
+			Post struct {
+				Responses map[string]interface{}
+			}
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("%v: %s", err, rec.Body)
+	}
+	if len(doc.Paths) != 1 {
+		t.Fatalf("expected one path but got %v", doc.Paths)
+	}
+	for path, item := range doc.Paths {
+		for _, tc := range []struct {
+			args string
+			code int
+		}{
+			{`["42"]`, http.StatusOK},
+			{`[42]`, http.StatusBadRequest},
+			{`["x"]`, http.StatusInternalServerError},
+		} {
+			if item.Post.Responses[strconv.Itoa(tc.code)] == nil {
+				t.Errorf("expected the %d response to be described", tc.code)
+			}
+			rec := httptest.NewRecorder()
+			api.ServeHTTP(rec, httptest.NewRequest("POST", path, strings.NewReader(tc.args)))
+			if rec.Code != tc.code {
+				t.Errorf("%s %s: expected %d but got %d: %s", path, tc.args, tc.code, rec.Code, rec.Body)
+			}
+		}
+	}
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest("GET", "/api/Atoi", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET to be rejected but got %d", rec.Code)
+	}
+}
+
+func TestHandlerCallNotFunc(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/httpsyms_test/notfunc")
+	p.Add(
+		pkgsyms.MakeFunc("Nil", (func())(nil)),
+		pkgsyms.MakeFunc("Info", struct{ Name string }{"x"}),
+	)
+	for _, name := range []string{"Nil", "Info"} {
+		rec := httptest.NewRecorder()
+		httpsyms.Handler().ServeHTTP(rec, httptest.NewRequest(
+			"POST", "/?pkg="+url.QueryEscape(p.Name)+"&sym="+name, strings.NewReader(`[]`)))
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("expected calling %s to fail with %d but got %d: %s",
+				name, http.StatusInternalServerError, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestHandlerCallPanic(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/httpsyms_test/panic")
+	p.Add(pkgsyms.MakeFunc("Panic", func() { panic("oops") }))
+	rec := httptest.NewRecorder()
+	httpsyms.Handler().ServeHTTP(rec, httptest.NewRequest(
+		"POST", "/?pkg="+url.QueryEscape(p.Name)+"&sym=Panic", strings.NewReader(`[]`)))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "oops") {
+		t.Fatalf("expected the panic to fail with %d but got %d: %s",
+			http.StatusInternalServerError, rec.Code, rec.Body)
+	}
+}
+
+func TestHandlerID(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/httpsyms_test")
+	p.Add(pkgsyms.MakeFunc("Join", strings.Join))
+	id := pkgsyms.ID(p.Name, "Join")
+	rec := httptest.NewRecorder()
+	httpsyms.Handler().ServeHTTP(rec, httptest.NewRequest(
+		"GET", "/?format=json&id="+url.QueryEscape(id), nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"id":`+strconv.Quote(id)) {
+		t.Fatalf("expected ID %s in:\n%s", id, body)
+	}
+}
+
+func TestMount(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/httpsyms_test/mount")
+	p.Add(
+		pkgsyms.MakeFunc("Hello", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		}),
+		pkgsyms.MakeFunc("Bye", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("bye"))
+		})),
+		pkgsyms.MakeFunc("Join", strings.Join),
+	)
+	mux := http.NewServeMux()
+	paths := httpsyms.Mount(mux, p, httpsyms.Prefix("/api/"), httpsyms.PathFunc(func(name string) string {
+		return "/" + strings.ToLower(name)
+	}))
+	if strings.Join(paths, ",") != "/api/hello,/api/bye" {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+	for path, want := range map[string]string{"/api/hello": "hello", "/api/bye": "bye"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if body := rec.Body.String(); body != want {
+			t.Errorf("%s: expected %q but got %q", path, want, body)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package httpsyms
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// MountOption configures Mount.
+type MountOption func(c *mountConfig)
+
+type mountConfig struct {
+	prefix string
+	path   func(name string) string
+}
+
+// Prefix mounts the handlers under the path prefix, like "/api".
+func Prefix(prefix string) MountOption {
+	return func(c *mountConfig) {
+		c.prefix = strings.TrimSuffix(prefix, "/")
+	}
+}
+
+// PathFunc sets the function that derives a handler's path from its symbol
+// name.  The path is joined to the prefix.  The default is "/" followed by
+// the name.  Returning an empty string skips the handler.
+func PathFunc(f func(name string) string) MountOption {
+	return func(c *mountConfig) {
+		c.path = f
+	}
+}
+
+// Mount registers each Func in p whose signature is compatible with
+// http.HandlerFunc with mux and returns the paths they were mounted at.
+// This is the "plugin-like" way of consuming a generated registry:  Handlers
+// added to the package are served without the host naming them.  Handlers
+// that the package's Authorizer denies when Mount is called aren't mounted.
+func Mount(mux *http.ServeMux, p *pkgsyms.Package, options ...MountOption) []string {
+	c := mountConfig{path: func(name string) string { return "/" + name }}
+	for _, o := range options {
+		o(&c)
+	}
+	var paths []string
+	p.RangeAs(nil, func(s pkgsyms.Symbol) bool {
+		f, ok := pkgsyms.AsFunc(s)
+		if !ok {
+			return true
+		}
+		var h http.Handler
+		switch fv := f.Get().(type) {
+		case http.HandlerFunc:
+			h = fv
+		case func(http.ResponseWriter, *http.Request):
+			h = http.HandlerFunc(fv)
+		default:
+			return true
+		}
+		name := c.path(f.Name())
+		if name == "" {
+			return true
+		}
+		path := c.prefix + name
+		mux.Handle(path, h)
+		paths = append(paths, path)
+		return true
+	})
+	return paths
+}
@@ -0,0 +1,138 @@
+package pkgsyms
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ID gets the canonical identity string of a symbol, like
+// "github.com/acme/foo".Handler.  The package name is quoted like an import
+// path so that the identity is unambiguous even when the package name
+// contains dots.  Without a symbol name, the identity is just the quoted
+// package name.
+func ID(pkg, sym string) string {
+	id := strconv.Quote(pkg)
+	if sym != "" {
+		id += "." + sym
+	}
+	return id
+}
+
+// ParseID parses an identity string created by ID into its package and
+// symbol names.
+func ParseID(id string) (pkg, sym string, err error) {
+	q, err := strconv.QuotedPrefix(id)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid symbol ID %q: %w", id, err)
+	}
+	if pkg, err = strconv.Unquote(q); err != nil {
+		return "", "", fmt.Errorf("invalid symbol ID %q: %w", id, err)
+	}
+	rest := id[len(q):]
+	if rest == "" {
+		return pkg, "", nil
+	}
+	// Names can be qualified, like the pkg.Name of MergeQualify, so
+	// anything after the dot is the name.
+	if rest[0] != '.' || len(rest) == 1 {
+		return "", "", fmt.Errorf(
+			"invalid symbol ID %q: expected .Name after the package", id)
+	}
+	return pkg, rest[1:], nil
+}
+
+// ID gets the canonical identity string of the symbol.  See ID.
+func (rs ResolvedSymbol) ID() string { return ID(rs.Package.Name, rs.Symbol.Name()) }
+
+// Resolve looks up the symbol with the given identity string.
+func Resolve(id string) (ResolvedSymbol, error) {
+	pkg, sym, err := ParseID(id)
+	if err != nil {
+		return ResolvedSymbol{}, err
+	}
+	if sym == "" {
+		return ResolvedSymbol{}, fmt.Errorf(
+			"invalid symbol ID %q: missing symbol name", id)
+	}
+	p, err := Lookup(pkg)
+	if err != nil {
+		return ResolvedSymbol{}, err
+	}
+	s, err := p.Lookup(sym)
+	if err != nil {
+		return ResolvedSymbol{}, err
+	}
+	return ResolvedSymbol{Package: p, Symbol: s}, nil
+}
+
+// WireID is a compact, stable identifier of a symbol for wire protocols.  It's
+// derived only from the symbol's package, kind and name, so every build of a
+// program, and every version of it that still has the symbol, agree on it.
+// A symbol whose kind changes gets a new WireID, so peers never mistake it
+// for the old one.
+type WireID uint64
+
+// MakeWireID computes the WireID of the symbol of the given kind and name in
+// the package.  It's the first 8 bytes, big-endian, of the SHA-256 hash of
+// ID(pkg, name) + " " + kind.String().
+func MakeWireID(pkg string, kind Kind, name string) WireID {
+	sum := sha256.Sum256([]byte(ID(pkg, name) + " " + kind.String()))
+	return WireID(binary.BigEndian.Uint64(sum[:8]))
+}
+
+// String formats the ID as 16 hexadecimal digits.
+func (id WireID) String() string { return fmt.Sprintf("%016x", uint64(id)) }
+
+// ParseWireID parses a WireID formatted by its String method.
+func ParseWireID(s string) (WireID, error) {
+	if len(s) != 16 {
+		return 0, fmt.Errorf("invalid wire ID %q: expected 16 hex digits", s)
+	}
+	u, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid wire ID %q: %w", s, err)
+	}
+	return WireID(u), nil
+}
+
+// WireID gets the symbol's WireID.
+func (rs ResolvedSymbol) WireID() WireID {
+	return MakeWireID(rs.Package.Name, KindOf(rs.Symbol), rs.Symbol.Name())
+}
+
+// LookupWireID looks up the symbol in the package with the given WireID.  It
+// returns NotFound if there is none.
+func (p *Package) LookupWireID(id WireID) (Symbol, error) {
+	var found Symbol
+	p.Range(func(s Symbol) bool {
+		if MakeWireID(p.Name, KindOf(s), s.Name()) == id {
+			found = s
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return nil, NotFound{Pkg: p.Name, Sym: id.String()}
+	}
+	return p.LookupAs(nil, found.Name())
+}
+
+// ResolveWireID looks up the symbol with the given WireID in every registered
+// package.
+func ResolveWireID(id WireID) (ResolvedSymbol, error) { return global.ResolveWireID(id) }
+
+// ResolveWireID looks up the symbol with the given WireID in every package in
+// the registry.
+func (r *Registry) ResolveWireID(id WireID) (ResolvedSymbol, error) {
+	for _, p := range r.Packages() {
+		if s, err := p.LookupWireID(id); err == nil {
+			return ResolvedSymbol{Package: p, Symbol: s}, nil
+		} else if !errors.Is(err, ErrNotFound) {
+			return ResolvedSymbol{}, err
+		}
+	}
+	return ResolvedSymbol{}, NotFound{Sym: id.String()}
+}
@@ -0,0 +1,13 @@
+// Command plugin is built with -buildmode=plugin by the benchmarks that
+// compare pkgsyms against the plugin package.
+package main
+
+import "github.com/skillian/pkgsyms/internal/testsyms"
+
+// Counter is a variable.
+var Counter int
+
+// Add is a function.
+func Add(a, b int) int { return testsyms.Add(a, b) }
+
+func main() {}
@@ -0,0 +1,19 @@
+// Package testsyms is a fixture for the pkgsyms tests and benchmarks.  Its
+// registry is generated by the pkgsyms command.
+package testsyms
+
+//go:generate go run ../../pkgsyms -output=pkgsyms.go
+
+// Answer is a constant.
+const Answer = 42
+
+// Counter is a variable.
+var Counter int
+
+// Point is a type.
+type Point struct {
+	X, Y int
+}
+
+// Add is a function.
+func Add(a, b int) int { return a + b }
@@ -0,0 +1,86 @@
+package pkgsyms
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// EventKind identifies what happened in an Event.
+type EventKind int
+
+const (
+	// EventAdd is logged for each symbol added to a set.
+	EventAdd EventKind = iota
+
+	// EventLookupHit is logged when (*Package).Lookup finds a symbol.
+	EventLookupHit
+
+	// EventLookupMiss is logged when (*Package).Lookup doesn't find a
+	// symbol, even after consulting the Providers.
+	EventLookupMiss
+
+	// EventRemove is logged for each symbol removed from a set.
+	EventRemove
+
+	// EventReady is logged when a package is first marked ready.
+	EventReady
+
+	// EventCall is logged when a call with (Func).Call returns.  Only
+	// Funcs looked up in a Package know its name.
+	EventCall
+
+	// EventNil is logged when the value of a nil Var or Func is gotten
+	// in a registry with strict nil checks.
+	EventNil
+)
+
+var eventKindStrings = []string{"add", "lookup-hit", "lookup-miss", "remove", "ready", "call", "nil"}
+
+func (k EventKind) String() string {
+	if k < 0 || int(k) >= len(eventKindStrings) {
+		return fmt.Sprintf("EventKind(%d)", int(k))
+	}
+	return eventKindStrings[k]
+}
+
+// Event describes an operation on the registry for the function set with
+// SetLogger.
+type Event struct {
+	Kind EventKind
+
+	// Pkg is the name of the package.  It's empty for sets of symbols
+	// that don't belong to a package, like the ones made with
+	// MakeSymbols.
+	Pkg string
+
+	// Sym is the symbol's name.  It's empty for EventReady.
+	Sym string
+
+	// Duration of the call for EventCall.
+	Duration time.Duration
+}
+
+var logger atomic.Value
+
+// SetLogger sets the function that's called with the registry's events, for
+// tracing dynamic symbol activity while debugging.  A nil function stops
+// logging.  The function is called synchronously, while adding and removing
+// symbols with the set locked, so it must not add symbols to or remove
+// symbols from the set that the event is about.
+func SetLogger(log func(event Event)) {
+	logger.Store(log)
+}
+
+// logEvent calls the function set with SetLogger, if any.
+func logEvent(kind EventKind, pkg, sym string) {
+	if log := loadLogger(); log != nil {
+		log(Event{Kind: kind, Pkg: pkg, Sym: sym})
+	}
+}
+
+// loadLogger gets the function set with SetLogger, if any.
+func loadLogger() func(Event) {
+	log, _ := logger.Load().(func(Event))
+	return log
+}
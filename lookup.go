@@ -0,0 +1,90 @@
+package pkgsyms
+
+import "fmt"
+
+// lookupKind looks up a symbol in the package that must be of the given Kind.
+func (p *Package) lookupKind(name string, want Kind) (Symbol, error) {
+	s, err := p.Lookup(name)
+	if err != nil {
+		if _, ok := err.(Unauthorized); ok {
+			return nil, err
+		}
+		return nil, NotFound{Pkg: p.Name, Sym: name}
+	}
+	if got := KindOf(s); got != want {
+		return nil, WrongKind{Pkg: p.Name, Sym: name, Want: want, Got: got}
+	}
+	return s, nil
+}
+
+// LookupValue looks up a symbol in the package and gets its value with
+// GetValue, so symbols implementing GetterE can report why their value isn't
+// available.
+func (p *Package) LookupValue(name string) (interface{}, error) {
+	s, err := p.Lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	v, err := GetValue(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ID(p.Name, name), err)
+	}
+	return v, nil
+}
+
+// LookupConst looks up a Const in the package.  It returns NotFound if there
+// is no symbol with the given name and WrongKind if the symbol isn't a Const.
+func (p *Package) LookupConst(name string) (Const, error) {
+	s, err := p.lookupKind(name, ConstKind)
+	if err != nil {
+		return Const{}, err
+	}
+	return s.(Const), nil
+}
+
+// LookupFunc looks up a Func in the package.  It returns NotFound if there is
+// no symbol with the given name and WrongKind if the symbol isn't a Func.
+// Variables made Callable are returned as Funcs.
+func (p *Package) LookupFunc(name string) (Func, error) {
+	s, err := p.lookupKind(name, FuncKind)
+	if err != nil {
+		if v, ok := p.callableVar(name); ok {
+			return v, nil
+		}
+		return Func{}, err
+	}
+	return s.(Func), nil
+}
+
+// callableVar gets the Func of a Callable variable.
+func (p *Package) callableVar(name string) (Func, bool) {
+	s, err := p.Lookup(name)
+	if err != nil {
+		return Func{}, false
+	}
+	v, ok := s.(Var)
+	if !ok {
+		return Func{}, false
+	}
+	return v.Func()
+}
+
+// LookupType looks up a Type in the package.  It returns NotFound if there is
+// no symbol with the given name and WrongKind if the symbol isn't a Type.
+func (p *Package) LookupType(name string) (Type, error) {
+	s, err := p.lookupKind(name, TypeKind)
+	if err != nil {
+		return Type{}, err
+	}
+	return s.(Type), nil
+}
+
+// LookupVar looks up a Var in the package.  It returns NotFound if there is
+// no symbol with the given name and WrongKind if the symbol isn't a Var.
+func (p *Package) LookupVar(name string) (Var, error) {
+	s, err := p.lookupKind(name, VarKind)
+	if err != nil {
+		return Var{}, err
+	}
+	return s.(Var), nil
+}
@@ -0,0 +1,147 @@
+package pkgsyms
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Manifest is the JSON document written by the pkgsyms command's -manifest
+// mode.  Instead of generating Go code for every constant and doc comment,
+// they're written into a manifest that's embedded into the package with
+// go:embed and loaded with AddManifest.
+type Manifest struct {
+	// Consts whose types are predeclared Go types.
+	Consts []ManifestConst `json:"consts,omitempty"`
+
+	// Docs maps symbol names to their documentation.
+	Docs map[string]string `json:"docs,omitempty"`
+}
+
+// ManifestConst describes a constant in a Manifest.
+type ManifestConst struct {
+	Name string `json:"name"`
+
+	// Type is the name of one of Go's predeclared boolean, numeric or
+	// string types.
+	Type string `json:"type"`
+
+	// Value of the constant formatted so that it can be parsed with the
+	// strconv package.
+	Value string `json:"value"`
+
+	// Group of the constant (see Const.Group), if any.
+	Group string `json:"group,omitempty"`
+}
+
+// manifestTypes are the types that ManifestConsts can have.
+var manifestTypes = func() map[string]reflect.Type {
+	m := make(map[string]reflect.Type)
+	for _, v := range []interface{}{
+		false, "",
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0), uintptr(0),
+		float32(0), float64(0),
+	} {
+		t := reflect.TypeOf(v)
+		m[t.Name()] = t
+	}
+	m["byte"] = m["uint8"]
+	m["rune"] = m["int32"]
+	return m
+}()
+
+// AddManifest queues a Manifest encoded in JSON or with MarshalCBOR to be
+// added to the set.  The manifest isn't parsed until the set's symbols are
+// first looked up or ranged over.  The manifest's docs are applied to
+// symbols that are already in the set at that time.
+//
+// Manifests are expected to be generated by the pkgsyms command, so a
+// manifest that can't be parsed is a programming error and causes a panic
+// when it's loaded.
+func (syms *Symbols) AddManifest(data []byte) {
+	syms.mutex.Lock()
+	defer syms.mutex.Unlock()
+	syms.manifests = append(syms.manifests, data)
+}
+
+// loadManifests parses and adds any pending manifests to the set.  The
+// caller must hold the mutex.
+func (syms *Symbols) loadManifests() {
+	if len(syms.manifests) == 0 {
+		return
+	}
+	pending := syms.manifests
+	syms.manifests = nil
+	for _, data := range pending {
+		var m Manifest
+		var err error
+		if isCBORMap(data) {
+			err = m.UnmarshalCBOR(data)
+		} else {
+			err = json.Unmarshal(data, &m)
+		}
+		if err != nil {
+			panic(fmt.Errorf("failed to parse pkgsyms manifest: %w", err))
+		}
+		if syms.names == nil {
+			syms.names = make(map[string]int, len(m.Consts))
+		}
+		for _, mc := range m.Consts {
+			c, err := mc.Const()
+			if err != nil {
+				panic(err)
+			}
+			syms.add(c)
+		}
+		if len(m.Docs) > 0 {
+			// Range may be iterating over the old slice, so
+			// don't update it in place.
+			syms.slice = append([]Symbol(nil), syms.slice...)
+			syms.sorted = nil
+		}
+		for name, doc := range m.Docs {
+			if i, ok := syms.names[name]; ok {
+				syms.slice[i] = withDoc(syms.slice[i], doc)
+			}
+		}
+	}
+}
+
+// Const creates the Const described by the ManifestConst.
+func (mc ManifestConst) Const() (Const, error) {
+	t, ok := manifestTypes[mc.Type]
+	if !ok {
+		return Const{}, fmt.Errorf(
+			"constant %q: unsupported type %q", mc.Name, mc.Type)
+	}
+	v := reflect.New(t).Elem()
+	if err := parseInto(v, mc.Value); err != nil {
+		return Const{}, fmt.Errorf("constant %q: %w", mc.Name, err)
+	}
+	c := MakeConst(Intern(mc.Name), v.Interface())
+	if mc.Group != "" {
+		c = c.WithGroup(Intern(mc.Group))
+	}
+	return c, nil
+}
+
+// withDoc sets the documentation of the Symbol implementations defined in
+// this package.  Other Symbols are returned unchanged.
+func withDoc(s Symbol, doc string) Symbol {
+	switch s := s.(type) {
+	case Const:
+		return s.WithDoc(doc)
+	case Func:
+		return s.WithDoc(doc)
+	case Type:
+		return s.WithDoc(doc)
+	case Var:
+		return s.WithDoc(doc)
+	case Generic:
+		return s.WithDoc(doc)
+	case Constraint:
+		return s.WithDoc(doc)
+	}
+	return s
+}
@@ -0,0 +1,106 @@
+package pkgsyms
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+var interned struct {
+	sync.Mutex
+	m map[string]string
+}
+
+// Intern gets a string equal to s that shares its storage with every other
+// interned string equal to s.  Names in generated code are string literals
+// that the linker already deduplicates, but names that are built at run
+// time, like the ones parsed from manifests or created by Providers, are
+// each allocated separately unless they're interned.  Interned strings are
+// kept for the life of the program.
+func Intern(s string) string {
+	interned.Lock()
+	defer interned.Unlock()
+	if v, ok := interned.m[s]; ok {
+		return v
+	}
+	if interned.m == nil {
+		interned.m = make(map[string]string)
+	}
+	interned.m[s] = s
+	return s
+}
+
+// MemStats approximates the memory held by sets of symbols.
+type MemStats struct {
+	// Packages is the number of packages counted by ReadMemStats.
+	Packages int
+
+	Symbols int
+
+	// NameBytes is the size of the symbols' distinct name strings.
+	// Names that share their storage, like interned names, count once.
+	NameBytes int
+
+	// DocBytes is the size of the symbols' documentation.
+	DocBytes int
+
+	// Bytes approximates all of the memory held, including NameBytes,
+	// DocBytes, the Symbol values and the index of their names, but not
+	// what the symbols refer to, like the values of Consts.
+	Bytes int
+}
+
+// Per-symbol overhead of the slice of symbols and the index of their names.
+const (
+	ifaceBytes    = int(unsafe.Sizeof(Symbol(nil)))
+	mapEntryBytes = int(unsafe.Sizeof("")+unsafe.Sizeof(0)) + 8
+)
+
+// memCounter adds symbols to MemStats, counting shared strings once.
+type memCounter struct {
+	MemStats
+	names map[*byte]bool
+}
+
+func (mc *memCounter) count(ss []Symbol) {
+	if mc.names == nil {
+		mc.names = make(map[*byte]bool)
+	}
+	for _, s := range ss {
+		mc.Symbols++
+		mc.Bytes += ifaceBytes + mapEntryBytes + int(reflect.TypeOf(s).Size())
+		name := s.Name()
+		if p := unsafe.StringData(name); p != nil && !mc.names[p] {
+			mc.names[p] = true
+			mc.NameBytes += len(name)
+			mc.Bytes += len(name)
+		}
+		doc := len(Doc(s))
+		mc.DocBytes += doc
+		mc.Bytes += doc
+	}
+}
+
+// MemStats approximates the memory held by the set.  Manifests that
+// haven't been loaded yet aren't counted.
+func (syms *Symbols) MemStats() MemStats {
+	var mc memCounter
+	syms.mutex.Lock()
+	ss := syms.slice
+	syms.mutex.Unlock()
+	mc.count(ss)
+	return mc.MemStats
+}
+
+// ReadMemStats approximates the memory held by every registered package.
+func ReadMemStats() MemStats {
+	var mc memCounter
+	for _, p := range Packages() {
+		mc.Packages++
+		p.mutex.Lock()
+		ss := p.slice
+		p.mutex.Unlock()
+		mc.count(ss)
+	}
+	return mc.MemStats
+}
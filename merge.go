@@ -0,0 +1,200 @@
+package pkgsyms
+
+import (
+	"fmt"
+	"path"
+	"sort"
+)
+
+// MergePolicy decides what (*Symbols).Merge does with a name that's defined
+// by more than one of the packages being merged or that's already in the
+// set.
+type MergePolicy int
+
+const (
+	// MergeError merges nothing and returns a CollisionError if any
+	// names collide.
+	MergeError MergePolicy = iota
+
+	// MergeFirstWins keeps the first symbol with each name, like Add.
+	MergeFirstWins
+
+	// MergeLastWins replaces symbols with the last one with each name.
+	MergeLastWins
+
+	// MergeQualify adds the symbols of the packages with colliding names
+	// as pkg.Name, where pkg is the last element of the package's name,
+	// and doesn't add the ambiguous unqualified name.  Symbols already in
+	// the set keep their names, and Symbol implementations from other
+	// packages can't be renamed, so they're merged like with
+	// MergeFirstWins.
+	MergeQualify
+)
+
+var mergePolicyStrings = []string{"error", "first-wins", "last-wins", "qualify"}
+
+func (p MergePolicy) String() string {
+	if p < 0 || int(p) >= len(mergePolicyStrings) {
+		return fmt.Sprintf("MergePolicy(%d)", int(p))
+	}
+	return mergePolicyStrings[p]
+}
+
+// Merge adds the symbols of the packages to the set, resolving the names
+// that collide by the policy, and returns the collisions.  Names that are
+// only defined by symbols that are Equal don't collide.  With MergeError,
+// nothing is added if there are any collisions and they're returned as a
+// CollisionError.
+func (syms *Symbols) Merge(policy MergePolicy, pkgs ...*Package) ([]Collision, error) {
+	// Packages merged into themselves would deadlock, and everything
+	// would collide, so take snapshots first.
+	snapshots := make([][]Symbol, len(pkgs))
+	for i, p := range pkgs {
+		snapshots[i] = p.snapshot()
+	}
+
+	syms.mutex.Lock()
+	defer syms.mutex.Unlock()
+	syms.loadManifests()
+	if syms.names == nil {
+		syms.names = make(map[string]int)
+	}
+
+	var collisions []Collision
+	byName := make(map[string]int)
+	note := func(name, pkg string) {
+		i, ok := byName[name]
+		if !ok {
+			byName[name] = len(collisions)
+			collisions = append(collisions, Collision{Name: name})
+			i = len(collisions) - 1
+		}
+		collisions[i].Pkgs = append(collisions[i].Pkgs, pkg)
+	}
+	type owner struct {
+		pkg string
+		sym Symbol
+	}
+	owners := make(map[string][]owner)
+	for name, i := range syms.names {
+		owners[name] = []owner{{"", syms.slice[i]}}
+	}
+	for i, p := range pkgs {
+		for _, s := range snapshots[i] {
+			owners[s.Name()] = append(owners[s.Name()], owner{p.Name, s})
+		}
+	}
+	for name, os := range owners {
+		// The same symbol registered more than once isn't a
+		// collision.
+		same := true
+		for _, o := range os[1:] {
+			same = same && Equal(os[0].sym, o.sym)
+		}
+		if !same {
+			for _, o := range os {
+				note(name, o.pkg)
+			}
+		}
+	}
+	sortCollisions(collisions)
+	if len(collisions) > 0 && policy == MergeError {
+		return collisions, CollisionError(collisions)
+	}
+
+	// Range may be iterating over the old slice, so don't update it in
+	// place.
+	syms.slice = append([]Symbol(nil), syms.slice...)
+	syms.sorted = nil
+	for i, p := range pkgs {
+		for _, s := range snapshots[i] {
+			name := s.Name()
+			_, collides := byName[name]
+			switch {
+			case !collides:
+				syms.add(s)
+			case policy == MergeLastWins:
+				if j, ok := syms.names[name]; ok {
+					syms.notify(ChangeRemove, syms.slice[j])
+					syms.slice[j] = s
+					syms.notify(ChangeAdd, s)
+				} else {
+					syms.add(s)
+				}
+			case policy == MergeQualify:
+				if r, ok := renamed(s, path.Base(p.Name)+"."+name); ok {
+					syms.add(r)
+				} else {
+					syms.add(s)
+				}
+			default:
+				syms.add(s)
+			}
+		}
+	}
+	return collisions, nil
+}
+
+// AddAll adds all of the symbols to the set or none of them.  Unlike Add,
+// which skips the names that are already defined, AddAll follows the
+// MergeError policy:  If any of the names is already in the set or is given
+// more than once, nothing is added and the collisions are returned as a
+// CollisionError.  Otherwise, it returns the number of symbols added.
+func (syms *Symbols) AddAll(ss []Symbol) (added int, err error) {
+	syms.mutex.Lock()
+	defer syms.mutex.Unlock()
+	syms.loadManifests()
+	if syms.names == nil {
+		syms.names = make(map[string]int, len(ss))
+	}
+	var collisions []Collision
+	seen := make(map[string]bool, len(ss))
+	for _, s := range ss {
+		name := s.Name()
+		if _, ok := syms.names[name]; ok {
+			collisions = append(collisions, Collision{Name: name, Pkgs: []string{""}})
+		} else if seen[name] {
+			collisions = append(collisions, Collision{Name: name})
+		}
+		seen[name] = true
+	}
+	if len(collisions) > 0 {
+		sortCollisions(collisions)
+		return 0, CollisionError(collisions)
+	}
+	for _, s := range ss {
+		syms.add(s)
+	}
+	return len(ss), nil
+}
+
+// renamed returns a copy of the Symbol implementations defined in this
+// package with another name.
+func renamed(s Symbol, name string) (Symbol, bool) {
+	switch s := s.(type) {
+	case Const:
+		s.name = name
+		return s, true
+	case Func:
+		s.name = name
+		return s, true
+	case Type:
+		s.name = name
+		return s, true
+	case Var:
+		s.name = name
+		return s, true
+	case Generic:
+		s.name = name
+		return s, true
+	case Constraint:
+		s.name = name
+		return s, true
+	}
+	return nil, false
+}
+
+// sortCollisions sorts collisions by their names.
+func sortCollisions(cs []Collision) {
+	sort.Slice(cs, func(i, j int) bool { return cs[i].Name < cs[j].Name })
+}
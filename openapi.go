@@ -0,0 +1,325 @@
+package pkgsyms
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// OpenAPI describes the package's functions as an OpenAPI 3.1 document, so
+// that an HTTP layer calling them with (Func).CallJSON, like httpsyms.API,
+// is self-describing.  Each function is a POST operation on prefix + "/" +
+// its name whose request body is the JSON array of its arguments and whose
+// response is the JSON array of its results, without a final error result.
+// Arguments that the function can't be called with are a 400 response and
+// an error returned by the function is a 500 response, both with the error
+// as plain text.  Struct types are described in the document's
+// components.  Functions with parameters or results that can't be
+// represented in JSON, like channels, are left out, as are functions taking
+// interfaces other than interface{}, and so are the functions that the
+// package's Authorizer denies.  The document's version is the package's
+// Checksum.
+func (p *Package) OpenAPI(prefix string) ([]byte, error) {
+	b := schemaBuilder{defs: make(map[string]interface{})}
+	paths := make(map[string]interface{})
+	p.RangeAs(nil, func(s Symbol) bool {
+		f, ok := AsFunc(s)
+		if !ok {
+			return true
+		}
+		op, ok := b.operation(f)
+		if ok {
+			paths[prefix+"/"+f.name] = map[string]interface{}{"post": op}
+		}
+		return true
+	})
+	info := map[string]interface{}{
+		"title":   p.Name,
+		"version": p.Checksum(),
+	}
+	if doc := p.Doc(); doc != "" {
+		info["description"] = doc
+	}
+	doc := map[string]interface{}{
+		"openapi": "3.1.0",
+		"info":    info,
+		"paths":   paths,
+	}
+	if len(b.defs) > 0 {
+		doc["components"] = map[string]interface{}{"schemas": b.defs}
+	}
+	return json.MarshalIndent(doc, "", "\t")
+}
+
+// JSONSchema gets the JSON schema of the values of type t as they're
+// marshaled by encoding/json.  Struct types are described by references to
+// "#/$defs/Name", which are put into the schema's $defs.  It reports false
+// if t's values can't be represented in JSON.
+func JSONSchema(t reflect.Type) (map[string]interface{}, bool) {
+	b := schemaBuilder{
+		defs:    make(map[string]interface{}),
+		refBase: "#/$defs/",
+	}
+	s, ok := b.schema(t)
+	if !ok {
+		return nil, false
+	}
+	if len(b.defs) > 0 {
+		s["$defs"] = b.defs
+	}
+	return s, true
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// schemaBuilder builds JSON schemas, collecting the schemas of named struct
+// types in defs so that recursive types can refer to themselves.
+type schemaBuilder struct {
+	defs map[string]interface{}
+
+	// refBase is prepended to the names of defs in references.  It's
+	// the OpenAPI components by default.
+	refBase string
+}
+
+// operation describes calling f with CallJSON.
+func (b *schemaBuilder) operation(f Func) (map[string]interface{}, bool) {
+	ft := reflect.TypeOf(f.fval)
+	if ft == nil || ft.Kind() != reflect.Func {
+		return nil, false
+	}
+	params := make([]interface{}, ft.NumIn())
+	for i := range params {
+		s, ok := b.schema(paramType(ft, i))
+		if !ok {
+			return nil, false
+		}
+		params[i] = s
+	}
+	args := map[string]interface{}{"type": "array"}
+	if ft.IsVariadic() {
+		args["items"] = params[len(params)-1]
+		params = params[:len(params)-1]
+	} else {
+		args["maxItems"] = len(params)
+	}
+	if len(params) > 0 {
+		args["prefixItems"] = params
+		args["minItems"] = len(params)
+	}
+	n := ft.NumOut()
+	if n > 0 && ft.Out(n-1) == errorType {
+		n--
+	}
+	results := make([]interface{}, n)
+	for i := range results {
+		s, ok := b.schema(ft.Out(i))
+		if !ok {
+			return nil, false
+		}
+		results[i] = s
+	}
+	resultsSchema := map[string]interface{}{"type": "array", "maxItems": n}
+	if n > 0 {
+		resultsSchema["prefixItems"] = results
+		resultsSchema["minItems"] = n
+	}
+	op := map[string]interface{}{
+		"operationId": f.name,
+		"requestBody": map[string]interface{}{
+			"required": true,
+			"content":  jsonContent(args),
+		},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "The function's results.",
+				"content":     jsonContent(resultsSchema),
+			},
+			"400": map[string]interface{}{
+				"description": "The function can't be called with the arguments.",
+				"content":     textContent,
+			},
+			"500": map[string]interface{}{
+				"description": "The function returned an error or panicked.",
+				"content":     textContent,
+			},
+		},
+	}
+	if f.doc != "" {
+		op["summary"] = strings.SplitN(f.doc, "\n", 2)[0]
+		op["description"] = f.doc
+	}
+	return op, true
+}
+
+// textContent describes the plain text of an error response.
+var textContent = map[string]interface{}{
+	"text/plain": map[string]interface{}{
+		"schema": map[string]interface{}{"type": "string"},
+	},
+}
+
+// jsonContent describes JSON content with the schema.
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// schema gets the JSON schema of t, or false if t's values can't be
+// represented in JSON.
+func (b *schemaBuilder) schema(t reflect.Type) (map[string]interface{}, bool) {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, true
+	case reflect.PtrTo(t).Implements(jsonUnmarshalerType):
+		// Its encoding is up to its methods.
+		return map[string]interface{}{}, true
+	case reflect.PtrTo(t).Implements(textUnmarshalerType):
+		return map[string]interface{}{"type": "string"}, true
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer"}, true
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, true
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, true
+	case reflect.Interface:
+		return map[string]interface{}{}, t.NumMethod() == 0
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}, true
+		}
+		items, ok := b.schema(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		s := map[string]interface{}{"type": "array", "items": items}
+		if t.Kind() == reflect.Array {
+			s["minItems"] = t.Len()
+			s["maxItems"] = t.Len()
+		}
+		return s, true
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !reflect.PtrTo(t.Key()).Implements(textUnmarshalerType) {
+				return nil, false
+			}
+		}
+		values, ok := b.schema(t.Elem())
+		if !ok {
+			return nil, false
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, true
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := defName(t)
+		ref := map[string]interface{}{"$ref": b.ref() + name}
+		if _, ok := b.defs[name]; ok {
+			return ref, true
+		}
+		// Recursive references find the name while the schema is
+		// built.
+		b.defs[name] = nil
+		s, ok := b.structSchema(t)
+		if !ok {
+			delete(b.defs, name)
+			return nil, false
+		}
+		b.defs[name] = s
+		return ref, true
+	}
+	return nil, false
+}
+
+// ref gets the prefix of references to defs.
+func (b *schemaBuilder) ref() string {
+	if b.refBase != "" {
+		return b.refBase
+	}
+	return "#/components/schemas/"
+}
+
+// structSchema describes a struct type's fields as encoding/json sees them.
+func (b *schemaBuilder) structSchema(t reflect.Type) (map[string]interface{}, bool) {
+	props := make(map[string]interface{})
+	if !b.fields(t, props) {
+		return nil, false
+	}
+	return map[string]interface{}{"type": "object", "properties": props}, true
+}
+
+// fields adds the schemas of a struct type's fields to props, flattening
+// untagged embedded structs like encoding/json does.  Fields that can't be
+// represented in JSON make the whole struct unrepresentable because
+// encoding/json fails on them.
+func (b *schemaBuilder) fields(t reflect.Type, props map[string]interface{}) bool {
+	var embedded []reflect.Type
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, ft)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s, ok := b.schema(f.Type)
+		if !ok {
+			return false
+		}
+		props[name] = s
+	}
+	// encoding/json prefers shallower fields, so embedded fields are
+	// only added if they aren't already there.
+	for _, et := range embedded {
+		inner := make(map[string]interface{})
+		if !b.fields(et, inner) {
+			return false
+		}
+		for name, s := range inner {
+			if _, ok := props[name]; !ok {
+				props[name] = s
+			}
+		}
+	}
+	return true
+}
+
+// defNameChars are the characters that OpenAPI allows in component names.
+var defNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// defName gets the name of a named struct type's schema in defs.
+func defName(t reflect.Type) string {
+	return defNameChars.ReplaceAllString(strings.ReplaceAll(t.PkgPath(), "/", ".")+"."+t.Name(), "_")
+}
@@ -0,0 +1,87 @@
+package pkgsyms
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SortOrder is the order that Page lists symbols in.
+type SortOrder int
+
+const (
+	// SortAdded lists symbols in the order they were added, like Range.
+	SortAdded SortOrder = iota
+
+	// SortName lists symbols by name.
+	SortName
+
+	// SortKind lists symbols by kind and then by name.
+	SortKind
+)
+
+var sortOrderStrings = []string{"added", "name", "kind"}
+
+func (o SortOrder) String() string {
+	if o < 0 || int(o) >= len(sortOrderStrings) {
+		return fmt.Sprintf("SortOrder(%d)", int(o))
+	}
+	return sortOrderStrings[o]
+}
+
+// ParseSortOrder parses the string of a SortOrder, like "name".
+func ParseSortOrder(s string) (SortOrder, error) {
+	for i, name := range sortOrderStrings {
+		if s == name {
+			return SortOrder(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown sort order %q", s)
+}
+
+// Page gets up to limit symbols of the set, starting at offset, in the given
+// order, so that large sets can be listed a page at a time.  A limit less than
+// or equal to 0 gets every symbol from offset on.  The sorted orders are
+// computed once and kept until the set changes, so paging through a set
+// doesn't sort or copy all of it for each page.
+func (syms *Symbols) Page(offset, limit int, order SortOrder) []Symbol {
+	ss := syms.ordered(order)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(ss) {
+		return nil
+	}
+	ss = ss[offset:]
+	if limit > 0 && limit < len(ss) {
+		ss = ss[:limit]
+	}
+	return append([]Symbol(nil), ss...)
+}
+
+// ordered gets the symbols of the set in the given order.  The result must not
+// be modified.
+func (syms *Symbols) ordered(order SortOrder) []Symbol {
+	syms.mutex.Lock()
+	defer syms.mutex.Unlock()
+	syms.loadManifests()
+	if order == SortAdded {
+		return syms.slice
+	}
+	if ss, ok := syms.sorted[order]; ok {
+		return ss
+	}
+	ss := append([]Symbol(nil), syms.slice...)
+	sort.SliceStable(ss, func(i, j int) bool {
+		if order == SortKind {
+			if ki, kj := KindOf(ss[i]), KindOf(ss[j]); ki != kj {
+				return ki < kj
+			}
+		}
+		return ss[i].Name() < ss[j].Name()
+	})
+	if syms.sorted == nil {
+		syms.sorted = make(map[SortOrder][]Symbol)
+	}
+	syms.sorted[order] = ss
+	return ss
+}
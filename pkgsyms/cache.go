@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	"github.com/skillian/pkgsyms/pkgsyms/internal/load"
+	"golang.org/x/tools/go/packages"
+)
+
+// generatorID identifies the build of the generator in every cache key so
+// that any change to it, released or not, invalidates the cache.  It's a
+// hash of the running executable or, if that can't be read, of its build
+// information.
+func generatorID() string {
+	generatorIDOnce.Do(func() {
+		h := sha256.New()
+		exe, err := os.Executable()
+		if err == nil {
+			err = hashFile(h, exe)
+		}
+		if err != nil {
+			h.Reset()
+			if bi, ok := debug.ReadBuildInfo(); ok {
+				io.WriteString(h, bi.String())
+			}
+		}
+		generatorIDValue = hex.EncodeToString(h.Sum(nil))
+	})
+	return generatorIDValue
+}
+
+var (
+	generatorIDOnce  sync.Once
+	generatorIDValue string
+)
+
+// hashFile writes the content of the file to h.
+func hashFile(h io.Writer, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// cacheEnv are the environment variables that affect how packages are
+// loaded.
+var cacheEnv = []string{"GOOS", "GOARCH", "GOFLAGS", "CGO_ENABLED", "GOEXPERIMENT"}
+
+// cache stores generated output on disk keyed by a hash of everything the
+// output depends on so that unchanged packages aren't loaded and type-checked
+// again.
+type cache struct {
+	dir string
+}
+
+// cacheEntry is the output of one run of the generator.
+type cacheEntry struct {
+	source   []byte
+	manifest []byte
+	warnings []byte
+}
+
+// openCache opens the cache in dir or, if dir is empty, in the user's cache
+// directory.
+func openCache(dir string) (*cache, error) {
+	if dir == "" {
+		ucd, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(ucd, pkgsymsPkgName)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &cache{dir: dir}, nil
+}
+
+// cacheKey hashes the generator's build (see generatorID), the build
+// environment, the parts
+// (like the command line) and the names and contents of the Go files of the
+// package in srcdir and of its dependencies outside of the standard library.
+// Listing the files doesn't type-check anything, so it's much cheaper than
+// loading the package.
+func cacheKey(srcdir string, parts ...[]byte) (string, error) {
+	mode := packages.NeedName | packages.NeedFiles |
+		packages.NeedImports | packages.NeedDeps | packages.NeedModule
+	cfg, err := load.Config(srcdir, mode, *gopath)
+	if err != nil {
+		return "", err
+	}
+	cfg.Tests = *variant != variantPrimary
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return "", err
+	}
+	var files []string
+	seen := make(map[*packages.Package]bool)
+	var visit func(p *packages.Package)
+	visit = func(p *packages.Package) {
+		if seen[p] {
+			return
+		}
+		seen[p] = true
+		if p.Module != nil || len(seen) == 1 {
+			files = append(files, p.GoFiles...)
+			files = append(files, p.OtherFiles...)
+		}
+		if len(seen) == 1 {
+			// Files that are added to or removed from the
+			// build change the output.
+			files = append(files, p.IgnoredFiles...)
+		}
+		for _, imp := range p.Imports {
+			visit(imp)
+		}
+	}
+	for _, p := range pkgs {
+		visit(p)
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", generatorID(), runtime.Version())
+	for _, name := range cacheEnv {
+		fmt.Fprintf(h, "%s=%s\x00", name, os.Getenv(name))
+	}
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d\x00", len(part))
+		h.Write(part)
+	}
+	for _, name := range files {
+		fmt.Fprintf(h, "%s\x00", name)
+		if err := hashFile(h, name); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Names of the files in a cache entry's directory.
+const (
+	cacheSource   = "source.go"
+	cacheManifest = "manifest.json"
+	cacheWarnings = "warnings.txt"
+)
+
+// get the entry with the key.
+func (c *cache) get(key string) (e cacheEntry, ok bool) {
+	dir := filepath.Join(c.dir, key)
+	var err error
+	if e.source, err = os.ReadFile(filepath.Join(dir, cacheSource)); err != nil {
+		return cacheEntry{}, false
+	}
+	e.manifest, _ = os.ReadFile(filepath.Join(dir, cacheManifest))
+	e.warnings, _ = os.ReadFile(filepath.Join(dir, cacheWarnings))
+	return e, true
+}
+
+// put the entry into the cache.  The source is written last so that partially
+// written entries are never found.
+func (c *cache) put(key string, e cacheEntry) error {
+	dir := filepath.Join(c.dir, key)
+	var st staged
+	defer st.cleanup()
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{cacheManifest, e.manifest},
+		{cacheWarnings, e.warnings},
+		{cacheSource, e.source},
+	} {
+		if f.data == nil {
+			continue
+		}
+		data := f.data
+		if err := st.write(filepath.Join(dir, f.name), func(w io.Writer) error {
+			_, err := io.Copy(w, bytes.NewReader(data))
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return st.commit()
+}
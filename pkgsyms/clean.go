@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/skillian/pkgsyms/pkgsyms/gen"
+)
+
+func cleanUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, `Remove files generated by %s.
+
+Usage of %s clean:
+	%s clean [flags] [directory | packages]
+
+Go files whose first line is the header written by %s are removed along
+with the manifests they embed.  Files written with -append are kept because
+they may contain hand-written code.
+
+Flags:
+`, progname, progname, progname, progname)
+		fs.PrintDefaults()
+	}
+}
+
+// cleanFlags defines the flags of the clean subcommand.
+func cleanFlags() (fs *flag.FlagSet, dryRun *bool) {
+	fs = flag.NewFlagSet("clean", flag.ExitOnError)
+	dryRun = fs.Bool("n", false, "print the files that would be removed without removing them")
+	fs.Usage = cleanUsage(fs)
+	return
+}
+
+func cleanMain(args []string) {
+	fs, dryRun := cleanFlags()
+	fs.Parse(args)
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	dirs, err := packageDirs(patterns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, dir := range dirs {
+		removed, err := cleanDir(dir, *dryRun)
+		for _, name := range removed {
+			fmt.Println(name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// embedDirective matches the go:embed line of a generated manifest.
+var embedDirective = regexp.MustCompile(`^//go:embed (\S+)$`)
+
+// cleanDir removes the generated files in dir and returns their names.  If
+// dryRun is set, the files are only returned.
+func cleanDir(dir string, dryRun bool) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		name := filepath.Join(dir, e.Name())
+		embeds, ok, err := generatedFile(name)
+		if err != nil {
+			return removed, err
+		}
+		if !ok {
+			continue
+		}
+		for _, n := range append([]string{name}, embeds...) {
+			if !dryRun {
+				if err := os.Remove(n); err != nil && !os.IsNotExist(err) {
+					return removed, err
+				}
+			}
+			removed = append(removed, n)
+		}
+	}
+	return removed, nil
+}
+
+// generatedFile reports whether the Go file was generated by pkgsyms and, if
+// it was, gets the files it embeds.
+func generatedFile(name string) (embeds []string, ok bool, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() || !gen.Header.MatchString(sc.Text()) {
+		return nil, false, sc.Err()
+	}
+	for sc.Scan() {
+		if m := embedDirective.FindStringSubmatch(sc.Text()); m != nil {
+			embeds = append(embeds, filepath.Join(filepath.Dir(name), m[1]))
+		}
+	}
+	return embeds, true, sc.Err()
+}
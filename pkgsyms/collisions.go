@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// parseMergePolicy parses the -collisions flag.
+func parseMergePolicy(s string) (pkgsyms.MergePolicy, error) {
+	for _, p := range []pkgsyms.MergePolicy{
+		pkgsyms.MergeError, pkgsyms.MergeFirstWins,
+		pkgsyms.MergeLastWins, pkgsyms.MergeQualify,
+	} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown collision policy %q", s)
+}
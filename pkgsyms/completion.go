@@ -0,0 +1,324 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+func completionUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, `Write a shell completion script.
+
+Usage of %s completion:
+	%s completion bash|zsh|fish
+
+The script completes subcommands, flags, package directories in the current
+module and, for "%s query", the packages and symbols of the running process
+given by -addr and -path.  Load it from the shell's startup file, like:
+
+	source <(%s completion bash)
+
+Flags:
+`, progname, progname, progname, progname)
+		fs.PrintDefaults()
+	}
+}
+
+// completionFlags defines the flags of the completion subcommand.
+func completionFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	fs.Usage = completionUsage(fs)
+	return fs
+}
+
+func completionMain(args []string) {
+	fs := completionFlags()
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := writeCompletion(os.Stdout, fs.Arg(0)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// completeCommand is the hidden subcommand that the completion scripts run
+// with the words of the command line after the program name, up to and
+// including the word being completed.  It prints the candidates for that
+// word on separate lines.
+const completeCommand = "__complete"
+
+func completeMain(args []string) {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	for _, c := range complete(args) {
+		fmt.Println(c)
+	}
+}
+
+// completionScripts are the completion scripts of each shell.  %[1]s is the
+// program's name and %[2]s is completeCommand.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for %[1]s
+_%[1]s() {
+	local IFS=$'\n'
+	COMPREPLY=($(%[1]s %[2]s "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _%[1]s %[1]s
+`,
+	"zsh": `#compdef %[1]s
+_%[1]s() {
+	local -a completions
+	completions=(${(f)"$(%[1]s %[2]s "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+	if (( ${#completions} )); then
+		compadd -a completions
+	else
+		_files
+	fi
+}
+compdef _%[1]s %[1]s
+`,
+	"fish": `# fish completion for %[1]s
+function __%[1]s_complete
+	set -l args (commandline -opc)
+	set -e args[1]
+	set -l cur (commandline -ct)
+	%[1]s %[2]s $args "$cur" 2>/dev/null
+end
+complete -c %[1]s -f -a '(__%[1]s_complete)'
+`,
+}
+
+// writeCompletion writes the completion script of shell to w.
+func writeCompletion(w io.Writer, shell string) error {
+	script, ok := completionScripts[shell]
+	if !ok {
+		return fmt.Errorf("no completion for shell %q; use bash, zsh or fish", shell)
+	}
+	_, err := fmt.Fprintf(w, script, progname, completeCommand)
+	return err
+}
+
+// subcommand describes a subcommand for completion.
+type subcommand struct {
+	// flags defines the subcommand's flags.
+	flags func() *flag.FlagSet
+
+	// args completes cur as the next of the subcommand's arguments after
+	// the flags have been parsed into fs.
+	args func(fs *flag.FlagSet, cur string) []string
+}
+
+// subcommands are the subcommands that are completed.
+var subcommands = map[string]subcommand{
+	"query": {
+		flags: func() *flag.FlagSet { fs, _, _, _ := queryFlags(); return fs },
+		args:  completeQuery,
+	},
+	"clean": {
+		flags: func() *flag.FlagSet { fs, _ := cleanFlags(); return fs },
+		args:  completeDirArgs,
+	},
+	"lint": {flags: lintFlags, args: completeDirArgs},
+	"migrate": {
+		flags: func() *flag.FlagSet { fs, _ := migrateFlags(); return fs },
+		args:  completeDirArgs,
+	},
+	"mock": {
+		flags: func() *flag.FlagSet { fs, _, _, _ := mockFlags(); return fs },
+		args: func(fs *flag.FlagSet, cur string) []string {
+			if fs.NArg() > 0 {
+				return nil
+			}
+			return completeDirs(cur)
+		},
+	},
+	"repl": {
+		flags: func() *flag.FlagSet { fs, _, _ := replFlags(); return fs },
+		args:  func(*flag.FlagSet, string) []string { return nil },
+	},
+	"completion": {
+		flags: completionFlags,
+		args: func(fs *flag.FlagSet, cur string) []string {
+			if fs.NArg() > 0 {
+				return nil
+			}
+			return withPrefix([]string{"bash", "fish", "zsh"}, cur)
+		},
+	},
+}
+
+// complete gets the candidates for the last of the words of a command line
+// after the program name.
+func complete(words []string) []string {
+	cur, prev := words[len(words)-1], words[:len(words)-1]
+	cmd := subcommand{
+		flags: func() *flag.FlagSet { return flag.CommandLine },
+		args:  completeDirArgs,
+	}
+	var names []string
+	if len(prev) == 0 {
+		for name := range subcommands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	} else if sub, ok := subcommands[prev[0]]; ok {
+		cmd, prev = sub, prev[1:]
+	}
+	fs := cmd.flags()
+	if strings.HasPrefix(cur, "-") && !strings.Contains(cur, "=") {
+		var flags []string
+		fs.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, "-"+f.Name)
+		})
+		return withPrefix(flags, cur)
+	}
+	// Parse a copy of the flags so that a flag missing its value, which
+	// cur is then, isn't fatal.
+	parse := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+	parse.SetOutput(io.Discard)
+	fs.VisitAll(func(f *flag.Flag) {
+		parse.Var(f.Value, f.Name, f.Usage)
+	})
+	if err := parse.Parse(prev); err != nil {
+		return nil
+	}
+	return append(withPrefix(names, cur), cmd.args(parse, cur)...)
+}
+
+// withPrefix gets the candidates that start with prefix.
+func withPrefix(candidates []string, prefix string) []string {
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// completeDirArgs completes package directories for commands that take any
+// number of them.
+func completeDirArgs(fs *flag.FlagSet, cur string) []string {
+	return completeDirs(cur)
+}
+
+// completeDirs completes cur as a directory of a package in the module of
+// the working directory.  Directories that the generator skips in patterns,
+// like testdata, and those of nested modules aren't candidates.
+func completeDirs(cur string) []string {
+	dirs := []string{".", "./..."}
+	base := cur[:strings.LastIndex(cur, "/")+1]
+	start := filepath.Clean(filepath.FromSlash(base))
+	if !inModule(start) {
+		return nil
+	}
+	seen := make(map[string]bool)
+	filepath.WalkDir(start, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(start, path)
+		if err != nil || rel == "." {
+			return nil
+		}
+		name := base + filepath.ToSlash(rel)
+		if d.IsDir() {
+			if !strings.HasPrefix(name, cur) || skipCompletion(d.Name()) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dir := base + filepath.ToSlash(filepath.Dir(rel))
+		if strings.HasSuffix(d.Name(), ".go") && dir != base+"." && !seen[dir] {
+			seen[dir] = true
+			if cur == "" {
+				dir = "./" + dir
+			}
+			dirs = append(dirs, dir)
+		}
+		return nil
+	})
+	sort.Strings(dirs)
+	return withPrefix(dirs, cur)
+}
+
+// skipCompletion reports whether directories with the name are left out of
+// completions, like they are out of the go command's patterns.
+func skipCompletion(name string) bool {
+	return name == "testdata" || name == "vendor" ||
+		strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// inModule reports whether dir is in the module of the working directory,
+// or below the working directory if it's not in a module.
+func inModule(dir string) bool {
+	wd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	root := wd
+	for d := wd; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			root = d
+			break
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(root, abs)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// completeQuery completes the package and symbol names of the query
+// subcommand from the process given by its flags.
+func completeQuery(fs *flag.FlagSet, cur string) []string {
+	reg := httpRegistry{
+		base: url.URL{
+			Scheme: "http",
+			Host:   fs.Lookup("addr").Value.String(),
+			Path:   fs.Lookup("path").Value.String(),
+		},
+		client: http.Client{Timeout: 2 * time.Second},
+	}
+	var names []string
+	switch fs.NArg() {
+	case 0:
+		pkgs, err := reg.Packages()
+		if err != nil {
+			return nil
+		}
+		names = pkgs
+	case 1:
+		infos, err := reg.Symbols(fs.Arg(0))
+		if err != nil {
+			return nil
+		}
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+	}
+	return withPrefix(names, cur)
+}
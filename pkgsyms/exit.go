@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Exit codes of the generator.  When several packages fail, the exit code is
+// the one of the first package that failed.
+const (
+	exitOK = iota
+
+	// exitFailure is for usage errors and failures that don't have their
+	// own exit code.
+	exitFailure
+
+	// exitLoad is for packages that can't be found, parsed or type-checked.
+	exitLoad
+
+	// exitDrift is for output files that -check found to be out of date.
+	exitDrift
+
+	// exitWarnings is for warnings with -fail-on-warning.
+	exitWarnings
+)
+
+// loadError is returned when a package can't be loaded.
+type loadError struct {
+	err error
+}
+
+func (e loadError) Error() string { return e.err.Error() }
+
+func (e loadError) Unwrap() error { return e.err }
+
+// driftError is returned by -check when an output file isn't what would be
+// generated.
+type driftError struct {
+	filename string
+}
+
+func (e driftError) Error() string {
+	return fmt.Sprintf("%s is out of date; regenerate it", e.filename)
+}
+
+// warningsError is returned with -fail-on-warning when there were warnings.
+type warningsError struct {
+	n int
+}
+
+func (e warningsError) Error() string {
+	return fmt.Sprintf("%d warnings with -fail-on-warning", e.n)
+}
+
+// exitCode gets the exit code for err.
+func exitCode(err error) int {
+	var le loadError
+	var de driftError
+	var we warningsError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &le):
+		return exitLoad
+	case errors.As(err, &de):
+		return exitDrift
+	case errors.As(err, &we):
+		return exitWarnings
+	}
+	return exitFailure
+}
@@ -0,0 +1,142 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// Names of the regions that -append regenerates.
+const (
+	checksumRegion = "checksum"
+	symbolsRegion  = "symbols"
+)
+
+const (
+	beginMarker = "// pkgsyms:begin "
+	endMarker   = "// pkgsyms:end "
+)
+
+// Appended reports whether f was generated with Append, from the markers of
+// its regions.
+func Appended(f *ast.File) bool {
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if c.Text == beginMarker+symbolsRegion {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// markRegion surrounds the lines of a region with markers indented by indent.
+func markRegion(indent, name, lines string) string {
+	return indent + beginMarker + name + "\n" +
+		lines +
+		indent + endMarker + name + "\n"
+}
+
+// spliceRegions replaces the marked regions in existing with the regions of
+// the same name in generated.
+func spliceRegions(existing, generated []byte) ([]byte, error) {
+	out := existing
+	for _, name := range []string{checksumRegion, symbolsRegion} {
+		start, end, err := findRegion(generated, name)
+		if err != nil {
+			return nil, err
+		}
+		region := generated[start:end]
+		if start, end, err = findRegion(out, name); err != nil {
+			return nil, fmt.Errorf("existing file: %w", err)
+		}
+		out = append(append(append([]byte(nil), out[:start]...), region...), out[end:]...)
+	}
+	return out, nil
+}
+
+// findRegion finds the lines of a region, including its markers.
+func findRegion(src []byte, name string) (start, end int, err error) {
+	begin, finish := []byte(beginMarker+name+"\n"), []byte(endMarker+name+"\n")
+	i := bytes.Index(src, begin)
+	if i < 0 {
+		return 0, 0, fmt.Errorf("missing %q marker", bytes.TrimSpace(begin))
+	}
+	j := bytes.Index(src[i:], finish)
+	if j < 0 {
+		return 0, 0, fmt.Errorf("missing %q marker", bytes.TrimSpace(finish))
+	}
+	start = bytes.LastIndexByte(src[:i], '\n') + 1
+	return start, i + j + len(finish), nil
+}
+
+// appendedSyntax reports whether f was written with Append, which is
+// recognized by its symbols region because the file isn't marked as
+// generated.
+func appendedSyntax(f *ast.File) bool {
+	marker := strings.TrimSpace(beginMarker + symbolsRegion)
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			if c.Text == marker {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mergeImports adds the imports of generated that src is missing to src and
+// removes the imports that src no longer uses, so that the import block of a
+// file written with Append, which is outside of the marked regions, matches
+// the generated code.  Blank and dot imports are kept.
+func mergeImports(src, generated []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	gen, err := parser.ParseFile(fset, "", generated, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("existing file: %w", err)
+	}
+	changed := false
+	for _, imp := range gen.Imports {
+		name, path := importSpec(imp)
+		if astutil.AddNamedImport(fset, f, name, path) {
+			changed = true
+		}
+	}
+	for _, imp := range append([]*ast.ImportSpec(nil), f.Imports...) {
+		name, path := importSpec(imp)
+		if name == "_" || name == "." || astutil.UsesImport(f, path) {
+			continue
+		}
+		if astutil.DeleteNamedImport(fset, f, name, path) {
+			changed = true
+		}
+	}
+	if !changed {
+		return src, nil
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// importSpec gets the name, if any, and the path of an import.
+func importSpec(imp *ast.ImportSpec) (name, path string) {
+	if imp.Name != nil {
+		name = imp.Name.Name
+	}
+	path, _ = strconv.Unquote(imp.Path.Value)
+	return name, path
+}
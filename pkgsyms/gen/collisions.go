@@ -0,0 +1,113 @@
+package gen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// resolveCollisions resolves the decls that are registered under the same
+// name by the Collisions policy.  The decls must be sorted.
+func (g *generator) resolveCollisions() error {
+	byName := make(map[string][]int)
+	var names []string
+	for i, d := range g.decls {
+		name := d.regName()
+		if len(byName[name]) == 1 {
+			names = append(names, name)
+		}
+		byName[name] = append(byName[name], i)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	drop := make(map[int]bool)
+	var errs []string
+	for _, name := range names {
+		idxs := byName[name]
+		decls := make([]string, len(idxs))
+		for i, j := range idxs {
+			d := g.decls[j]
+			decls[i] = fmt.Sprintf("%s %s at %v", d.kind, d.Name, g.pkg.Fset.Position(d.pos))
+		}
+		switch g.cfg.collisions {
+		case pkgsyms.MergeError:
+			errs = append(errs, fmt.Sprintf("%q: %s", name, strings.Join(decls, ", ")))
+		case pkgsyms.MergeQualify:
+			for _, j := range idxs {
+				if g.namePrefix == "" {
+					g.decls[j].rename = g.alias + "." + g.decls[j].Name
+				} else {
+					g.decls[j].rename = g.decls[j].Name
+				}
+			}
+		default:
+			keep := idxs[0]
+			if g.cfg.collisions == pkgsyms.MergeLastWins {
+				keep = idxs[len(idxs)-1]
+			}
+			for _, j := range idxs {
+				if drop[j] = j != keep; drop[j] {
+					g.skip(g.decls[j].pos, g.decls[j].kind, g.decls[j].Name, fmt.Sprintf(
+						"%q is registered by %s; only %s %s is registered (-collisions=%v)",
+						name, strings.Join(decls, ", "),
+						g.decls[keep].kind, g.decls[keep].Name, g.cfg.collisions))
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("names registered more than once:\n\t%s", strings.Join(errs, "\n\t"))
+	}
+	decls := g.decls[:0]
+	for i, d := range g.decls {
+		if !drop[i] {
+			decls = append(decls, d)
+		}
+	}
+	g.decls = decls
+	return nil
+}
+
+// checkNames warns about registered names that collide with the generated
+// declarations or that would shadow Go's keywords and builtins in consumers
+// that lower-case names, like scripting languages.
+func (g *generator) checkNames() {
+	// generated maps the generated names to the flags that rename them.
+	generated := map[string]string{
+		g.cfg.checksumName(): "-varname",
+	}
+	if g.cfg.registrar != "" {
+		generated[g.cfg.registrar] = "-registrar"
+	} else {
+		generated[g.cfg.varName] = "-varname"
+	}
+	for _, d := range g.decls {
+		name := d.Name
+		if d.rename != "" {
+			name = d.rename
+		}
+		pos := g.pkg.Fset.Position(d.pos)
+		lower := strings.ToLower(name)
+		switch flag, ok := generated[name]; {
+		case ok:
+			g.cfg.warnf(
+				"%v: %s %s collides with the generated %s; "+
+					"use %s or a %q directive to rename it",
+				pos, d.kind, name, name, flag, strings.TrimSpace(nameDirectivePrefix))
+		case token.Lookup(lower).IsKeyword():
+			g.cfg.warnf(
+				"%v: %s %s shadows the Go keyword %q when lower-cased; "+
+					"use a %q directive to rename it",
+				pos, d.kind, name, lower, strings.TrimSpace(nameDirectivePrefix))
+		case types.Universe.Lookup(lower) != nil:
+			g.cfg.warnf(
+				"%v: %s %s shadows the Go builtin %q when lower-cased; "+
+					"use a %q directive to rename it",
+				pos, d.kind, name, lower, strings.TrimSpace(nameDirectivePrefix))
+		}
+	}
+}
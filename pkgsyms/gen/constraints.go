@@ -0,0 +1,393 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build/constraint"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// Known GOOS and GOARCH values that constrain files by their names, like
+// file_linux_amd64.go.
+var (
+	knownOS = strings.Fields(`aix android darwin dragonfly freebsd hurd illumos
+		ios js linux nacl netbsd openbsd plan9 solaris wasip1 windows zos`)
+	knownArch = strings.Fields(`386 amd64 amd64p32 arm armbe arm64 arm64be
+		loong64 mips mipsle mips64 mips64le mips64p32 mips64p32le ppc ppc64
+		ppc64le riscv riscv64 s390 s390x sparc sparc64 wasm`)
+)
+
+// fileConstraint gets the build constraint of a Go file from its //go:build
+// line, its name and whether it imports "C", or nil if it's always built.
+func fileConstraint(fset *token.FileSet, filename string) (constraint.Expr, error) {
+	f, err := parser.ParseFile(fset, filename, nil, parser.ImportsOnly|parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	var exprs []constraint.Expr
+	for _, cg := range f.Comments {
+		if cg.Pos() > f.Package {
+			break
+		}
+		for _, c := range cg.List {
+			if !constraint.IsGoBuild(c.Text) {
+				continue
+			}
+			x, err := constraint.Parse(c.Text)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", fset.Position(c.Pos()), err)
+			}
+			exprs = append(exprs, x)
+		}
+	}
+	exprs = append(exprs, nameConstraint(filename)...)
+	for _, imp := range f.Imports {
+		if imp.Path.Value == `"C"` {
+			exprs = append(exprs, &constraint.TagExpr{Tag: "cgo"})
+			break
+		}
+	}
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+	x := exprs[0]
+	for _, y := range exprs[1:] {
+		x = &constraint.AndExpr{X: x, Y: y}
+	}
+	return x, nil
+}
+
+// nameConstraint gets the GOOS and GOARCH tags implied by a file's name.
+func nameConstraint(filename string) []constraint.Expr {
+	name := strings.TrimSuffix(filepath.Base(filename), ".go")
+	i := strings.IndexByte(name, '_')
+	if i < 0 {
+		return nil
+	}
+	elems := strings.Split(name[i+1:], "_")
+	n := len(elems)
+	tag := func(t string) constraint.Expr { return &constraint.TagExpr{Tag: t} }
+	switch {
+	case n >= 2 && contains(knownOS, elems[n-2]) && contains(knownArch, elems[n-1]):
+		return []constraint.Expr{tag(elems[n-2]), tag(elems[n-1])}
+	case contains(knownOS, elems[n-1]) || contains(knownArch, elems[n-1]):
+		return []constraint.Expr{tag(elems[n-1])}
+	}
+	return nil
+}
+
+func contains(strs []string, s string) bool {
+	for _, t := range strs {
+		if t == s {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions reports whether the constraint refers to the tag.
+func mentions(x constraint.Expr, tag string) bool {
+	switch x := x.(type) {
+	case *constraint.TagExpr:
+		return x.Tag == tag
+	case *constraint.NotExpr:
+		return mentions(x.X, tag)
+	case *constraint.AndExpr:
+		return mentions(x.X, tag) || mentions(x.Y, tag)
+	case *constraint.OrExpr:
+		return mentions(x.X, tag) || mentions(x.Y, tag)
+	}
+	return false
+}
+
+// constrain records the build constraints of the files that the decls were
+// declared in.
+func (g *generator) constrain() error {
+	exprs := make(map[string]string)
+	for i, d := range g.decls {
+		filename := g.pkg.Fset.Position(d.pos).Filename
+		s, ok := exprs[filename]
+		if !ok {
+			x, err := fileConstraint(token.NewFileSet(), filename)
+			if err != nil {
+				return err
+			}
+			if x != nil {
+				s = x.String()
+			}
+			exprs[filename] = s
+		}
+		g.decls[i].constraint = s
+	}
+	return nil
+}
+
+// inspectIgnored creates the decls of the files that weren't loaded because
+// of their build constraints.  Without type information, only their syntax
+// is known, so they're neither excluded by type nor checked against
+// interfaces and they have no methods or schemas.  Files constrained by the
+// "ignore" tag and files of other packages are skipped.
+func (g *generator) inspectIgnored() ([]decl, error) {
+	var decls []decl
+	for _, filename := range g.pkg.IgnoredFiles {
+		if !strings.HasSuffix(filename, ".go") || strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+		x, err := fileConstraint(token.NewFileSet(), filename)
+		if err != nil {
+			return nil, err
+		}
+		if x == nil || mentions(x, "ignore") {
+			continue
+		}
+		f, err := parser.ParseFile(g.pkg.Fset, filename, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if f.Name.Name != g.pkg.Name {
+			continue
+		}
+		for _, d := range g.syntaxDecls(f) {
+			d.constraint = x.String()
+			decls = append(decls, d)
+		}
+	}
+	return decls, nil
+}
+
+// syntaxDecls creates the decls of a file's exported top-level declarations
+// from its syntax alone.
+func (g *generator) syntaxDecls(f *ast.File) []decl {
+	var decls []decl
+	for _, n := range f.Decls {
+		switch n := n.(type) {
+		case *ast.GenDecl:
+			first := len(decls)
+			for _, s := range n.Specs {
+				switch s := s.(type) {
+				case *ast.TypeSpec:
+					if !s.Name.IsExported() {
+						continue
+					}
+					d := decl{
+						g:      g,
+						kind:   typeDecl,
+						Name:   s.Name.Name,
+						Doc:    specDoc(n, s.Doc),
+						rename: nameDirective(specComments(n, s.Doc)),
+						pos:    s.Name.Pos(),
+					}
+					switch {
+					case s.TypeParams != nil:
+						d.kind = genericDecl
+						d.params = g.syntaxParams(s.TypeParams)
+					case isConstraintSyntax(s.Type):
+						d.kind = constraintDecl
+						d.Type = g.sprint(s.Type)
+					default:
+						switch s.Type.(type) {
+						case *ast.StructType, *ast.InterfaceType:
+						default:
+							d.Type = g.sprint(s.Type)
+						}
+					}
+					decls = append(decls, d)
+				case *ast.ValueSpec:
+					kind := varDecl
+					if n.Tok == token.CONST {
+						kind = constDecl
+					}
+					for _, id := range s.Names {
+						if !id.IsExported() {
+							continue
+						}
+						decls = append(decls, decl{
+							g:      g,
+							kind:   kind,
+							Name:   id.Name,
+							Doc:    specDoc(n, s.Doc),
+							rename: nameDirective(specComments(n, s.Doc)),
+							readOnly: kind == varDecl && (g.cfg.readOnly[id.Name] ||
+								hasDirective(specComments(n, s.Doc), readOnlyDirective)),
+							pos: id.Pos(),
+						})
+					}
+				}
+			}
+			groupConsts(decls[first:], n)
+		case *ast.FuncDecl:
+			if n.Recv != nil || !n.Name.IsExported() {
+				continue
+			}
+			d := decl{
+				g:      g,
+				kind:   funcDecl,
+				Name:   n.Name.Name,
+				Doc:    strings.TrimSpace(n.Doc.Text()),
+				rename: nameDirective(n.Doc),
+				pos:    n.Name.Pos(),
+			}
+			if n.Type.TypeParams != nil {
+				d.kind = genericDecl
+				d.params = g.syntaxParams(n.Type.TypeParams)
+			}
+			decls = append(decls, d)
+		}
+	}
+	return decls
+}
+
+// syntaxParams describes type parameters from their syntax.
+func (g *generator) syntaxParams(fl *ast.FieldList) []pkgsyms.TypeParam {
+	var params []pkgsyms.TypeParam
+	for _, f := range fl.List {
+		c := g.sprint(f.Type)
+		for _, name := range f.Names {
+			params = append(params, pkgsyms.TypeParam{Name: name.Name, Constraint: c})
+		}
+	}
+	return params
+}
+
+// isConstraintSyntax reports whether a type is an interface with type
+// elements, like ~int | ~string, so it can only be used as a constraint.
+func isConstraintSyntax(t ast.Expr) bool {
+	it, ok := t.(*ast.InterfaceType)
+	if !ok {
+		return false
+	}
+	for _, f := range it.Methods.List {
+		if len(f.Names) > 0 {
+			continue
+		}
+		switch x := f.Type.(type) {
+		case *ast.BinaryExpr:
+			return true
+		case *ast.UnaryExpr:
+			if x.Op == token.TILDE {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sprint formats an expression as Go source.
+func (g *generator) sprint(x ast.Expr) string {
+	var sb strings.Builder
+	if err := printer.Fprint(&sb, g.pkg.Fset, x); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// splitConstrained moves the decls from constrained files out of g.decls and
+// groups them with the decls of the ignored files by their constraints.
+func (g *generator) splitConstrained(ignored []decl) map[string][]decl {
+	groups := make(map[string][]decl)
+	seen := make(map[string]bool)
+	add := func(d decl) {
+		key := d.constraint + "\x00" + d.Name
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		groups[d.constraint] = append(groups[d.constraint], d)
+	}
+	decls := g.decls[:0]
+	for _, d := range g.decls {
+		if d.constraint == "" {
+			decls = append(decls, d)
+			continue
+		}
+		add(d)
+	}
+	g.decls = decls
+	for _, d := range ignored {
+		add(d)
+	}
+	for _, ds := range groups {
+		sortDecls(ds)
+	}
+	return groups
+}
+
+// taggedReplacer turns build constraints into parts of filenames.
+var taggedReplacer = strings.NewReplacer(
+	"!", "not_", "&&", "_and_", "||", "_or_", " ", "", "(", "", ")", "", ".", "")
+
+// taggedFilename gets the name of the file that registers the symbols with
+// the build constraint.  The name ends in _tags.go so that it doesn't imply
+// a GOOS or GOARCH itself.
+func taggedFilename(base, expr string) string {
+	return strings.TrimSuffix(base, ".go") + "_" + taggedReplacer.Replace(expr) + "_tags.go"
+}
+
+// conditionalRegistrars is the variable that the files generated for build
+// constraints add their registrars to when Registrar is used.
+const conditionalRegistrars = pkgsymsPkgName + "Conditional"
+
+// writeTagged generates a file for each group of constrained decls with the
+// group's build constraint and passes them to the TaggedFiles function.
+// Their symbols are added with AddConditional while the package's variables
+// are initialized so that they're registered before the init function of
+// the main file marks the package ready.  With Registrar, a function adding
+// them is appended to conditionalRegistrars for the main file's registrar to
+// call instead.
+func (g *generator) writeTagged(groups map[string][]decl, pkgname, imports string) error {
+	exprs := make([]string, 0, len(groups))
+	for expr := range groups {
+		exprs = append(exprs, expr)
+	}
+	sort.Strings(exprs)
+	for _, expr := range exprs {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, `// Code generated by "%s"; DO NOT EDIT.
+%s
+
+//go:build %s
+
+package %s
+
+import (
+	%s
+)
+
+// The symbols declared in files built with %q.
+var _ = func() struct{} {
+`,
+			g.cfg.command, g.versionComment(), expr, pkgname, imports, expr)
+		indent := "\t"
+		if g.cfg.registrar != "" {
+			fmt.Fprintf(&buf,
+				"\t%s = append(%s, func(p *%s.Package) {\n",
+				conditionalRegistrars, conditionalRegistrars, pkgsymsPkgName)
+			fmt.Fprintf(&buf, "\t\tp.AddConditional(\n")
+			indent = "\t\t"
+		} else if g.cfg.target != "" {
+			fmt.Fprintf(&buf, "\t%s.Add(\n", g.targetExpr)
+		} else {
+			fmt.Fprintf(&buf, "\t%s.AddConditional(\n", g.cfg.varName)
+		}
+		for _, d := range groups[expr] {
+			fmt.Fprintf(&buf, "%s\t%s,\n", indent, d)
+		}
+		fmt.Fprintf(&buf, "%s)\n", indent)
+		if g.cfg.registrar != "" {
+			buf.WriteString("\t})\n")
+		}
+		buf.WriteString("\treturn struct{}{}\n}()\n")
+		if err := g.cfg.taggedWrite(taggedFilename(g.cfg.taggedBase, expr), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
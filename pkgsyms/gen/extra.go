@@ -0,0 +1,41 @@
+package gen
+
+import (
+	"go/types"
+)
+
+// extraSymbolsFunc is the name of the function that a package can declare,
+// conventionally in pkgsyms_extra.go, to add hand-written symbols to its
+// registry:
+//
+//	func extraSymbols() []pkgsyms.Symbol
+//
+// The generated code calls it after adding the generated symbols, so it can
+// register computed or renamed symbols without editing generated code.
+const extraSymbolsFunc = "extraSymbols"
+
+// hasExtraSymbols reports whether the package declares an extraSymbols
+// function.  A declaration with a different signature is warned about and
+// ignored.
+func (g *generator) hasExtraSymbols() bool {
+	obj := g.pkg.Types.Scope().Lookup(extraSymbolsFunc)
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Params().Len() == 0 && sig.Results().Len() == 1 && sig.TypeParams().Len() == 0 {
+		if sl, ok := sig.Results().At(0).Type().(*types.Slice); ok {
+			if named, ok := sl.Elem().(*types.Named); ok {
+				tn := named.Obj()
+				if tn.Pkg() != nil && tn.Pkg().Path() == pkgsymsPkgPath && tn.Name() == "Symbol" {
+					return true
+				}
+			}
+		}
+	}
+	g.cfg.warnf(
+		"%v: %s isn't called because it isn't a func() []%s.Symbol",
+		g.pkg.Fset.Position(fn.Pos()), extraSymbolsFunc, pkgsymsPkgName)
+	return false
+}
@@ -0,0 +1,1595 @@
+// Package gen generates the Go files that register the exported symbols of a
+// package with pkgsyms.  It's the core of the pkgsyms command, for tools
+// like build system rules that generate registries without running it.
+//
+// Generate writes the registry of a package loaded with LoadMode and
+// GenerateFiles collects every file of it in memory.
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"go/types"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/pkgsyms/internal/load"
+	"golang.org/x/tools/go/packages"
+)
+
+const (
+	// LoadMode is the mode that packages passed to Generate must be
+	// loaded with.
+	LoadMode = (packages.NeedName |
+		packages.NeedFiles |
+		packages.NeedCompiledGoFiles |
+		packages.NeedImports |
+		packages.NeedTypes | packages.NeedTypesSizes |
+		packages.NeedSyntax | packages.NeedTypesInfo |
+		packages.NeedDeps)
+
+	pkgsymsPkgName = "pkgsyms"
+	pkgsymsPkgPath = "github.com/skillian/" + pkgsymsPkgName
+)
+
+// Header matches the first line of the files that Generate writes.  The
+// command recorded in it is named after the pkgsyms binary, so it may have a
+// suffix like pkgsyms.exe, and its arguments are the first submatch.
+var Header = regexp.MustCompile(`^// Code generated by "pkgsyms[^ "]*( [^"]*)?"; DO NOT EDIT\.$`)
+
+// Config configures pkgsyms
+type Config struct {
+	pkgAlias string
+
+	// varName is the name of the generated *pkgsyms.Package variable.
+	varName string
+
+	// command is recorded in the generated file's header.
+	command string
+
+	// version of the generator recorded in the generated file.
+	version string
+
+	// docs records doc comments in the registry.
+	docs bool
+
+	// manifest is the filename of the manifest to write constants and
+	// docs into instead of the generated Go code.
+	manifest string
+
+	// manifestCBOR encodes the manifest in CBOR instead of JSON.
+	manifestCBOR bool
+
+	// funcVars makes variables of function type callable as Funcs.
+	funcVars bool
+
+	// safeContainers registers variables of map and channel types
+	// Guarded.
+	safeContainers bool
+
+	// qualify is how registered names are qualified.
+	qualify string
+
+	// warnf reports problems that don't stop generation.
+	warnf func(format string, args ...interface{})
+
+	// appending marks the generated regions of the file so that they can
+	// be regenerated in place.
+	appending bool
+
+	// existing is the content of the file being appended to.
+	existing []byte
+
+	// excludeTypes match the types of symbols that aren't registered.
+	excludeTypes []*regexp.Regexp
+
+	// readOnly are the names of variables registered read-only.
+	readOnly map[string]bool
+
+	// readOnlySync registers variables containing sync and sync/atomic
+	// values read-only.
+	readOnlySync bool
+
+	// implements are the qualified names of interfaces that types are
+	// checked against.
+	implements []string
+
+	// localImplements checks types against the exported interfaces of
+	// the package itself.
+	localImplements bool
+
+	// methods records the exported methods of types.
+	methods bool
+
+	// schema records the fields of struct types.
+	schema bool
+
+	// taggedBase is the name of the main output file that the names of
+	// the files written by taggedWrite are based on.
+	taggedBase string
+
+	// taggedWrite receives the files registering the symbols from files
+	// with build constraints.  If it's nil, those symbols are registered
+	// in the main file like every other symbol.
+	taggedWrite func(filename string, src []byte) error
+
+	// registrar is the name of the function generated to register the
+	// symbols instead of an init function and package variable.
+	registrar string
+
+	// target is the variable or field of type pkgsyms.Symbols or
+	// *pkgsyms.Symbols that the symbols are registered into instead of a
+	// package variable, like "example.com/app.Registry.Symbols".
+	target string
+
+	// collisions decides what happens to decls registered under the same
+	// name.
+	collisions pkgsyms.MergePolicy
+
+	// maxVarSize is the size in bytes above which registered variables
+	// are warned about.  Zero disables the warning.
+	maxVarSize int64
+
+	// sink receives the files other than the Go file.
+	sink func(filename string, data []byte) error
+
+	// strict fails generation when symbols can't be registered instead of
+	// skipping them.
+	strict bool
+
+	// gopath loads the packages of the implemented interfaces in GOPATH
+	// mode.
+	gopath bool
+}
+
+// Option modifies Config.
+type Option func(c *Config) error
+
+// Alias allows an alias name to be specified for the package.
+func Alias(name string) Option {
+	return func(c *Config) error {
+		if c.pkgAlias != "" {
+			return fmt.Errorf(
+				"redefinition of package alias from %q to %q",
+				c.pkgAlias, name)
+		}
+		c.pkgAlias = name
+		return nil
+	}
+}
+
+// VarName sets the name of the generated package variable.
+func VarName(name string) Option {
+	return func(c *Config) error {
+		c.varName = name
+		return nil
+	}
+}
+
+// GeneratorVersion sets the version of the generator recorded in the
+// generated file's header and passed to (*pkgsyms.Package).SetGenerator.
+// The default is toolVersion.
+func GeneratorVersion(version string) Option {
+	return func(c *Config) error {
+		c.version = version
+		return nil
+	}
+}
+
+// toolVersion gets the version of the pkgsyms module the generator was built
+// from, like v1.2.0 when the command was installed with go install or run
+// with go run at a version or when a tool embedding the generator requires
+// it, or (devel) otherwise.
+func toolVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	for _, m := range append([]*debug.Module{&bi.Main}, bi.Deps...) {
+		if m.Path == pkgsymsPkgPath && m.Version != "" {
+			if m.Replace != nil && m.Replace.Version != "" {
+				return m.Replace.Version
+			}
+			return m.Version
+		}
+	}
+	return "(devel)"
+}
+
+// Command sets the command line recorded in the generated file's header.
+func Command(cmdline string) Option {
+	return func(c *Config) error {
+		c.command = cmdline
+		return nil
+	}
+}
+
+// Docs records doc comments in the registry.
+func Docs(docs bool) Option {
+	return func(c *Config) error {
+		c.docs = docs
+		return nil
+	}
+}
+
+// FuncVars makes variables of function type callable as Funcs.
+func FuncVars(funcVars bool) Option {
+	return func(c *Config) error {
+		c.funcVars = funcVars
+		return nil
+	}
+}
+
+// SafeContainers registers variables of map and channel types Guarded, so
+// they can be used concurrently through their MapVar and ChanVar.
+func SafeContainers(safe bool) Option {
+	return func(c *Config) error {
+		c.safeContainers = safe
+		return nil
+	}
+}
+
+// Qualify sets how the names of the registered symbols are qualified.  With
+// "alias", symbols are registered as alias.Name where alias is the name that
+// the file being appended to imports the package by, if it names the import,
+// or else the package's name, so that the symbols of many packages can be
+// merged into one set without colliding.  The empty string leaves the names
+// unqualified.
+func Qualify(mode string) Option {
+	return func(c *Config) error {
+		switch mode {
+		case "", "alias":
+		default:
+			return fmt.Errorf("unknown qualify mode %q", mode)
+		}
+		c.qualify = mode
+		return nil
+	}
+}
+
+// Warnf sets the function that reports problems that don't stop generation,
+// like exported names that collide with Go's builtins.  The default is
+// log.Printf.
+func Warnf(f func(format string, args ...interface{})) Option {
+	return func(c *Config) error {
+		c.warnf = f
+		return nil
+	}
+}
+
+// Append marks the generated regions of the output with pkgsyms:begin and
+// pkgsyms:end comments.  If existing isn't empty, it's the content of a file
+// previously generated with Append and only its marked regions are replaced.
+// Everything outside of the markers, like hand-registered symbols, is kept.
+func Append(existing []byte) Option {
+	return func(c *Config) error {
+		c.appending = true
+		c.existing = existing
+		return nil
+	}
+}
+
+// ExcludeType excludes symbols whose types match the regular expression.
+// Types are written as in Go source with package names, like "*testing.T",
+// and functions are matched by their signatures, so pattern can match
+// anything that references a type, like `unsafe\.Pointer`.  Types are
+// matched by their underlying types.
+func ExcludeType(pattern string) Option {
+	return func(c *Config) error {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid type pattern: %w", err)
+		}
+		c.excludeTypes = append(c.excludeTypes, re)
+		return nil
+	}
+}
+
+// ReadOnly registers the named variables read-only.  Variables can also be
+// made read-only with a //pkgsyms:readonly directive in their doc comments.
+func ReadOnly(names ...string) Option {
+	return func(c *Config) error {
+		if c.readOnly == nil {
+			c.readOnly = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			c.readOnly[name] = true
+		}
+		return nil
+	}
+}
+
+// ReadOnlySync registers variables that contain values from the sync and
+// sync/atomic packages read-only instead of warning about them.
+func ReadOnlySync(readOnly bool) Option {
+	return func(c *Config) error {
+		c.readOnlySync = readOnly
+		return nil
+	}
+}
+
+// Implements records which of the interfaces, given by their package paths
+// and names like "io.Reader", each registered type or a pointer to it
+// implements.
+func Implements(names ...string) Option {
+	return func(c *Config) error {
+		c.implements = append(c.implements, names...)
+		return nil
+	}
+}
+
+// LocalImplements records which of the registered interfaces of the package
+// itself each registered concrete type or a pointer to it implements, in
+// addition to any interfaces given to Implements.
+func LocalImplements(local bool) Option {
+	return func(c *Config) error {
+		c.localImplements = local
+		return nil
+	}
+}
+
+// Methods records the exported methods of each registered type.
+func Methods(methods bool) Option {
+	return func(c *Config) error {
+		c.methods = methods
+		return nil
+	}
+}
+
+// Schema records the schema of each registered struct type: the names,
+// kinds and required-ness of its fields, inferred from their tags.  See
+// (pkgsyms.Type).Validate.
+func Schema(schema bool) Option {
+	return func(c *Config) error {
+		c.schema = schema
+		return nil
+	}
+}
+
+// TaggedFiles registers the symbols declared in files with build
+// constraints, including files that aren't built for the current platform,
+// in separate files with the same constraints so that the registry is
+// accurate everywhere the package builds.  The files are named after base,
+// the main output file, and passed to write.
+func TaggedFiles(base string, write func(filename string, src []byte) error) Option {
+	return func(c *Config) error {
+		c.taggedBase = base
+		c.taggedWrite = write
+		return nil
+	}
+}
+
+// Registrar generates a function with the given name, like
+// func RegisterSymbols(p *pkgsyms.Package), that adds the symbols to p
+// instead of an init function that adds them to a package variable.  Nothing
+// is registered until the function is called, so hosts decide when and into
+// which Package the symbols go.
+func Registrar(name string) Option {
+	return func(c *Config) error {
+		if name != "" && !token.IsIdentifier(name) {
+			return fmt.Errorf("invalid registrar name %q", name)
+		}
+		c.registrar = name
+		return nil
+	}
+}
+
+// Target registers the symbols into an existing variable, or a field of one,
+// whose type is pkgsyms.Symbols or *pkgsyms.Symbols instead of a new package
+// variable.  The target is the variable's package path followed by the
+// variable's name and the names of the fields, like
+// "example.com/app.Registry.Symbols", for frameworks that keep their registry
+// in their own structs.  A Symbols isn't a Package, so the generated code
+// doesn't record the generator's version or the package's documentation and
+// doesn't mark anything ready.
+func Target(target string) Option {
+	return func(c *Config) error {
+		if target != "" {
+			if _, _, err := parseTarget(target); err != nil {
+				return err
+			}
+		}
+		c.target = target
+		return nil
+	}
+}
+
+// Collisions sets what happens to symbols that are registered under the same
+// name, like two functions renamed to the same name by name directives.  The
+// default, pkgsyms.MergeFirstWins, warns about the collision and only
+// registers the first symbol.  With pkgsyms.MergeLastWins, only the last one
+// is registered, with pkgsyms.MergeError, Generate fails and with
+// pkgsyms.MergeQualify, each of them is registered as pkg.Name with its own
+// name.  Symbols are sorted by kind and then by name.
+func Collisions(policy pkgsyms.MergePolicy) Option {
+	return func(c *Config) error {
+		c.collisions = policy
+		return nil
+	}
+}
+
+// MaxVarSize warns about registered variables larger than size bytes.
+// Registering a variable keeps it in binaries even if nothing looks it up,
+// which matters for things like large lookup tables and embedded assets.
+// A variable's size is the size of its type plus the length of the constant
+// string it's initialized with or the sizes of the files it embeds with
+// //go:embed.  Other data that slices, maps and pointers refer to isn't
+// counted.  Zero disables the warning.
+func MaxVarSize(size int64) Option {
+	return func(c *Config) error {
+		c.maxVarSize = size
+		return nil
+	}
+}
+
+// FileSink passes the files that Generate writes besides the Go file, like
+// the manifest, to write instead of writing them to the file system, so that
+// tools embedding the generator can collect them in memory.  The files are
+// passed once the Go file was written without errors; without a FileSink,
+// they're only written to the file system then.  See also GenerateFiles.
+func FileSink(write func(filename string, data []byte) error) Option {
+	return func(c *Config) error {
+		c.sink = write
+		return nil
+	}
+}
+
+// Strict makes Generate fail when any exported symbol can't be registered,
+// like an untyped constant that overflows its default type or a symbol
+// dropped by the Collisions policy, instead of skipping it with a warning.
+// It makes Mock fail when any exported symbol has no stand-in.
+func Strict(strict bool) Option {
+	return func(c *Config) error {
+		c.strict = strict
+		return nil
+	}
+}
+
+// GOPATH loads the packages of the interfaces given to Implements in GOPATH
+// mode even inside a module, like the pkgsyms command's -gopath flag.
+func GOPATH(gopath bool) Option {
+	return func(c *Config) error {
+		c.gopath = gopath
+		return nil
+	}
+}
+
+// Manifest writes constants and docs into an embedded JSON manifest file
+// instead of into the generated Go code.
+func Manifest(filename string) Option {
+	return func(c *Config) error {
+		c.manifest = filename
+		return nil
+	}
+}
+
+// ManifestFormat sets the encoding of the Manifest file: "json", the
+// default, or "cbor", which is smaller and cheaper to parse on constrained
+// devices.
+func ManifestFormat(format string) Option {
+	return func(c *Config) error {
+		switch format {
+		case "json":
+			c.manifestCBOR = false
+		case "cbor":
+			c.manifestCBOR = true
+		default:
+			return fmt.Errorf("unknown manifest format %q", format)
+		}
+		return nil
+	}
+}
+
+// Generate writes the file registering pkg's exported symbols to w.  pkg must
+// be loaded with LoadMode, or from export data without its syntax, in which
+// case docs and directives are lost.
+func Generate(w io.Writer, pkg *packages.Package, options ...Option) error {
+	cfg := Config{
+		varName:    "Pkg",
+		command:    pkgsymsPkgName,
+		version:    toolVersion(),
+		warnf:      log.Printf,
+		collisions: pkgsyms.MergeFirstWins,
+		sink:       writeFile,
+	}
+	for _, o := range options {
+		if err := o(&cfg); err != nil {
+			return err
+		}
+	}
+	if cfg.target != "" && cfg.registrar != "" {
+		return errors.New("a target and a registrar can't be used together")
+	}
+	g := generator{
+		pkg:   pkg,
+		cfg:   &cfg,
+		decls: make([]decl, 0, 512),
+	}
+	_, typeErrs := load.Errors(pkg, LoadMode)
+	for _, e := range typeErrs {
+		cfg.warnf("%s", load.Diagnostic(e, "warning"))
+	}
+	pkgbase := path.Base(g.pkg.Name)
+	pkgname := cfg.pkgAlias
+	if pkgname == "" {
+		pkgname = pkgbase
+	}
+	g.alias = g.localName()
+	g.generate(pkgname == pkgbase)
+	g.checkConsts()
+	g.exclude()
+	if err := g.checkImplements(); err != nil {
+		return err
+	}
+	g.recordMethods()
+	g.recordSchemas()
+
+	sortDecls(g.decls)
+	if err := g.resolveCollisions(); err != nil {
+		return err
+	}
+	if len(g.skipped) > 0 {
+		if cfg.strict {
+			return fmt.Errorf(
+				"%d symbols can't be registered:\n\t%s",
+				len(g.skipped), strings.Join(g.skipped, "\n\t"))
+		}
+		for _, msg := range g.skipped {
+			cfg.warnf("%s", msg)
+		}
+	}
+	g.checkNames()
+	g.checkSyncVars()
+	g.checkVarSizes()
+
+	var tagged map[string][]decl
+	if cfg.taggedWrite != nil {
+		if err := g.constrain(); err != nil {
+			return err
+		}
+		ignored, err := g.inspectIgnored()
+		if err != nil {
+			return err
+		}
+		tagged = g.splitConstrained(ignored)
+	}
+
+	checklines := make([]string, len(g.decls))
+	for i, d := range g.decls {
+		checklines[i] = d.kind.String() + " " + d.regName()
+	}
+
+	imports := fmt.Sprintf("%q", pkgsymsPkgPath)
+	if pkgname != pkgbase {
+		if g.alias != g.pkg.Name {
+			imports += fmt.Sprintf("\n\t%s %q", g.alias, g.pkg.PkgPath)
+		} else {
+			imports += fmt.Sprintf("\n\t%q", g.pkg.PkgPath)
+		}
+	}
+	if cfg.target != "" {
+		importPath, err := g.resolveTarget()
+		if err != nil {
+			return err
+		}
+		if importPath != "" {
+			imports += fmt.Sprintf("\n\t%q", importPath)
+		}
+	}
+	if err := g.writeTagged(tagged, pkgname, imports); err != nil {
+		return err
+	}
+
+	// With a registrar, the symbols are added to the Package passed to it
+	// instead of a package variable.
+	recv := cfg.varName
+	pkgVar := fmt.Sprintf(
+		"\nvar %s = %s.Of(%q)\n", cfg.varName, pkgsymsPkgName, g.pkg.PkgPath)
+	funcDecl := "func init()"
+	setGenerator := fmt.Sprintf(
+		"\t%s.SetGenerator(%q, %d)\n", recv, cfg.version, pkgsyms.APIVersion)
+	markReady := fmt.Sprintf("\t%s.MarkReady()\n", recv)
+	addConditional := "AddConditional"
+	var condDecl, conditional string
+	if cfg.target != "" {
+		// A Symbols only has the methods to add symbols.
+		recv = g.targetExpr
+		pkgVar = ""
+		setGenerator, markReady = "", ""
+		addConditional = "Add"
+	}
+	if cfg.registrar != "" {
+		recv = "p"
+		pkgVar = ""
+		setGenerator = fmt.Sprintf(
+			"\t%s.SetGenerator(%q, %d)\n", recv, cfg.version, pkgsyms.APIVersion)
+		markReady = fmt.Sprintf("\t%s.MarkReady()\n", recv)
+		funcDecl = fmt.Sprintf(
+			"// %s adds the package's symbols to p and marks it ready.\n"+
+				"func %s(p *%s.Package)",
+			cfg.registrar, cfg.registrar, pkgsymsPkgName)
+		if len(tagged) > 0 {
+			condDecl = fmt.Sprintf(
+				"// %s are the registrars of the symbols declared in\n"+
+					"// files with build constraints.\n"+
+					"var %s []func(p *%s.Package)\n\n",
+				conditionalRegistrars, conditionalRegistrars, pkgsymsPkgName)
+			conditional = fmt.Sprintf(
+				"\tfor _, register := range %s {\n\t\tregister(p)\n\t}\n",
+				conditionalRegistrars)
+		}
+	}
+
+	var extra string
+	if g.prefix == "" && g.hasExtraSymbols() {
+		extra = fmt.Sprintf("\t%s.%s(%s()...)\n", recv, addConditional, extraSymbolsFunc)
+	}
+
+	var embedDecl, addManifest string
+	if cfg.manifest != "" {
+		if err := g.writeManifest(cfg.manifest); err != nil {
+			return err
+		}
+		imports = "_ \"embed\"\n\n\t" + imports
+		embedDecl = fmt.Sprintf(
+			"\n//go:embed %s\nvar %sManifest []byte\n",
+			filepath.Base(cfg.manifest), pkgsymsPkgName)
+		addManifest = fmt.Sprintf(
+			"\t%s.AddManifest(%sManifest)\n", recv, pkgsymsPkgName)
+	}
+
+	declstrs := make([]string, len(g.decls))
+	for i, d := range g.decls {
+		declstrs[i] = strings.Join(
+			[]string{"\t\t", d.String(), ",\n"}, "")
+	}
+	symbols := strings.Join(declstrs, "")
+
+	header := fmt.Sprintf(
+		"// Code generated by \"%s\"; DO NOT EDIT.\n%s",
+		cfg.command, g.versionComment())
+	// The symbols of a target aren't in a Package that Verify can check.
+	verifyDoc := "  See\n// (*pkgsyms.Package).Verify."
+	if cfg.target != "" {
+		verifyDoc = ""
+	}
+	checksum := fmt.Sprintf(`// %s is the checksum of the symbols registered by this file.%s
+const %[1]s = %[3]q
+`,
+		cfg.checksumName(), verifyDoc, pkgsyms.ChecksumOf(checklines))
+	if cfg.appending {
+		header = fmt.Sprintf(
+			"// The code between the pkgsyms:begin and pkgsyms:end markers is\n"+
+				"// generated by \"%s\".  Edit outside of the markers.\n%s",
+			cfg.command, g.versionComment())
+		checksum = markRegion("", checksumRegion, "\n"+checksum+"\n")
+		symbols = markRegion("\t\t", symbolsRegion, symbols)
+	}
+
+	var setDoc string
+	if cfg.docs && cfg.target == "" {
+		if doc := g.packageDoc(); doc != "" {
+			setDoc = fmt.Sprintf("\t%s.SetDoc(%q)\n", recv, doc)
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(
+		&buf, `%s
+
+package %s
+
+import (
+	%s
+)
+
+// Fails to compile against versions of %s that don't support the
+// generated code.
+const _ = %s.SupportsAPIVersion%d
+%s%s
+%s
+%s%s {
+%s%s	%s.Add(
+%s	)
+%s%s%s%s}
+`,
+		header,
+		pkgname,
+		imports,
+		pkgsymsPkgName,
+		pkgsymsPkgName,
+		pkgsyms.APIVersion,
+		pkgVar,
+		embedDecl,
+		checksum,
+		condDecl,
+		funcDecl,
+		setGenerator,
+		setDoc,
+		recv,
+		symbols,
+		addManifest,
+		extra,
+		conditional,
+		markReady,
+	)
+	out := buf.Bytes()
+	if len(cfg.existing) > 0 {
+		var err error
+		generated := out
+		if out, err = spliceRegions(cfg.existing, generated); err != nil {
+			return err
+		}
+		if out, err = mergeImports(out, generated); err != nil {
+			return err
+		}
+	}
+	if _, err := w.Write(out); err != nil {
+		return err
+	}
+	for _, f := range g.sinkFiles {
+		if err := cfg.sink(f.filename, f.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeFile is the default FileSink.
+func writeFile(filename string, data []byte) error {
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %q: %w", filename, err)
+	}
+	return nil
+}
+
+// GenerateFiles generates the registry of pkg in memory and returns the
+// contents of every file it's made of by their names: the Go file named
+// filename, the manifest if Manifest is used and the files for build
+// constraints, which are named after filename.  Nothing is written to the file
+// system.
+func GenerateFiles(pkg *packages.Package, filename string, options ...Option) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	collect := func(name string, data []byte) error {
+		files[name] = append([]byte(nil), data...)
+		return nil
+	}
+	options = append(options[:len(options):len(options)],
+		FileSink(collect), TaggedFiles(filename, collect))
+	var buf bytes.Buffer
+	if err := Generate(&buf, pkg, options...); err != nil {
+		return nil, err
+	}
+	files[filename] = buf.Bytes()
+	return files, nil
+}
+
+// versionComment describes the generator's version in generated files.
+func (g *generator) versionComment() string {
+	return fmt.Sprintf(
+		"// pkgsyms version %s, API version %d.",
+		g.cfg.version, pkgsyms.APIVersion)
+}
+
+// sortDecls sorts decls by their kinds and then their names.
+func sortDecls(decls []decl) {
+	sort.Slice(decls, func(i, j int) bool {
+		a, b := decls[i], decls[j]
+		c := a.kind - b.kind
+		if c != 0 {
+			return c < 0
+		}
+		return strings.Compare(a.Name, b.Name) < 0
+	})
+}
+
+type generator struct {
+	pkg    *packages.Package
+	cfg    *Config
+	decls  []decl
+	prefix string
+
+	// appended is set while inspecting a file written with Append, whose
+	// generated declarations aren't the package's own symbols.
+	appended bool
+
+	// alias is the name that the generated file refers to the package by.
+	alias string
+
+	// namePrefix is prepended to the registered names.
+	namePrefix string
+
+	// targetExpr is the expression of the Symbols that the symbols are
+	// registered into with Target.
+	targetExpr string
+
+	// skipped describes the symbols that can't be registered.
+	skipped []string
+
+	// sinkFiles are passed to the FileSink once the Go file is written.
+	sinkFiles []sinkFile
+}
+
+type sinkFile struct {
+	filename string
+	data     []byte
+}
+
+// localName gets the name that the file being appended to imports the
+// package by, or else the package's name.
+func (g *generator) localName() string {
+	if len(g.cfg.existing) == 0 {
+		return g.pkg.Name
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "", g.cfg.existing, parser.ImportsOnly)
+	if err != nil {
+		return g.pkg.Name
+	}
+	for _, imp := range f.Imports {
+		name, path := importSpec(imp)
+		if path == g.pkg.PkgPath && name != "" && name != "_" && name != "." {
+			return name
+		}
+	}
+	return g.pkg.Name
+}
+
+func (g *generator) generate(omitPrefix bool) {
+	if !omitPrefix {
+		g.prefix = g.alias + "."
+	}
+	if g.cfg.qualify == "alias" {
+		g.namePrefix = g.alias + "."
+	}
+	if len(g.pkg.Syntax) == 0 {
+		g.inspectScope()
+		return
+	}
+	for _, f := range g.pkg.Syntax {
+		if generatedSyntax(f) {
+			// A previously generated file would have its own
+			// package variable registered.
+			continue
+		}
+		g.appended = appendedSyntax(f)
+		ast.Inspect(f, g.inspect)
+	}
+}
+
+// checksumName is the name of the generated constant holding the checksum.
+// It's unexported so that generating a registry doesn't add to the
+// package's API.
+func (c *Config) checksumName() string {
+	r, n := utf8.DecodeRuneInString(c.varName)
+	return string(unicode.ToLower(r)) + c.varName[n:] + "Checksum"
+}
+
+// generatedName reports whether name is declared by the generated code of the
+// file being inspected rather than by the package.
+func (g *generator) generatedName(name string) bool {
+	return g.appended && (name == g.cfg.varName ||
+		name == g.cfg.checksumName() || name == g.cfg.registrar)
+}
+
+// generatedSyntax reports whether f starts with the header of the files
+// that the generator writes.
+func generatedSyntax(f *ast.File) bool {
+	return len(f.Comments) > 0 && f.Comments[0].Pos() < f.Package &&
+		Header.MatchString(f.Comments[0].List[0].Text)
+}
+
+func (g *generator) inspect(n ast.Node) bool {
+	var kind declKind
+	var sb strings.Builder
+	switch n := n.(type) {
+	case *ast.GenDecl:
+		switch n.Tok {
+		case token.TYPE:
+			for _, s := range n.Specs {
+				ts := s.(*ast.TypeSpec)
+				name := ts.Name
+				if !name.IsExported() {
+					continue
+				}
+				if ts.Assign.IsValid() && ts.TypeParams != nil {
+					g.skip(name.Pos(), typeDecl, name.Name,
+						"generic type aliases aren't supported")
+					continue
+				}
+				d := decl{
+					g:      g,
+					kind:   typeDecl,
+					Name:   name.Name,
+					Doc:    specDoc(n, ts.Doc),
+					rename: nameDirective(specComments(n, ts.Doc)),
+					pos:    name.Pos(),
+				}
+				g.classifyType(&d, g.pkg.TypesInfo.Defs[name].Type())
+				g.decls = append(g.decls, d)
+			}
+			return false
+		case token.CONST:
+			kind = constDecl
+			fallthrough
+		case token.VAR:
+			if kind == badDecl {
+				kind = varDecl
+			}
+			first := len(g.decls)
+			for _, s := range n.Specs {
+				vs := s.(*ast.ValueSpec)
+				for i, id := range vs.Names {
+					if !id.IsExported() || g.generatedName(id.Name) {
+						continue
+					}
+					tp := vs.Type
+					if tp == nil && i < len(vs.Values) {
+						tp = vs.Values[i]
+					}
+					sb.Reset()
+					if tp == nil {
+						// implicitly repeated const spec,
+						// e.g. after iota.
+					} else if err := printer.Fprint(&sb, g.pkg.Fset, tp); err != nil {
+						log.Fatal(fmt.Errorf(
+							"failed to get type of %#v: %w", vs, err))
+					}
+					obj := g.pkg.TypesInfo.Defs[id]
+					c, _ := obj.(*types.Const)
+					_, isFunc := obj.Type().Underlying().(*types.Signature)
+					container := kind == varDecl && isContainer(obj.Type())
+					g.decls = append(g.decls, decl{
+						g:      g,
+						kind:   kind,
+						Name:   id.Name,
+						Type:   sb.String(),
+						Doc:    specDoc(n, vs.Doc),
+						rename: nameDirective(specComments(n, vs.Doc)),
+						readOnly: kind == varDecl && (g.cfg.readOnly[id.Name] ||
+							hasDirective(specComments(n, vs.Doc), readOnlyDirective)),
+						pos:       id.Pos(),
+						cnst:      c,
+						isFunc:    isFunc,
+						container: container,
+						dataSize:  g.dataSize(n, vs, i, kind),
+					})
+				}
+			}
+			groupConsts(g.decls[first:], n)
+			return false
+		}
+	case *ast.FuncDecl:
+		if n.Recv != nil {
+			return true
+		}
+		if !n.Name.IsExported() || g.generatedName(n.Name.Name) {
+			return true
+		}
+		d := decl{
+			g:      g,
+			kind:   funcDecl,
+			Name:   n.Name.Name,
+			Doc:    strings.TrimSpace(n.Doc.Text()),
+			rename: nameDirective(n.Doc),
+			pos:    n.Name.Pos(),
+		}
+		g.classifyFunc(&d, g.pkg.TypesInfo.Defs[n.Name].Type().(*types.Signature))
+		g.decls = append(g.decls, d)
+		return false
+	}
+	return true
+}
+
+// classifyType sets the kind of a typeDecl for generic types and constraint
+// interfaces and records the underlying types of other types.
+func (g *generator) classifyType(d *decl, t types.Type) {
+	if named, ok := t.(*types.Named); ok && named.TypeParams().Len() > 0 {
+		d.kind = genericDecl
+		d.params = g.typeParams(named.TypeParams())
+	} else if it, ok := t.Underlying().(*types.Interface); ok && !it.IsMethodSet() {
+		d.kind = constraintDecl
+		d.Type = types.TypeString(it, g.qualifier)
+	} else if !isStructOrInterface(t.Underlying()) {
+		d.Type = types.TypeString(t.Underlying(), g.qualifier)
+	}
+}
+
+// classifyFunc sets the kind of generic funcDecls.
+func (g *generator) classifyFunc(d *decl, sig *types.Signature) {
+	if sig.TypeParams().Len() > 0 {
+		d.kind = genericDecl
+		d.params = g.typeParams(sig.TypeParams())
+	}
+}
+
+// inspectScope creates the decls from the package's type information when
+// its syntax isn't available, like when it's loaded from export data.
+// Without syntax, there are no doc comments or directives.
+func (g *generator) inspectScope() {
+	scope := g.pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		d := decl{g: g, Name: name, pos: obj.Pos()}
+		switch obj := obj.(type) {
+		case *types.Const:
+			d.kind = constDecl
+			d.cnst = obj
+		case *types.TypeName:
+			d.kind = typeDecl
+			g.classifyType(&d, obj.Type())
+		case *types.Func:
+			d.kind = funcDecl
+			g.classifyFunc(&d, obj.Type().(*types.Signature))
+		case *types.Var:
+			d.kind = varDecl
+			_, d.isFunc = obj.Type().Underlying().(*types.Signature)
+			d.container = isContainer(obj.Type())
+			d.readOnly = g.cfg.readOnly[name]
+		default:
+			continue
+		}
+		g.decls = append(g.decls, d)
+	}
+}
+
+// skip records that the named symbol can't be registered and why.
+func (g *generator) skip(pos token.Pos, kind declKind, name, reason string) {
+	g.skipped = append(g.skipped, fmt.Sprintf(
+		"%v: skipping %s %s: %s", g.pkg.Fset.Position(pos), kind, name, reason))
+}
+
+// checkConsts skips the untyped constants whose values overflow their
+// default types, so they can't be passed to pkgsyms.MakeConst.
+func (g *generator) checkConsts() {
+	decls := g.decls[:0]
+	for _, d := range g.decls {
+		if d.cnst != nil {
+			if t := overflowedType(d.cnst); t != nil {
+				g.skip(d.pos, d.kind, d.Name, fmt.Sprintf(
+					"%s overflows %s", d.cnst.Val(), t))
+				continue
+			}
+		}
+		decls = append(decls, d)
+	}
+	g.decls = decls
+}
+
+// overflowedType gets the default type of an untyped constant if its value
+// overflows it, or nil if it doesn't.
+func overflowedType(c *types.Const) types.Type {
+	b, ok := c.Type().(*types.Basic)
+	if !ok || b.Info()&types.IsUntyped == 0 {
+		return nil
+	}
+	t := types.Default(b).(*types.Basic)
+	v := c.Val()
+	switch t.Kind() {
+	case types.Int:
+		if _, exact := constant.Int64Val(v); !exact {
+			return t
+		}
+	case types.Int32:
+		if x, exact := constant.Int64Val(v); !exact || int64(int32(x)) != x {
+			return t
+		}
+	case types.Float64:
+		if x, _ := constant.Float64Val(v); math.IsInf(x, 0) {
+			return t
+		}
+	case types.Complex128:
+		re, _ := constant.Float64Val(constant.Real(v))
+		im, _ := constant.Float64Val(constant.Imag(v))
+		if math.IsInf(re, 0) || math.IsInf(im, 0) {
+			return t
+		}
+	}
+	return nil
+}
+
+// packageDoc gets the package's doc comment.
+func (g *generator) packageDoc() string {
+	for _, f := range g.pkg.Syntax {
+		if f.Doc != nil {
+			return strings.TrimSpace(f.Doc.Text())
+		}
+	}
+	return ""
+}
+
+// recordMethods records the exported methods of the typeDecls.
+func (g *generator) recordMethods() {
+	if !g.cfg.methods {
+		return
+	}
+	for i, d := range g.decls {
+		if d.kind != typeDecl {
+			continue
+		}
+		t := g.pkg.Types.Scope().Lookup(d.Name).Type()
+		if !types.IsInterface(t) {
+			t = types.NewPointer(t)
+		}
+		ms := types.NewMethodSet(t)
+		for j := 0; j < ms.Len(); j++ {
+			fn := ms.At(j).Obj().(*types.Func)
+			if !fn.Exported() {
+				continue
+			}
+			sig := fn.Type().(*types.Signature)
+			_, ptr := sig.Recv().Type().(*types.Pointer)
+			g.decls[i].methods = append(g.decls[i].methods, pkgsyms.Method{
+				Name: fn.Name(),
+				Signature: types.TypeString(
+					types.NewSignatureType(nil, nil, nil,
+						sig.Params(), sig.Results(), sig.Variadic()),
+					g.qualifier),
+				Pointer: ptr,
+			})
+		}
+	}
+}
+
+// exclude removes the decls whose types match the excluded type patterns.
+func (g *generator) exclude() {
+	if len(g.cfg.excludeTypes) == 0 {
+		return
+	}
+	decls := g.decls[:0]
+	for _, d := range g.decls {
+		t := g.pkg.Types.Scope().Lookup(d.Name).Type()
+		if d.kind == typeDecl {
+			t = t.Underlying()
+		}
+		ts := types.TypeString(t, g.qualifier)
+		excluded := false
+		for _, re := range g.cfg.excludeTypes {
+			if re.MatchString(ts) {
+				excluded = true
+				break
+			}
+		}
+		if !excluded {
+			decls = append(decls, d)
+		}
+	}
+	g.decls = decls
+}
+
+// checkSyncVars warns about variables whose types contain values from the
+// sync and sync/atomic packages.  Setting such variables through the
+// registry copies over their state while they may be in use.  With
+// ReadOnlySync, they're registered read-only instead.
+func (g *generator) checkSyncVars() {
+	for i, d := range g.decls {
+		if d.kind != varDecl || d.readOnly {
+			continue
+		}
+		t := g.pkg.Types.Scope().Lookup(d.Name).Type()
+		st := syncType(t, make(map[types.Type]bool))
+		if st == nil {
+			continue
+		}
+		if g.cfg.readOnlySync {
+			g.decls[i].readOnly = true
+			continue
+		}
+		how := "contains a"
+		if types.Identical(st, t) {
+			how = "is a"
+		}
+		g.cfg.warnf(
+			"%v: Var %s %s %s; setting it through the registry "+
+				"overwrites its state; use -readonly-sync or a %q "+
+				"directive to register it read-only",
+			g.pkg.Fset.Position(d.pos), d.Name, how,
+			types.TypeString(st, (*types.Package).Path),
+			readOnlyDirective)
+	}
+}
+
+// checkVarSizes warns about the variables larger than MaxVarSize.
+func (g *generator) checkVarSizes() {
+	if g.cfg.maxVarSize <= 0 || g.pkg.TypesSizes == nil {
+		return
+	}
+	for _, d := range g.decls {
+		if d.kind != varDecl {
+			continue
+		}
+		t := g.pkg.Types.Scope().Lookup(d.Name).Type()
+		if size := g.pkg.TypesSizes.Sizeof(t) + d.dataSize; size > g.cfg.maxVarSize {
+			g.cfg.warnf(
+				"%v: Var %s is %d bytes; registering it keeps it in every "+
+					"binary that imports the package; use -exclude-type "+
+					"or -max-var-size to change that",
+				g.pkg.Fset.Position(d.pos), d.Name, size)
+		}
+	}
+}
+
+// embedDirectivePrefix starts the directive that initializes a variable with
+// the contents of files.
+const embedDirectivePrefix = "//go:embed "
+
+// dataSize gets the length of the constant string, or of a []byte
+// conversion of one, that the i'th name of vs is initialized with, or the
+// sum of the sizes of the files that vs embeds.  It's zero for other
+// declarations.
+func (g *generator) dataSize(gd *ast.GenDecl, vs *ast.ValueSpec, i int, kind declKind) int64 {
+	if kind != varDecl {
+		return 0
+	}
+	if i < len(vs.Values) {
+		e := vs.Values[i]
+		if call, ok := e.(*ast.CallExpr); ok && len(call.Args) == 1 {
+			e = call.Args[0]
+		}
+		if tv, ok := g.pkg.TypesInfo.Types[e]; ok &&
+			tv.Value != nil && tv.Value.Kind() == constant.String {
+			return int64(len(constant.StringVal(tv.Value)))
+		}
+		return 0
+	}
+	cg := specComments(gd, vs.Doc)
+	if cg == nil {
+		return 0
+	}
+	dir := filepath.Dir(g.pkg.Fset.Position(vs.Pos()).Filename)
+	var size int64
+	for _, c := range cg.List {
+		if !strings.HasPrefix(c.Text, embedDirectivePrefix) {
+			continue
+		}
+		for _, pattern := range strings.Fields(c.Text[len(embedDirectivePrefix):]) {
+			if p, err := strconv.Unquote(pattern); err == nil {
+				pattern = p
+			}
+			pattern = strings.TrimPrefix(pattern, "all:")
+			matches, _ := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+			for _, m := range matches {
+				filepath.WalkDir(m, func(_ string, de fs.DirEntry, err error) error {
+					if err != nil {
+						return nil
+					}
+					if info, err := de.Info(); err == nil && info.Mode().IsRegular() {
+						size += info.Size()
+					}
+					return nil
+				})
+			}
+		}
+	}
+	return size
+}
+
+// syncType gets the first type from the sync or sync/atomic packages that t
+// is or contains without indirection, or nil if there isn't one.
+func syncType(t types.Type, seen map[types.Type]bool) types.Type {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	if named, ok := t.(*types.Named); ok {
+		if pkg := named.Obj().Pkg(); pkg != nil {
+			switch pkg.Path() {
+			case "sync", "sync/atomic":
+				return t
+			}
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if st := syncType(u.Field(i).Type(), seen); st != nil {
+				return st
+			}
+		}
+	case *types.Array:
+		return syncType(u.Elem(), seen)
+	}
+	return nil
+}
+
+// qualifier qualifies types from other packages by their package names.
+func (g *generator) qualifier(p *types.Package) string {
+	if p == g.pkg.Types {
+		return ""
+	}
+	return p.Name()
+}
+
+// isStructOrInterface reports whether t is a struct or interface type.  The
+// definitions of other underlying types are recorded in the registry.
+func isStructOrInterface(t types.Type) bool {
+	switch t.(type) {
+	case *types.Struct, *types.Interface:
+		return true
+	}
+	return false
+}
+
+// typeParams describes the type parameters of a generic type or function.
+func (g *generator) typeParams(tps *types.TypeParamList) []pkgsyms.TypeParam {
+	params := make([]pkgsyms.TypeParam, tps.Len())
+	for i := range params {
+		tp := tps.At(i)
+		params[i] = pkgsyms.TypeParam{
+			Name:       tp.Obj().Name(),
+			Constraint: types.TypeString(tp.Constraint(), g.qualifier),
+		}
+	}
+	return params
+}
+
+// specDoc gets the documentation of a spec within a declaration.  Specs in a
+// parenthesized group use their own comments; otherwise the comment belongs to
+// the declaration.
+func specDoc(gd *ast.GenDecl, doc *ast.CommentGroup) string {
+	return strings.TrimSpace(specComments(gd, doc).Text())
+}
+
+// specComments gets the comments documenting a spec.  See specDoc.
+func specComments(gd *ast.GenDecl, doc *ast.CommentGroup) *ast.CommentGroup {
+	if doc == nil && !gd.Lparen.IsValid() {
+		doc = gd.Doc
+	}
+	return doc
+}
+
+// nameDirectivePrefix starts a directive in a doc comment that registers the
+// symbol under another name, e.g.:
+//
+//	//pkgsyms:name Package
+//	var Pkg = ...
+const nameDirectivePrefix = "//pkgsyms:name "
+
+// groupConsts groups the constDecls of a parenthesized const block, if it
+// has more than one, under the name of the first.
+func groupConsts(decls []decl, n *ast.GenDecl) {
+	if n.Tok != token.CONST || !n.Lparen.IsValid() || len(decls) < 2 {
+		return
+	}
+	group := decls[0].regName()
+	for i := range decls {
+		decls[i].group = group
+	}
+}
+
+// isContainer reports whether t is a map or channel type.
+func isContainer(t types.Type) bool {
+	switch t.Underlying().(type) {
+	case *types.Map, *types.Chan:
+		return true
+	}
+	return false
+}
+
+// readOnlyDirective in a variable's doc comment registers it read-only.
+const readOnlyDirective = "//pkgsyms:readonly"
+
+// nameDirective gets the name from a name directive in the comments, if any.
+func nameDirective(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+	for _, c := range cg.List {
+		if strings.HasPrefix(c.Text, nameDirectivePrefix) {
+			return strings.TrimSpace(c.Text[len(nameDirectivePrefix):])
+		}
+	}
+	return ""
+}
+
+// hasDirective reports whether the comments include the directive.
+func hasDirective(cg *ast.CommentGroup, directive string) bool {
+	if cg == nil {
+		return false
+	}
+	for _, c := range cg.List {
+		if strings.TrimSpace(c.Text) == directive {
+			return true
+		}
+	}
+	return false
+}
+
+type decl struct {
+	g *generator
+
+	kind declKind
+
+	// Name of the declared object
+	Name string
+
+	// optional type of the object.  For typeDecls, it's the underlying
+	// type unless it's a struct or interface.
+	Type string
+
+	// Doc is the object's doc comment.
+	Doc string
+
+	// cnst is the type-checked constant of a constDecl.
+	cnst *types.Const
+
+	// isFunc is set on varDecls of function type.
+	isFunc bool
+
+	// container is set on varDecls of map or channel type.
+	container bool
+
+	// group is the registered name of the first constant of the
+	// parenthesized const block of a constDecl, if it has more than one.
+	group string
+
+	// params are the type parameters of a genericDecl.
+	params []pkgsyms.TypeParam
+
+	// rename is the name given by a name directive, if any.
+	rename string
+
+	// pos is the position of the declared name.
+	pos token.Pos
+
+	// readOnly registers a varDecl read-only.
+	readOnly bool
+
+	// dataSize is the length of the constant string or the sum of the
+	// sizes of the //go:embed files that a varDecl is initialized with.
+	dataSize int64
+
+	// implements are the names of the interfaces a typeDecl implements.
+	implements []string
+
+	// methods are the exported methods of a typeDecl.
+	methods []pkgsyms.Method
+
+	// schema are the fields of a struct typeDecl.
+	schema []pkgsyms.Field
+
+	// constraint is the build constraint of the file the decl is in, if
+	// it has one and TaggedFiles is used.
+	constraint string
+}
+
+type declKind int
+
+const (
+	badDecl declKind = iota
+	constDecl
+	typeDecl
+	funcDecl
+	varDecl
+	genericDecl
+	constraintDecl
+)
+
+var declStrings = []string{
+	"<bad decl>",
+	"Const",
+	"Type",
+	"Func",
+	"Var",
+	"Generic",
+	"Constraint",
+}
+
+func (k declKind) String() string { return declStrings[int(k)] }
+
+// regName is the name the decl is registered under.
+func (d decl) regName() string {
+	if d.rename != "" {
+		return d.g.namePrefix + d.rename
+	}
+	return d.g.namePrefix + d.Name
+}
+
+func (d decl) String() string {
+	var s string
+	switch d.kind {
+	case typeDecl:
+		s = fmt.Sprintf(
+			"%s.MakeType(%q, (*%s)(nil))",
+			pkgsymsPkgName, d.regName(), d.g.prefix+d.Name)
+		if d.Type != "" {
+			s += fmt.Sprintf(".WithUnderlying(%q)", d.Type)
+		}
+		if len(d.implements) > 0 {
+			names := make([]string, len(d.implements))
+			for i, name := range d.implements {
+				names[i] = strconv.Quote(name)
+			}
+			s += fmt.Sprintf(".WithImplements(%s)", strings.Join(names, ", "))
+		}
+		if len(d.methods) > 0 {
+			ms := make([]string, len(d.methods))
+			for i, m := range d.methods {
+				ptr := ""
+				if m.Pointer {
+					ptr = ", Pointer: true"
+				}
+				ms[i] = fmt.Sprintf(
+					"%s.Method{Name: %q, Signature: %q%s}",
+					pkgsymsPkgName, m.Name, m.Signature, ptr)
+			}
+			s += fmt.Sprintf(".WithMethods(%s)", strings.Join(ms, ", "))
+		}
+		if len(d.schema) > 0 {
+			fs := make([]string, len(d.schema))
+			for i, f := range d.schema {
+				req := ""
+				if f.Required {
+					req = ", Required: true"
+				}
+				fs[i] = fmt.Sprintf(
+					"%s.Field{Name: %q, GoName: %q, Kind: %q, Type: %q%s}",
+					pkgsymsPkgName, f.Name, f.GoName, f.Kind, f.Type, req)
+			}
+			s += fmt.Sprintf(".WithSchema(%s)", strings.Join(fs, ", "))
+		}
+	case genericDecl:
+		params := make([]string, len(d.params))
+		for i, tp := range d.params {
+			params[i] = fmt.Sprintf(
+				", %s.TypeParam{Name: %q, Constraint: %q}",
+				pkgsymsPkgName, tp.Name, tp.Constraint)
+		}
+		s = fmt.Sprintf(
+			"%s.MakeGeneric(%q%s)",
+			pkgsymsPkgName, d.regName(), strings.Join(params, ""))
+	case constraintDecl:
+		s = fmt.Sprintf(
+			"%s.MakeConstraint(%q, %q)",
+			pkgsymsPkgName, d.regName(), d.Type)
+	case constDecl:
+		s = fmt.Sprintf(
+			"%s.MakeConst(%q, %s)",
+			pkgsymsPkgName, d.regName(), d.g.prefix+d.Name)
+		if d.group != "" {
+			s += fmt.Sprintf(".WithGroup(%q)", d.group)
+		}
+	case varDecl:
+		s = fmt.Sprintf(
+			"%s.MakeVar(%q, &%s)",
+			pkgsymsPkgName, d.regName(), d.g.prefix+d.Name)
+		if d.g.cfg.funcVars && d.isFunc {
+			s += ".Callable()"
+		}
+		if d.g.cfg.safeContainers && d.container {
+			s += ".Guarded()"
+		}
+		if d.readOnly {
+			s += ".ReadOnly()"
+		}
+	default:
+		s = fmt.Sprintf(
+			"%s.Make%s(%q, %s)",
+			pkgsymsPkgName, d.kind, d.regName(), d.g.prefix+d.Name)
+	}
+	if d.g.cfg.docs && d.Doc != "" {
+		s += fmt.Sprintf(".WithDoc(%q)", d.Doc)
+	}
+	return s
+}
@@ -0,0 +1,89 @@
+package gen_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms/pkgsyms/gen"
+	"golang.org/x/tools/go/packages"
+)
+
+// loadModule writes files into a new module and loads its package.
+func loadModule(t *testing.T, files map[string]string) (dir string, pkg *packages.Package) {
+	t.Helper()
+	dir = t.TempDir()
+	files["go.mod"] = "module example.com/files\n"
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkgs, err := packages.Load(&packages.Config{Mode: gen.LoadMode, Dir: dir}, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 || len(pkgs[0].Errors) > 0 {
+		t.Fatalf("failed to load %s: %v", dir, pkgs)
+	}
+	return dir, pkgs[0]
+}
+
+func TestGenerateFiles(t *testing.T) {
+	out := t.TempDir()
+	dir, pkg := loadModule(t, map[string]string{
+		"files.go":         "package files\n\n// Answer is the answer.\nconst Answer = 42\n",
+		"files_linux.go":   "package files\n\nfunc Linux() {}\n",
+		"files_windows.go": "package files\n\nfunc Windows() {}\n",
+	})
+	filename := filepath.Join(out, "pkgsyms.go")
+	files, err := gen.GenerateFiles(pkg, filename, gen.Command("pkgsyms"),
+		gen.Docs(true), gen.Manifest(filepath.Join(out, "pkgsyms.json")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"pkgsyms.go", "pkgsyms.json", "pkgsyms_linux_tags.go", "pkgsyms_windows_tags.go"} {
+		if len(files[filepath.Join(out, name)]) == 0 {
+			t.Errorf("expected %s in %d files", name, len(files))
+		}
+	}
+	if first, _, _ := strings.Cut(string(files[filename]), "\n"); !gen.Header.MatchString(first) {
+		t.Errorf("expected %s to start with the header:\n%s", filename, files[filename])
+	}
+	if entries, err := os.ReadDir(out); err != nil || len(entries) != 0 {
+		t.Fatalf("expected nothing to be written, got %v, %v", entries, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pkgsyms.go")); err == nil {
+		t.Fatalf("expected nothing to be written to %s", dir)
+	}
+}
+
+func TestMaxVarSizeData(t *testing.T) {
+	_, pkg := loadModule(t, map[string]string{
+		"data.go": "package files\n\nimport _ \"embed\"\n\n" +
+			"// Asset is embedded.\n//\n//go:embed asset.txt\nvar Asset []byte\n\n" +
+			"// Text is a constant string.\nvar Text = \"" + strings.Repeat("x", 100) + "\"\n\n" +
+			"// Bytes converts a constant string.\nvar Bytes = []byte(\"" + strings.Repeat("x", 100) + "\")\n\n" +
+			"// Short is a short string.\nvar Short = \"x\"\n",
+		"asset.txt": strings.Repeat("x", 100),
+	})
+	var warnings []string
+	warnf := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	if err := gen.Generate(io.Discard, pkg, gen.MaxVarSize(64), gen.Warnf(warnf)); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Var Asset is 124 bytes", "Var Bytes is 124 bytes", "Var Text is 116 bytes"}
+	if len(warnings) != len(want) {
+		t.Fatalf("expected %d warnings but got %q", len(want), warnings)
+	}
+	for i, w := range want {
+		if !strings.Contains(warnings[i], w) {
+			t.Errorf("expected warning %d to contain %q, got %q", i, w, warnings[i])
+		}
+	}
+}
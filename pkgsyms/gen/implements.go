@@ -0,0 +1,151 @@
+package gen
+
+import (
+	"fmt"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"github.com/skillian/pkgsyms/pkgsyms/internal/load"
+	"golang.org/x/tools/go/packages"
+)
+
+// checkImplements records which of the interfaces in the configuration each
+// typeDecl implements and, with LocalImplements, which of the package's own
+// registered interfaces each concrete typeDecl implements.
+func (g *generator) checkImplements() error {
+	var (
+		names  []string
+		ifaces []*types.Interface
+	)
+	for _, name := range g.cfg.implements {
+		name = strings.TrimSpace(name)
+		it, err := g.lookupInterface(name)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+		ifaces = append(ifaces, it)
+	}
+	// Only the explicitly named interfaces are checked against interface
+	// types.
+	explicit := len(ifaces)
+	if g.cfg.localImplements {
+		for _, d := range g.decls {
+			if d.kind != typeDecl {
+				continue
+			}
+			it, ok := g.pkg.Types.Scope().Lookup(d.Name).Type().Underlying().(*types.Interface)
+			// Everything implements an empty interface and
+			// constraints can't be implemented.
+			if !ok || it.NumMethods() == 0 || !it.IsMethodSet() {
+				continue
+			}
+			names = append(names, d.regName())
+			ifaces = append(ifaces, it)
+		}
+	}
+	if len(ifaces) == 0 {
+		return nil
+	}
+	for i, d := range g.decls {
+		if d.kind != typeDecl {
+			continue
+		}
+		t := g.pkg.Types.Scope().Lookup(d.Name).Type()
+		n := len(ifaces)
+		if types.IsInterface(t) {
+			n = explicit
+		}
+		for j, it := range ifaces[:n] {
+			if types.Implements(t, it) || types.Implements(types.NewPointer(t), it) {
+				g.decls[i].implements = append(g.decls[i].implements, names[j])
+			}
+		}
+	}
+	return nil
+}
+
+// lookupInterface finds an interface by its package path and name, like
+// "io.Reader" or "github.com/acme/foo.Handler".  Interfaces in the generated
+// package can be named without their package.
+func (g *generator) lookupInterface(name string) (*types.Interface, error) {
+	pkg := g.pkg.Types
+	i := strings.LastIndexByte(name, '.')
+	if i >= 0 {
+		var err error
+		if pkg, err = g.loadTypes(name[:i]); err != nil {
+			return nil, err
+		}
+	}
+	obj := pkg.Scope().Lookup(name[i+1:])
+	if obj == nil {
+		return nil, fmt.Errorf("interface %q not found", name)
+	}
+	it, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an interface", name)
+	}
+	return it, nil
+}
+
+// loadTypes gets the types of the package with the given path, loading it if
+// the generated package doesn't depend on it.
+func (g *generator) loadTypes(path string) (*types.Package, error) {
+	if pkg := g.findPackage(path); pkg != nil {
+		return pkg, nil
+	}
+	mode := packages.NeedName | packages.NeedTypes |
+		packages.NeedImports | packages.NeedDeps
+	cfg, err := load.Config(g.dir(), mode, g.cfg.gopath)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := packages.Load(cfg, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %q: %w", path, err)
+	}
+	if len(pkgs) != 1 || pkgs[0].Types == nil {
+		return nil, fmt.Errorf("failed to load %q", path)
+	}
+	if len(pkgs[0].Errors) > 0 {
+		return nil, fmt.Errorf(
+			"failed to load %q: %v", path, pkgs[0].Errors[0])
+	}
+	return pkgs[0].Types, nil
+}
+
+// dir gets the directory of the generated package, where the packages of
+// the implemented interfaces are loaded from.  It's the working directory if
+// the package has no files, like when it's loaded from export data.
+func (g *generator) dir() string {
+	if len(g.pkg.GoFiles) == 0 {
+		return "."
+	}
+	return filepath.Dir(g.pkg.GoFiles[0])
+}
+
+// findPackage finds a package that the generated package depends on.
+func (g *generator) findPackage(path string) *types.Package {
+	if path == g.pkg.PkgPath {
+		return g.pkg.Types
+	}
+	var found *types.Package
+	seen := make(map[*packages.Package]bool)
+	var visit func(p *packages.Package)
+	visit = func(p *packages.Package) {
+		if found != nil || seen[p] {
+			return
+		}
+		seen[p] = true
+		if p.PkgPath == path {
+			found = p.Types
+			return
+		}
+		for _, imp := range p.Imports {
+			visit(imp)
+		}
+	}
+	visit(g.pkg)
+	return found
+}
@@ -0,0 +1,80 @@
+package gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/constant"
+	"go/types"
+	"strconv"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// writeManifest moves the constants with predeclared types and every decl's
+// documentation out of g.decls and into a pkgsyms.Manifest passed to the
+// FileSink as filename after the Go file was written.
+func (g *generator) writeManifest(filename string) error {
+	m := pkgsyms.Manifest{Docs: make(map[string]string)}
+	decls := g.decls[:0]
+	for _, d := range g.decls {
+		if g.cfg.docs && d.Doc != "" {
+			m.Docs[d.regName()] = d.Doc
+		}
+		d.Doc = ""
+		if mc, ok := manifestConst(d); ok {
+			m.Consts = append(m.Consts, mc)
+			continue
+		}
+		decls = append(decls, d)
+	}
+	g.decls = decls
+	var data []byte
+	var err error
+	if g.cfg.manifestCBOR {
+		data, err = m.MarshalCBOR()
+	} else {
+		data, err = json.MarshalIndent(m, "", "\t")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	g.sinkFiles = append(g.sinkFiles, sinkFile{filename, data})
+	return nil
+}
+
+// manifestConst describes a constant in a manifest if its type is one of
+// Go's predeclared types.  Untyped constants get their default type.
+func manifestConst(d decl) (mc pkgsyms.ManifestConst, ok bool) {
+	if d.cnst == nil {
+		return
+	}
+	t, ok := d.cnst.Type().(*types.Basic)
+	if !ok {
+		return
+	}
+	t = types.Default(t).(*types.Basic)
+	v := d.cnst.Val()
+	info := t.Info()
+	switch {
+	case info&types.IsBoolean != 0:
+		mc.Value = strconv.FormatBool(constant.BoolVal(v))
+	case info&types.IsString != 0:
+		mc.Value = constant.StringVal(v)
+	case info&types.IsInteger != 0:
+		mc.Value = v.ExactString()
+	case info&types.IsFloat != 0:
+		f, _ := constant.Float64Val(v)
+		bits := 64
+		if t.Kind() == types.Float32 {
+			bits = 32
+		}
+		mc.Value = strconv.FormatFloat(f, 'g', -1, bits)
+	default:
+		return mc, false
+	}
+	mc.Name = d.regName()
+	mc.Type = t.Name()
+	mc.Group = d.group
+	return mc, true
+}
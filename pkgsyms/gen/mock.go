@@ -0,0 +1,466 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/constant"
+	"go/format"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// mockNames are the names that the generated file declares besides the
+// stand-ins.
+var mockNames = []string{"PkgPath", "Call", "Calls", "Reset", "Register"}
+
+// mocker generates the stand-ins of a package.
+type mocker struct {
+	pkg *types.Package
+
+	// imports maps the paths of the imported packages to their names in
+	// the generated file.
+	imports map[string]string
+	used    map[string]bool
+
+	// standIns are the package's types that get stand-ins.
+	standIns map[*types.TypeName]bool
+}
+
+// Mock generates the source of a package named name that registers stand-ins
+// for pkg's exported symbols, like the pkgsyms command's mock subcommand.
+// The stand-in of a type has the same underlying type but no methods, a
+// function's stand-in records its calls and returns zero values and
+// variables start as zero values.  Constants keep their values.  If name is
+// empty, it's pkg's name followed by mock.  cmdline is recorded in the
+// generated file's header.  Symbols that can't have stand-ins, like those
+// referring to C types or to internal packages that the generated package
+// can't import, are listed in a comment instead, unless the Strict option
+// is given, which makes them an error.  Other options are ignored.
+func Mock(pkg *packages.Package, name, cmdline string, options ...Option) ([]byte, error) {
+	var cfg Config
+	for _, o := range options {
+		if err := o(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	if name == "" {
+		name = pkg.Name + "mock"
+	}
+	m := &mocker{
+		pkg:     pkg.Types,
+		imports: map[string]string{pkgsymsPkgPath: pkgsymsPkgName, "sync": "sync"},
+		used:    map[string]bool{pkgsymsPkgName: true, "sync": true},
+	}
+	scope := pkg.Types.Scope()
+	for _, n := range mockNames {
+		if obj := scope.Lookup(n); obj != nil && obj.Exported() {
+			return nil, fmt.Errorf(
+				"%s declares %s, which the mock declares too", pkg.PkgPath, n)
+		}
+	}
+
+	m.findStandIns()
+
+	var decls, stubs, adds, skipped []string
+	for _, n := range scope.Names() {
+		obj := scope.Lookup(n)
+		if !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.TypeName:
+			def, ok := m.typeDecl(obj)
+			if !ok {
+				skipped = append(skipped, n)
+				continue
+			}
+			decls = append(decls, def)
+			if it, ok := obj.Type().Underlying().(*types.Interface); ok && !it.IsMethodSet() {
+				// Constraints can't be used as values.
+				t, _ := m.typeString(it)
+				adds = append(adds, fmt.Sprintf("pkgsyms.MakeConstraint(%q, %q)", n, t))
+				continue
+			}
+			adds = append(adds, fmt.Sprintf("pkgsyms.MakeType(%q, (*%s)(nil))", n, n))
+		case *types.Const:
+			val, ok := m.constExpr(obj)
+			if !ok {
+				skipped = append(skipped, n)
+				continue
+			}
+			adds = append(adds, fmt.Sprintf("pkgsyms.MakeConst(%q, %s)", n, val))
+		case *types.Var:
+			t, ok := m.typeString(obj.Type())
+			if !ok {
+				skipped = append(skipped, n)
+				continue
+			}
+			stubs = append(stubs, fmt.Sprintf("var var%s %s\n", n, t))
+			adds = append(adds, fmt.Sprintf("pkgsyms.MakeVar(%q, &var%s)", n, n))
+		case *types.Func:
+			stub, ok := m.funcStub(obj)
+			if !ok {
+				skipped = append(skipped, n)
+				continue
+			}
+			stubs = append(stubs, stub)
+			adds = append(adds, fmt.Sprintf("pkgsyms.MakeFunc(%q, stub%s)", n, n))
+		}
+	}
+
+	if cfg.strict && len(skipped) > 0 {
+		return nil, fmt.Errorf(
+			"%d symbols of %s have no stand-ins: %s",
+			len(skipped), pkg.PkgPath, strings.Join(skipped, ", "))
+	}
+
+	paths := make([]string, 0, len(m.imports))
+	for p := range m.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by %q; DO NOT EDIT.\n\n", cmdline)
+	fmt.Fprintf(&buf, "// Package %s registers stand-ins for the symbols of %s.\n", name, pkg.PkgPath)
+	fmt.Fprintf(&buf, "package %s\n\nimport (\n", name)
+	// The standard library comes first, like goimports groups it.
+	sort.SliceStable(paths, func(i, j int) bool {
+		return isStd(paths[i]) && !isStd(paths[j])
+	})
+	for i, p := range paths {
+		if i > 0 && isStd(paths[i-1]) && !isStd(p) {
+			buf.WriteString("\n")
+		}
+		if n := m.imports[p]; n != pathName(p) {
+			fmt.Fprintf(&buf, "\t%s %q\n", n, p)
+		} else {
+			fmt.Fprintf(&buf, "\t%q\n", p)
+		}
+	}
+	fmt.Fprintf(&buf, `)
+
+// PkgPath is the import path of the package that the stand-ins are for.
+const PkgPath = %q
+
+// Call is a call to a stand-in function.
+type Call struct {
+	Name string
+	Args []interface{}
+}
+
+var calls struct {
+	sync.Mutex
+	slice []Call
+}
+
+// Calls gets the calls to the stand-in functions in the order they were
+// made.
+func Calls() []Call {
+	calls.Lock()
+	defer calls.Unlock()
+	return append([]Call(nil), calls.slice...)
+}
+
+// Reset forgets the recorded calls.
+func Reset() {
+	calls.Lock()
+	defer calls.Unlock()
+	calls.slice = nil
+}
+
+func record(name string, args ...interface{}) {
+	calls.Lock()
+	defer calls.Unlock()
+	calls.slice = append(calls.slice, Call{Name: name, Args: args})
+}
+`, pkg.PkgPath)
+	for _, d := range decls {
+		buf.WriteString("\n" + d)
+	}
+	for _, s := range stubs {
+		buf.WriteString("\n" + s)
+	}
+	buf.WriteString("\n// Register adds the stand-ins to p and marks it ready.\n")
+	if len(skipped) > 0 {
+		fmt.Fprintf(&buf,
+			"//\n// These symbols have no stand-ins: %s.\n",
+			strings.Join(skipped, ", "))
+	}
+	buf.WriteString("func Register(p *pkgsyms.Package) {\n\tp.Add(\n")
+	for _, a := range adds {
+		fmt.Fprintf(&buf, "\t\t%s,\n", a)
+	}
+	buf.WriteString("\t)\n\tp.MarkReady()\n}\n")
+	return format.Source(buf.Bytes())
+}
+
+// isStd reports whether the import path is in the standard library.
+func isStd(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// pathName guesses the name of the package with the import path.
+func pathName(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
+
+// qualifier refers to the package's own types by the names of their
+// stand-ins and to other packages by their import names.
+func (m *mocker) qualifier(p *types.Package) string {
+	if p == m.pkg {
+		return ""
+	}
+	if n, ok := m.imports[p.Path()]; ok {
+		return n
+	}
+	n := p.Name()
+	for i := 1; m.used[n] || m.pkg.Scope().Lookup(n) != nil; i++ {
+		n = p.Name() + strconv.Itoa(i)
+	}
+	m.imports[p.Path()] = n
+	m.used[n] = true
+	return n
+}
+
+// typeString formats t for the generated file.  It returns false if t can't
+// be written there because it refers to something that has no stand-in.
+func (m *mocker) typeString(t types.Type) (string, bool) {
+	if !m.representable(t) {
+		return "", false
+	}
+	return types.TypeString(t, m.qualifier), true
+}
+
+// importable reports whether the generated package can import the package
+// with the path, assuming that it's next to the mocked package.  Internal
+// packages can only be imported from the tree rooted at their parent.
+func (m *mocker) importable(path string) bool {
+	var root string
+	switch i := strings.LastIndex(path, "/internal/"); {
+	case i >= 0:
+		root = path[:i]
+	case strings.HasSuffix(path, "/internal"):
+		root = strings.TrimSuffix(path, "/internal")
+	case path == "internal" || strings.HasPrefix(path, "internal/"):
+		return isStd(m.pkg.Path())
+	default:
+		return true
+	}
+	return m.pkg.Path() == root || strings.HasPrefix(m.pkg.Path(), root+"/")
+}
+
+// representable reports whether t only refers to exported types that the
+// generated package can import and to the package's non-generic types,
+// which have stand-ins.  Types from C, which cgo gives unexported names,
+// aren't representable.
+func (m *mocker) representable(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.Basic:
+		return t.Kind() != types.UnsafePointer && t.Kind() != types.Invalid
+	case *types.Alias:
+		return m.representable(types.Unalias(t))
+	case *types.Named:
+		obj := t.Obj()
+		if obj.Pkg() == nil {
+			return true // error and comparable
+		}
+		if !obj.Exported() || obj.Pkg() == m.pkg && !m.standIns[obj] ||
+			!m.importable(obj.Pkg().Path()) {
+			return false
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			if !m.representable(t.TypeArgs().At(i)) {
+				return false
+			}
+		}
+		return true
+	case *types.Pointer:
+		return m.representable(t.Elem())
+	case *types.Slice:
+		return m.representable(t.Elem())
+	case *types.Array:
+		return m.representable(t.Elem())
+	case *types.Chan:
+		return m.representable(t.Elem())
+	case *types.Map:
+		return m.representable(t.Key()) && m.representable(t.Elem())
+	case *types.Signature:
+		if t.TypeParams().Len() > 0 {
+			return false
+		}
+		return m.representableTuple(t.Params()) && m.representableTuple(t.Results())
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if !m.representable(t.Field(i).Type()) {
+				return false
+			}
+		}
+		return true
+	case *types.Interface:
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			if !m.representable(t.ExplicitMethod(i).Type()) {
+				return false
+			}
+		}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			if !m.representable(t.EmbeddedType(i)) {
+				return false
+			}
+		}
+		return true
+	case *types.Union:
+		for i := 0; i < t.Len(); i++ {
+			if !m.representable(t.Term(i).Type()) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (m *mocker) representableTuple(t *types.Tuple) bool {
+	for i := 0; i < t.Len(); i++ {
+		if !m.representable(t.At(i).Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// findStandIns finds the package's exported types whose definitions only
+// refer to types that are representable, which in turn may depend on which
+// of the package's types have stand-ins.
+func (m *mocker) findStandIns() {
+	m.standIns = make(map[*types.TypeName]bool)
+	scope := m.pkg.Scope()
+	for _, n := range scope.Names() {
+		if tn, ok := scope.Lookup(n).(*types.TypeName); ok && tn.Exported() {
+			if named, ok := tn.Type().(*types.Named); !ok || named.TypeParams().Len() == 0 {
+				m.standIns[tn] = true
+			}
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for tn := range m.standIns {
+			def := tn.Type()
+			if !tn.IsAlias() {
+				def = def.Underlying()
+			}
+			if !m.representable(def) {
+				delete(m.standIns, tn)
+				changed = true
+			}
+		}
+	}
+}
+
+// typeDecl declares the stand-in of a type with the type's underlying type.
+func (m *mocker) typeDecl(obj *types.TypeName) (string, bool) {
+	if !m.standIns[obj] {
+		return "", false
+	}
+	if obj.IsAlias() {
+		t, ok := m.typeString(obj.Type())
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("type %s = %s\n", obj.Name(), t), true
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return "", false
+	}
+	t, ok := m.typeString(named.Underlying())
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf("type %s %s\n", obj.Name(), t), true
+}
+
+// constExpr gets an expression with the constant's value and type.
+func (m *mocker) constExpr(c *types.Const) (string, bool) {
+	if overflowedType(c) != nil {
+		return "", false
+	}
+	v := c.Val()
+	var lit string
+	switch v.Kind() {
+	case constant.Bool, constant.String, constant.Int:
+		lit = v.ExactString()
+	case constant.Float:
+		f, _ := constant.Float64Val(v)
+		bits := 64
+		if b, ok := c.Type().Underlying().(*types.Basic); ok && b.Kind() == types.Float32 {
+			bits = 32
+		}
+		lit = strconv.FormatFloat(f, 'g', -1, bits)
+		if !strings.ContainsAny(lit, ".eIN") {
+			lit += ".0"
+		}
+	case constant.Complex:
+		re, _ := constant.Float64Val(constant.Real(v))
+		im, _ := constant.Float64Val(constant.Imag(v))
+		lit = fmt.Sprintf("complex(%s, %s)",
+			strconv.FormatFloat(re, 'g', -1, 64), strconv.FormatFloat(im, 'g', -1, 64))
+	default:
+		return "", false
+	}
+	b, ok := c.Type().(*types.Basic)
+	switch {
+	case ok && b.Kind() == types.UntypedRune:
+		return "rune(" + lit + ")", true
+	case ok && b.Info()&types.IsUntyped != 0:
+		return lit, true
+	}
+	t, ok := m.typeString(c.Type())
+	if !ok {
+		return "", false
+	}
+	return t + "(" + lit + ")", true
+}
+
+// funcStub declares a function named stub followed by the function's name
+// with the function's signature that records its calls and returns zero
+// values.
+func (m *mocker) funcStub(fn *types.Func) (string, bool) {
+	sig := fn.Type().(*types.Signature)
+	if !m.representable(sig) {
+		return "", false
+	}
+	var params, args []string
+	for i := 0; i < sig.Params().Len(); i++ {
+		t := sig.Params().At(i).Type()
+		p := fmt.Sprintf("p%d", i)
+		s, _ := m.typeString(t)
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			s, _ = m.typeString(t.(*types.Slice).Elem())
+			s = "..." + s
+		}
+		params = append(params, p+" "+s)
+		args = append(args, p)
+	}
+	var results []string
+	for i := 0; i < sig.Results().Len(); i++ {
+		s, _ := m.typeString(sig.Results().At(i).Type())
+		results = append(results, fmt.Sprintf("r%d %s", i, s))
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "func stub%s(%s)", fn.Name(), strings.Join(params, ", "))
+	if len(results) > 0 {
+		fmt.Fprintf(&sb, " (%s)", strings.Join(results, ", "))
+	}
+	fmt.Fprintf(&sb, " {\n\trecord(%s)\n", strings.Join(append([]string{strconv.Quote(fn.Name())}, args...), ", "))
+	if len(results) > 0 {
+		sb.WriteString("\treturn\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String(), true
+}
@@ -0,0 +1,109 @@
+package gen
+
+import (
+	"go/types"
+	"reflect"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// recordSchemas records the fields of the struct typeDecls.
+func (g *generator) recordSchemas() {
+	if !g.cfg.schema {
+		return
+	}
+	for i, d := range g.decls {
+		if d.kind != typeDecl {
+			continue
+		}
+		st, ok := g.pkg.Types.Scope().Lookup(d.Name).Type().Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		g.decls[i].schema = g.structFields(nil, st, make(map[*types.Struct]bool))
+	}
+}
+
+// structFields appends the fields of st to fields the way encoding/json
+// sees them: by their json names, without unexported or "-" fields and with
+// the fields of untagged embedded structs promoted.
+func (g *generator) structFields(fields []pkgsyms.Field, st *types.Struct, seen map[*types.Struct]bool) []pkgsyms.Field {
+	if seen[st] {
+		return fields
+	}
+	seen[st] = true
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		tag := reflect.StructTag(st.Tag(i))
+		name, _, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		t := v.Type()
+		if p, ok := t.Underlying().(*types.Pointer); ok {
+			t = p.Elem()
+		}
+		if v.Embedded() && name == "" {
+			if est, ok := t.Underlying().(*types.Struct); ok {
+				fields = g.structFields(fields, est, seen)
+				continue
+			}
+		}
+		if !v.Exported() {
+			continue
+		}
+		if name == "" {
+			name = v.Name()
+		}
+		fields = append(fields, pkgsyms.Field{
+			Name:     name,
+			GoName:   v.Name(),
+			Kind:     kindOf(t),
+			Type:     types.TypeString(v.Type(), g.qualifier),
+			Required: required(tag),
+		})
+	}
+	return fields
+}
+
+// required reports whether a field's tag makes it required.
+func required(tag reflect.StructTag) bool {
+	if tag.Get("required") == "true" {
+		return true
+	}
+	for _, rule := range strings.Split(tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// kindOf names the kind of t like reflect.Kind.
+func kindOf(t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if u.Kind() == types.UnsafePointer {
+			return reflect.UnsafePointer.String()
+		}
+		return types.Typ[u.Kind()].Name()
+	case *types.Array:
+		return reflect.Array.String()
+	case *types.Slice:
+		return reflect.Slice.String()
+	case *types.Map:
+		return reflect.Map.String()
+	case *types.Struct:
+		return reflect.Struct.String()
+	case *types.Pointer:
+		return reflect.Pointer.String()
+	case *types.Signature:
+		return reflect.Func.String()
+	case *types.Interface:
+		return reflect.Interface.String()
+	case *types.Chan:
+		return reflect.Chan.String()
+	}
+	return reflect.Invalid.String()
+}
@@ -0,0 +1,74 @@
+package gen
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// parseTarget splits a target like "example.com/app.Registry.Symbols" into
+// the path of the variable's package and the names of the variable and its
+// fields.
+func parseTarget(target string) (pkgPath string, sels []string, err error) {
+	slash := strings.LastIndexByte(target, '/')
+	parts := strings.Split(target[slash+1:], ".")
+	if len(parts) < 2 {
+		return "", nil, fmt.Errorf(
+			"invalid target %q: expected the package path and variable, like example.com/app.Registry", target)
+	}
+	for _, sel := range parts[1:] {
+		if !token.IsIdentifier(sel) {
+			return "", nil, fmt.Errorf("invalid target %q: %q isn't an identifier", target, sel)
+		}
+	}
+	return target[:slash+1] + parts[0], parts[1:], nil
+}
+
+// resolveTarget checks that the Target is a variable or field of type
+// pkgsyms.Symbols or *pkgsyms.Symbols and sets targetExpr to the expression
+// that refers to it.  It returns the path of the package to import for it,
+// if the generated file doesn't have it yet.
+func (g *generator) resolveTarget() (importPath string, err error) {
+	pkgPath, sels, err := parseTarget(g.cfg.target)
+	if err != nil {
+		return "", err
+	}
+	pkg, err := g.loadTypes(pkgPath)
+	if err != nil {
+		return "", err
+	}
+	own := pkgPath == g.pkg.PkgPath
+	qualifier := g.prefix
+	if !own {
+		importPath, qualifier = pkgPath, pkg.Name()+"."
+	}
+	for _, sel := range sels {
+		if !own && !token.IsExported(sel) {
+			return "", fmt.Errorf("target %q: %s isn't exported", g.cfg.target, sel)
+		}
+	}
+	v, ok := pkg.Scope().Lookup(sels[0]).(*types.Var)
+	if !ok {
+		return "", fmt.Errorf("target %q: %s.%s isn't a variable", g.cfg.target, pkgPath, sels[0])
+	}
+	t := v.Type()
+	for _, sel := range sels[1:] {
+		obj, _, _ := types.LookupFieldOrMethod(t, true, pkg, sel)
+		f, ok := obj.(*types.Var)
+		if !ok || !f.IsField() {
+			return "", fmt.Errorf("target %q: %v has no field %s", g.cfg.target, t, sel)
+		}
+		t = f.Type()
+	}
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != pkgsymsPkgPath || named.Obj().Name() != "Symbols" {
+		return "", fmt.Errorf(
+			"target %q is a %v, not a %s.Symbols", g.cfg.target, t, pkgsymsPkgName)
+	}
+	g.targetExpr = qualifier + strings.Join(sels, ".")
+	return importPath, nil
+}
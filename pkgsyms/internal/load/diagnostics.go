@@ -0,0 +1,72 @@
+package load
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ExportDataNeeds loads a package's type information from its export data
+// without its syntax.
+const ExportDataNeeds = packages.NeedName | packages.NeedTypes | packages.NeedTypesSizes
+
+// Errors splits the errors of a loaded package into the ones that stop
+// generation and the ones that are only warned about.  Type errors don't
+// stop generation from source because the symbols are still found in the
+// syntax, but nothing can be generated from export data with errors.
+func Errors(pkg *packages.Package, mode packages.LoadMode) (fatal, warn []packages.Error) {
+	for _, e := range pkg.Errors {
+		if e.Kind == packages.TypeError && mode != ExportDataNeeds {
+			warn = append(warn, e)
+		} else {
+			fatal = append(fatal, e)
+		}
+	}
+	return
+}
+
+// Diagnostic formats e like the compiler does, with its severity and the
+// step of loading that reported it.
+func Diagnostic(e packages.Error, severity string) string {
+	var sb strings.Builder
+	if e.Pos != "" && e.Pos != "-" {
+		sb.WriteString(e.Pos)
+		sb.WriteString(": ")
+	}
+	fmt.Fprintf(&sb, "%s: %s", severity, e.Msg)
+	if kind := errorKindName(e.Kind); kind != "" {
+		fmt.Fprintf(&sb, " (%s)", kind)
+	}
+	return sb.String()
+}
+
+// Diagnostics formats errs with Diagnostic, one per line.
+func Diagnostics(errs []packages.Error, severity string) string {
+	strs := make([]string, len(errs))
+	for i, e := range errs {
+		strs[i] = Diagnostic(e, severity)
+	}
+	return strings.Join(strs, "\n\t")
+}
+
+func errorKindName(k packages.ErrorKind) string {
+	switch k {
+	case packages.ListError:
+		return "go list"
+	case packages.ParseError:
+		return "parse"
+	case packages.TypeError:
+		return "type check"
+	}
+	return ""
+}
+
+// PkgPaths gets the import paths of pkgs for messages.
+func PkgPaths(pkgs []*packages.Package) string {
+	paths := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		paths[i] = p.PkgPath
+	}
+	return strings.Join(paths, ", ")
+}
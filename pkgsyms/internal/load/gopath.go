@@ -0,0 +1,118 @@
+// Package load loads packages for the pkgsyms command and its generator.
+package load
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Config gets the configuration to load packages in dir with.  Patterns are
+// relative to dir.  Outside of a module, packages are loaded in GOPATH mode if
+// dir is in a GOPATH's src directory.  With gopath, like with the -gopath
+// flag, they're always loaded in GOPATH mode.  GOFLAGS is kept in GOPATH mode
+// except for the flags that only apply to modules, like -mod.
+func Config(dir string, mode packages.LoadMode, gopath bool) (*packages.Config, error) {
+	cfg := &packages.Config{Mode: mode, Dir: dir}
+	env, err := goEnv(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !gopath && env.GOMOD != "" && env.GOMOD != os.DevNull {
+		return cfg, nil
+	}
+	if err := checkGOPATH(dir, env.GOPATH); err != nil {
+		return nil, err
+	}
+	cfg.Env = append(os.Environ(),
+		"GO111MODULE=off", "GOFLAGS="+gopathFlags(env.GOFLAGS))
+	return cfg, nil
+}
+
+// moduleFlags are the flags that the go command rejects in GOPATH mode.
+var moduleFlags = map[string]bool{"mod": true, "modfile": true, "modcacherw": true}
+
+// gopathFlags removes moduleFlags from goflags.
+func gopathFlags(goflags string) string {
+	var kept []string
+	for _, f := range strings.Fields(goflags) {
+		name := f
+		if i := strings.IndexByte(f, '='); i >= 0 {
+			name = f[:i]
+		}
+		if !moduleFlags[strings.TrimLeft(name, "-")] {
+			kept = append(kept, f)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// checkGOPATH returns an error if dir isn't in the src directory of a
+// GOPATH entry, where GOPATH mode can't give its packages import paths.
+func checkGOPATH(dir, gopath string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = real
+	}
+	for _, root := range filepath.SplitList(gopath) {
+		src := filepath.Join(root, "src")
+		if real, err := filepath.EvalSymlinks(src); err == nil {
+			src = real
+		}
+		if rel, err := filepath.Rel(src, abs); err == nil && rel != "." &&
+			rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf(
+		"%s is neither in a module nor in a GOPATH src directory "+
+			"(GOPATH=%s); run \"go mod init\" or move the package into "+
+			"$GOPATH/src", dir, gopath)
+}
+
+// env holds the go env variables that Config depends on.
+type env struct {
+	GOMOD   string
+	GOPATH  string
+	GOFLAGS string
+}
+
+var (
+	envMutex sync.Mutex
+	envCache = make(map[string]env)
+)
+
+// goEnv gets the go env variables in dir.  They're cached by the absolute
+// directory because every package that's loaded needs them.
+func goEnv(dir string) (env, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return env{}, err
+	}
+	envMutex.Lock()
+	defer envMutex.Unlock()
+	if e, ok := envCache[abs]; ok {
+		return e, nil
+	}
+	cmd := exec.Command("go", "env", "-json", "GOMOD", "GOPATH", "GOFLAGS")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return env{}, fmt.Errorf("go env: %w", err)
+	}
+	var e env
+	if err := json.Unmarshal(out, &e); err != nil {
+		return env{}, fmt.Errorf("go env: %w", err)
+	}
+	envCache[abs] = e
+	return e, nil
+}
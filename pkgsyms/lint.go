@@ -0,0 +1,246 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/pkgsyms/gen"
+	"github.com/skillian/pkgsyms/pkgsyms/internal/load"
+	"golang.org/x/tools/go/packages"
+)
+
+func lintUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, `Report registered symbols that are never looked up.
+
+Usage of %s lint:
+	%s lint [flags] [packages]
+
+The packages (./... by default) are searched for registries generated by
+%s and for calls that look symbols up by name, like LookupFunc("Name") or
+Resolve(ID).  Registered symbols whose names are never looked up are
+reported because they defeat dead code elimination for nothing.  Lookups
+with names that aren't constants can't be followed, so they're counted and
+reported too.
+
+Flags:
+`, progname, progname, progname)
+		fs.PrintDefaults()
+	}
+}
+
+// lintFlags defines the flags of the lint subcommand.
+func lintFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.Usage = lintUsage(fs)
+	return fs
+}
+
+func lintMain(args []string) {
+	fs := lintFlags()
+	fs.Parse(args)
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	cfg, err := load.Config(".", gen.LoadMode, *gopath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	res := lint(pkgs)
+	for _, f := range res.unused {
+		fmt.Println(f)
+	}
+	if res.dynamic > 0 {
+		log.Printf(
+			"%d lookups with non-constant names weren't followed; "+
+				"some of the symbols may be looked up by them", res.dynamic)
+	}
+	if len(res.unused) > 0 {
+		os.Exit(1)
+	}
+}
+
+// lintResult is the result of linting packages.
+type lintResult struct {
+	// unused describe the registered symbols that are never looked up.
+	unused []string
+
+	// dynamic is the number of lookups of names that aren't constants.
+	dynamic int
+}
+
+// registered is a symbol registered by a generated file.
+type registered struct {
+	pkg, name string
+	pos       token.Position
+}
+
+// factoryPkgPath is the path of the package whose New looks up constructors.
+const factoryPkgPath = pkgsymsPkgPath + "/factory"
+
+// lookupMethods take a symbol name as their first argument.
+var lookupMethods = map[string]bool{
+	"Lookup":      true,
+	"LookupConst": true,
+	"LookupFunc":  true,
+	"LookupType":  true,
+	"LookupVar":   true,
+}
+
+// lint finds the symbols registered in pkgs and the symbols that are looked
+// up in pkgs, but not in their dependencies, and reports the registered
+// symbols that aren't looked up.  Lookups by name alone match symbols of that
+// name in any package.  Files generated with -append are searched for both
+// because they can have hand-written code outside of their markers.
+func lint(pkgs []*packages.Package) lintResult {
+	var res lintResult
+	var regs []registered
+	names := make(map[string]bool)
+	ids := make(map[string]bool)
+	for _, p := range pkgs {
+		if p.TypesInfo == nil {
+			continue
+		}
+		for _, f := range p.Syntax {
+			filename := p.Fset.Position(f.Pos()).Filename
+			if _, ok, _ := generatedFile(filename); ok {
+				regs = append(regs, registrations(p, f)...)
+				continue
+			}
+			if gen.Appended(f) {
+				regs = append(regs, registrations(p, f)...)
+			}
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				fn := callee(p.TypesInfo, call)
+				if fn == nil || fn.Pkg() == nil || len(call.Args) == 0 {
+					return true
+				}
+				path := fn.Pkg().Path()
+				if path != pkgsymsPkgPath && path != factoryPkgPath {
+					return true
+				}
+				isMethod := fn.Type().(*types.Signature).Recv() != nil
+				switch {
+				case isMethod && lookupMethods[fn.Name()]:
+					if s, ok := constString(p.TypesInfo, call.Args[0]); ok {
+						names[s] = true
+					} else {
+						res.dynamic++
+					}
+				case !isMethod && path == pkgsymsPkgPath && fn.Name() == "Resolve":
+					if s, ok := constString(p.TypesInfo, call.Args[0]); ok {
+						ids[s] = true
+					} else {
+						res.dynamic++
+					}
+				case path == factoryPkgPath && fn.Name() == "New" && len(call.Args) > 1:
+					// factory.New(p, name, ...)
+					if s, ok := constString(p.TypesInfo, call.Args[1]); ok {
+						names[s] = true
+						names["New"+s] = true
+					} else {
+						res.dynamic++
+					}
+				}
+				return true
+			})
+		}
+	}
+	for _, r := range regs {
+		if names[r.name] || ids[pkgsyms.ID(r.pkg, r.name)] {
+			continue
+		}
+		res.unused = append(res.unused, fmt.Sprintf(
+			"%v: %s is registered but never looked up",
+			r.pos, pkgsyms.ID(r.pkg, r.name)))
+	}
+	sort.Strings(res.unused)
+	return res
+}
+
+// registrations gets the symbols registered by a generated file.
+func registrations(p *packages.Package, f *ast.File) []registered {
+	var pkg string
+	var regs []registered
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		fn := callee(p.TypesInfo, call)
+		if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != pkgsymsPkgPath {
+			return true
+		}
+		s, ok := constString(p.TypesInfo, call.Args[0])
+		if !ok {
+			return true
+		}
+		switch {
+		case fn.Name() == "Of":
+			pkg = s
+		case strings.HasPrefix(fn.Name(), "Make") && fn.Name() != "MakeSymbols":
+			regs = append(regs, registered{name: s, pos: p.Fset.Position(call.Args[0].Pos())})
+		}
+		return true
+	})
+	for i := range regs {
+		regs[i].pkg = pkg
+	}
+	return regs
+}
+
+// callee gets the function or method called, if it's statically known.
+func callee(info *types.Info, call *ast.CallExpr) *types.Func {
+	fun := call.Fun
+	for {
+		p, ok := fun.(*ast.ParenExpr)
+		if !ok {
+			break
+		}
+		fun = p.X
+	}
+	if ix, ok := fun.(*ast.IndexExpr); ok {
+		fun = ix.X
+	} else if ix, ok := fun.(*ast.IndexListExpr); ok {
+		fun = ix.X
+	}
+	var id *ast.Ident
+	switch fun := fun.(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, _ := info.Uses[id].(*types.Func)
+	return fn
+}
+
+// constString gets the value of a constant string expression.
+func constString(info *types.Info, expr ast.Expr) (string, bool) {
+	tv, ok := info.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
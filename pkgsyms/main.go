@@ -1,32 +1,21 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"go/ast"
-	"go/printer"
-	"go/token"
-	"io"
 	"log"
 	"os"
-	"path"
 	"path/filepath"
-	"sort"
+	"runtime"
 	"strings"
 
-	"github.com/skillian/errors"
+	"github.com/skillian/pkgsyms/pkgsyms/gen"
+	"github.com/skillian/pkgsyms/pkgsyms/internal/load"
 	"golang.org/x/tools/go/packages"
 )
 
 const (
-	pkgNeeds = (packages.NeedName |
-		packages.NeedFiles |
-		packages.NeedCompiledGoFiles |
-		packages.NeedImports |
-		packages.NeedTypes | packages.NeedTypesSizes |
-		packages.NeedSyntax | packages.NeedTypesInfo |
-		packages.NeedDeps)
-
 	pkgsymsPkgName = "pkgsyms"
 	pkgsymsPkgPath = "github.com/skillian/" + pkgsymsPkgName
 )
@@ -34,283 +23,222 @@ const (
 var (
 	progname = filepath.Base(os.Args[0])
 
-	output  = flag.String("output", "", "output filename; default srcdir/pkgsyms.go")
-	varname = flag.String("varname", "Pkg", "variable name of the package symbols")
-	pkgname = flag.String("package", "", "package name to use in the output")
-	//pkgprefix = flag.String("prefix", "", "the package prefix")
-	srcdir string
+	output   = flag.String("output", "", "output filename or directory; default srcdir/pkgsyms.go")
+	varname  = flag.String("varname", "Pkg", "variable name of the package symbols")
+	pkgname  = flag.String("package", "", "package name to use in the output")
+	docs     = flag.Bool("docs", false, "record doc comments in the registry")
+	mfest    = flag.Bool("manifest", false, "write constants and docs into an embedded JSON manifest")
+	mfmt     = flag.String("manifest-format", "json", "encoding of the -manifest file: \"json\" or the more compact \"cbor\"")
+	fvars    = flag.Bool("funcvars", false, "make variables of function type callable as Funcs")
+	safecont = flag.Bool("safe-containers", false, "guard variables of map and channel types with a lock for their MapVar and ChanVar accessors")
+	appendf  = flag.Bool("append", false, "only regenerate the marked regions of an existing output file")
+	exclude  stringsFlag
+	rdonly   stringsFlag
+	skipdirs stringsFlag
+	implmts  = flag.String("implements", "", "comma-separated interfaces, like io.Reader, to record which types implement")
+	localimp = flag.Bool("local-implements", false, "record which of the package's own exported interfaces each type implements")
+	expdata  = flag.Bool("export-data", false, "load the package from its export data when its source isn't available; docs and directives are lost")
+	nocache  = flag.Bool("no-cache", false, "don't use or update the cache of generated output")
+	cachedir = flag.String("cache-dir", "", "directory of the cache of generated output; default is in the user cache directory")
+	progress = flag.String("progress", "auto", "progress of batch runs: \"bar\", \"json\" events on stdout, \"none\" or \"auto\"")
+	sizerep  = flag.Bool("size-report", false, "report how much the registry adds to the size of a binary that imports the package")
+	njobs    = flag.Int("jobs", runtime.GOMAXPROCS(0), "number of packages to generate concurrently")
+	schema   = flag.Bool("schema", false, "record the fields of struct types so input can be validated against them")
+	methods  = flag.Bool("methods", false, "record the exported methods of types")
+	gopath   = flag.Bool("gopath", false, "load packages in GOPATH mode even inside a module")
+	check    = flag.Bool("check", false, "report whether the output files are up to date instead of writing them")
+	failwarn = flag.Bool("fail-on-warning", false, "exit with status 4 if there are any warnings")
+	registr  = flag.String("registrar", "", "generate a function with this name that registers the symbols into a given *pkgsyms.Package instead of an init function")
+	target   = flag.String("target", "", "register the symbols into an existing pkgsyms.Symbols field, like example.com/app.Registry.Symbols, instead of a new package variable")
+	collide  = flag.String("collisions", "first-wins", "what to do with symbols registered under the same name: \"first-wins\", \"last-wins\", \"qualify\" or \"error\"")
+	maxvar   = flag.Int64("max-var-size", 64<<10, "warn about registered variables larger than this many bytes, counting constant strings and embedded files; 0 disables the warning")
+	strict   = flag.Bool("strict", false, "fail instead of skipping symbols that can't be registered")
+	rosync   = flag.Bool("readonly-sync", false, "register variables containing sync or sync/atomic values read-only")
+	qualify  = flag.String("qualify", "", "qualify registered names; \"alias\" registers them as alias.Name")
+	variant  = flag.String("variant", "", "generate from the package's \"test\" variant, which includes its _test.go files, or its \"xtest\" external test package; the default output is then pkgsyms_test.go and, for \"test\", the default -varname is TestPkg")
 )
 
-// Config configures pkgsyms
-type Config struct {
-	pkgAlias string
-}
-
-// Option modifies Config.
-type Option func(c *Config) error
-
-// Alias allows an alias name to be specified for the package.
-func Alias(name string) Option {
-	return func(c *Config) error {
-		if c.pkgAlias != "" {
-			return errors.Errorf(
-				"redefinition of package alias from %q to %q",
-				c.pkgAlias, name)
-		}
-		c.pkgAlias = name
-		return nil
-	}
-}
-
 func usage() {
 	fmt.Fprintf(os.Stderr, `Create a plugin-like object to access symbols from a package.
 
 Usage of %s:
-	%s [flags] [directory]
-
-The directory must be a Go package.
+	%s [flags] [directory | packages]
+	%s query [flags] PKG [SYM]
+	%s clean [flags] [directory | packages]
+	%s lint [flags] [packages]
+	%s migrate [flags] [directory | packages]
+	%s mock [flags] [directory]
+	%s repl [flags]
+	%s completion bash|zsh|fish
+
+The directory must be a Go package.  Several packages can be given as
+directories or patterns like ./... and are generated concurrently (see
+-jobs), each into pkgsyms.go in its own directory.  Packages below testdata
+and vendor directories that the patterns don't name, in directories matching
+-skip-dirs and in directories that git ignores are skipped.  See "%s query
+-h" for querying the registry of a running process, "%s clean -h" for
+removing generated files, "%s lint -h" for finding registered symbols
+that are never looked up, "%s migrate -h" for regenerating files written
+by older versions, "%s mock -h" for generating stand-in registries for
+tests, "%s repl -h" for exploring the registry of a running process in a
+console and "%s completion -h" for shell completion.
+
+Exit status is 0 on success, 1 for usage errors and other failures, 2 when a
+package can't be loaded, 3 when -check finds an out-of-date file and 4 when
+there are warnings with -fail-on-warning.
 
 Flags:
-`, progname, progname)
+`, progname, progname, progname, progname, progname, progname, progname, progname, progname, progname, progname, progname, progname, progname, progname, progname)
 	flag.PrintDefaults()
 }
 
 func main() {
-	var err error
 	log.SetFlags(0)
 	log.SetPrefix(pkgsymsPkgName + ": ")
 	flag.Usage = usage
-	flag.Parse()
-
-	args := flag.Args()
-	switch len(args) {
-	case 0:
-		srcdir = "."
-	case 1:
-		srcdir = args[0]
-	default:
-		log.Fatal("one or zero directories allowed, not", len(args))
+	flag.Var(&exclude, "exclude-type", "exclude symbols whose types match the regular expression; may be repeated")
+	flag.Var(&rdonly, "readonly", "register the named variable read-only; may be repeated")
+	flag.Var(&skipdirs, "skip-dirs", "skip the directories matching the glob when generating several packages; may be repeated")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "query":
+			queryMain(os.Args[2:])
+			return
+		case "clean":
+			cleanMain(os.Args[2:])
+			return
+		case "lint":
+			lintMain(os.Args[2:])
+			return
+		case "migrate":
+			migrateMain(os.Args[2:])
+			return
+		case "mock":
+			mockMain(os.Args[2:])
+			return
+		case "repl":
+			replMain(os.Args[2:])
+			return
+		case "completion":
+			completionMain(os.Args[2:])
+			return
+		case completeCommand:
+			completeMain(os.Args[2:])
+			return
+		}
 	}
-
-	outfile, err := getOutput()
-	if err != nil {
-		log.Fatal(errors.ErrorfWithCause(
-			err, "failed to get output file: %q", *output))
+	flag.CommandLine.Init(progname, flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitFailure)
 	}
-	defer outfile.Close()
 
-	g := generator{
-		pkg:   mustParsePackage(srcdir),
-		decls: make([]decl, 0, 512),
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
 	}
-	pkgbase := path.Base(g.pkg.Name)
-	if *pkgname == "" {
-		*pkgname = pkgbase
+	dirs, err := packageDirs(patterns)
+	if err != nil {
+		log.Print(err)
+		os.Exit(exitLoad)
 	}
-	g.generate(*pkgname == pkgbase)
-
-	sort.Slice(g.decls, func(i, j int) bool {
-		a, b := g.decls[i], g.decls[j]
-		c := a.kind - b.kind
-		if c != 0 {
-			return c < 0
-		}
-		return strings.Compare(a.Name, b.Name) < 0
-	})
-
-	declstrs := make([]string, len(g.decls))
-	for i, d := range g.decls {
-		declstrs[i] = strings.Join(
-			[]string{"\t\t", d.String(), ",\n"}, "")
+	if len(dirs) > 1 && *output != "" {
+		log.Fatal("-output can't be used when generating more than one package")
 	}
-
-	imports := fmt.Sprintf("%q", pkgsymsPkgPath)
-	if *pkgname != pkgbase {
-		imports += fmt.Sprintf("\n\t%q", g.pkg.PkgPath)
+	jobs := make([]job, len(dirs))
+	for i, dir := range dirs {
+		jobs[i] = job{srcdir: dir, output: outputPath(*output, dir)}
 	}
-
-	fmt.Fprintf(
-		outfile, `// Code generated by "%s"; DO NOT EDIT.
-
-package %s
-
-import (
-	%s
-)
-
-var %s = %s.Of(%q)
-
-func init() {
-	%s.Add(
-%s	)
-}
-`,
-		strings.Join(append([]string{progname}, os.Args[1:]...), " "),
-		*pkgname,
-		imports,
-		*varname, pkgsymsPkgName, g.pkg.PkgPath,
-		*varname,
-		strings.Join(declstrs, ""),
-	)
-}
-
-func mustParsePackage(srcdir string) *packages.Package {
-	pkg, err := parsePackage(srcdir)
+	prog, err := newProgress(*progress, len(jobs))
 	if err != nil {
 		log.Fatal(err)
 	}
-	return pkg
+	os.Exit(runJobs(jobs, *njobs, prog))
 }
 
+// parsePackage loads the package in srcdir with everything the generator
+// needs.
 func parsePackage(srcdir string) (*packages.Package, error) {
-	cfg := packages.Config{Mode: pkgNeeds}
-	pkgs, err := packages.Load(&cfg, srcdir)
-	if err != nil {
-		return nil, errors.ErrorfWithCause(
-			err, "failed to parse %q", srcdir)
-	}
-	if len(pkgs) != 1 {
-		return nil, errors.Errorf(
-			"expected exactly one package when parsing %q, not %d",
-			srcdir, len(pkgs))
-	}
-	return pkgs[0], nil
-}
-
-type generator struct {
-	pkg    *packages.Package
-	decls  []decl
-	prefix string
+	return loadPackage(srcdir, gen.LoadMode)
 }
 
-func (g *generator) generate(omitPrefix bool) {
-	if !omitPrefix {
-		g.prefix = g.pkg.Name + "."
+// loadPackage loads a single package with the given mode.  With -variant, the
+// package's test variants are loaded and selectVariant picks one.  The errors
+// that loadErrors considers fatal are all returned with their positions.
+func loadPackage(srcdir string, mode packages.LoadMode) (*packages.Package, error) {
+	cfg, err := load.Config(srcdir, mode, *gopath)
+	if err != nil {
+		return nil, err
 	}
-	for _, f := range g.pkg.Syntax {
-		ast.Inspect(f, g.inspect)
+	cfg.Tests = *variant != variantPrimary
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, fmt.Errorf(
+			"failed to parse %q: %w", srcdir, err)
 	}
-}
-
-func (g *generator) inspect(n ast.Node) bool {
-	var kind declKind
-	var sb strings.Builder
-	switch n := n.(type) {
-	case *ast.GenDecl:
-		switch n.Tok {
-		case token.TYPE:
-			for _, s := range n.Specs {
-				name := s.(*ast.TypeSpec).Name
-				if !name.IsExported() {
-					continue
-				}
-				g.decls = append(g.decls, decl{g: g, kind: typeDecl, Name: name.Name})
-			}
-			return false
-		case token.CONST:
-			kind = constDecl
-			fallthrough
-		case token.VAR:
-			if kind == badDecl {
-				kind = varDecl
-			}
-			for _, s := range n.Specs {
-				vs := s.(*ast.ValueSpec)
-				for i, id := range vs.Names {
-					if !id.IsExported() {
-						continue
-					}
-					tp := vs.Type
-					if tp == nil {
-						tp = vs.Values[i]
-					}
-					sb.Reset()
-					if err := printer.Fprint(&sb, g.pkg.Fset, tp); err != nil {
-						log.Fatal(errors.ErrorfWithCause(
-							err, "failed to get type of %#v", vs))
-					}
-					g.decls = append(g.decls, decl{
-						g:    g,
-						kind: kind,
-						Name: id.Name,
-						Type: sb.String(),
-					})
-				}
-			}
-			return false
+	if cfg.Tests {
+		if pkgs, err = selectVariant(pkgs, *variant); err != nil {
+			return nil, err
 		}
-	case *ast.FuncDecl:
-		if n.Recv != nil {
-			return true
-		}
-		if !n.Name.IsExported() {
-			return true
+	}
+	switch {
+	case len(pkgs) == 0 && cfg.Tests:
+		return nil, fmt.Errorf(
+			"%q has no %s variant; check that it has the _test.go "+
+				"files for it", srcdir, *variant)
+	case len(pkgs) == 0:
+		return nil, fmt.Errorf(
+			"no package found in %q; check that it's a package "+
+				"directory with Go files for this GOOS and GOARCH", srcdir)
+	case len(pkgs) > 1:
+		return nil, fmt.Errorf(
+			"%q has %d packages (%s); pass the directory of a single "+
+				"package or a pattern that matches each of them",
+			srcdir, len(pkgs), load.PkgPaths(pkgs))
+	}
+	if fatal, _ := load.Errors(pkgs[0], mode); len(fatal) > 0 {
+		what := "source"
+		if mode == load.ExportDataNeeds {
+			what = "export data"
 		}
-		g.decls = append(g.decls, decl{g: g, kind: funcDecl, Name: n.Name.Name})
-		return false
+		return nil, fmt.Errorf(
+			"failed to load %q from %s:\n\t%s",
+			srcdir, what, load.Diagnostics(fatal, "error"))
 	}
-	return true
-}
-
-type decl struct {
-	g *generator
-
-	kind declKind
-
-	// Name of the declared object
-	Name string
-
-	// optional type of the object.
-	Type string
-}
-
-type declKind int
-
-const (
-	badDecl declKind = iota
-	constDecl
-	typeDecl
-	funcDecl
-	varDecl
-)
-
-var declStrings = []string{
-	"<bad decl>",
-	"Const",
-	"Type",
-	"Func",
-	"Var",
+	return pkgs[0], nil
 }
 
-func (k declKind) String() string { return declStrings[int(k)] }
-
-func (d decl) String() string {
-	switch d.kind {
-	case typeDecl:
-		return fmt.Sprintf(
-			"%s.MakeType(%q, (*%s)(nil))",
-			pkgsymsPkgName, d.Name, d.g.prefix+d.Name)
-	default:
-		return fmt.Sprintf(
-			"%s.Make%s(%q, %s)",
-			pkgsymsPkgName, d.kind, d.Name, d.g.prefix+d.Name)
+// outputPath gets the filename to write to from the -output flag.  Without
+// -output, it's pkgsyms.go in the source directory.  If -output ends with a
+// path separator or names an existing directory, it's pkgsyms.go in that
+// directory.  With -variant, it's pkgsyms_test.go instead.
+func outputPath(output, srcdir string) string {
+	filename := pkgsymsPkgName + ".go"
+	if *variant != variantPrimary {
+		filename = pkgsymsPkgName + "_test.go"
 	}
-}
-
-func getOutput() (io.WriteCloser, error) {
 	switch {
-	case *output == "-":
-		return nopCloser{os.Stdout}, nil
-	case len(*output) == 0:
-		*output = filepath.Join(srcdir, pkgsymsPkgName+".go")
-		fallthrough
-	default:
-		return os.Create(*output)
+	case output == "":
+		return filepath.Join(srcdir, filename)
+	case output == "-":
+		return output
+	case strings.HasSuffix(output, "/") || strings.HasSuffix(output, string(filepath.Separator)):
+		return filepath.Join(output, filename)
+	}
+	if fi, err := os.Stat(output); err == nil && fi.IsDir() {
+		return filepath.Join(output, filename)
 	}
+	return output
 }
 
-type nopCloser struct {
-	io.Writer
-}
+// stringsFlag is a flag that may be given more than once.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
 
-func (nopCloser) Close() error { return nil }
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
@@ -0,0 +1,998 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/httpsyms"
+	"github.com/skillian/pkgsyms/pkgsyms/gen"
+	"github.com/skillian/pkgsyms/replsyms"
+	"golang.org/x/tools/go/packages"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name    string
+		dir     string
+		options []gen.Option
+	}{
+		{name: "basic", dir: "basic"},
+		{name: "docs", dir: "basic", options: []gen.Option{gen.Docs(true)}},
+		{name: "alias", dir: "basic", options: []gen.Option{gen.Alias("basicsyms")}},
+		{name: "varname", dir: "basic", options: []gen.Option{gen.VarName("Symbols")}},
+		{name: "funcvars", dir: "basic", options: []gen.Option{gen.FuncVars(true)}},
+		{name: "generic", dir: "generic"},
+		{name: "append", dir: "basic", options: []gen.Option{gen.Append(nil)}},
+		{name: "exclude", dir: "exclude", options: []gen.Option{
+			gen.ExcludeType(`\*testing\.T\b`), gen.ExcludeType(`unsafe\.Pointer`)}},
+		{name: "containers", dir: "containers", options: []gen.Option{gen.SafeContainers(true)}},
+		{name: "readonly", dir: "syncvars", options: []gen.Option{gen.ReadOnly("Name"), gen.ReadOnlySync(true)}},
+		{name: "implements", dir: "implements", options: []gen.Option{gen.Implements("io.Reader", "io.Writer")}},
+		{name: "methods", dir: "implements", options: []gen.Option{gen.Methods(true)}},
+		{name: "local_implements", dir: "implements", options: []gen.Option{gen.Implements("io.Writer"), gen.LocalImplements(true)}},
+		{name: "schema", dir: "schema", options: []gen.Option{gen.Schema(true)}},
+		{name: "registrar", dir: "basic", options: []gen.Option{gen.Registrar("RegisterSymbols"), gen.Docs(true)}},
+		{name: "target", dir: "target", options: []gen.Option{
+			gen.Target("github.com/skillian/pkgsyms/pkgsyms/testdata/target.Registry.Symbols"), gen.Docs(true)}},
+		{name: "extra", dir: "extra"},
+		{name: "collisions", dir: "renames", options: []gen.Option{gen.Collisions(pkgsyms.MergeQualify)}},
+		{name: "qualify", dir: "basic", options: []gen.Option{gen.Alias("other"), gen.Qualify("alias")}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pkg := loadTestdata(t, tc.dir)
+			var buf bytes.Buffer
+			options := append([]gen.Option{gen.Command("pkgsyms")}, tc.options...)
+			if err := gen.Generate(&buf, pkg, options...); err != nil {
+				t.Fatal(err)
+			}
+			compareGolden(t, filepath.Join("testdata", tc.name+".golden"), buf.Bytes())
+		})
+	}
+}
+
+func TestGenerateTargetErrors(t *testing.T) {
+	pkg := loadTestdata(t, "target")
+	for _, options := range [][]gen.Option{
+		{gen.Target("github.com/skillian/pkgsyms/pkgsyms/testdata/target.Registry.Name")},
+		{gen.Target("github.com/skillian/pkgsyms/pkgsyms/testdata/target.Missing")},
+		{gen.Target("github.com/skillian/pkgsyms/pkgsyms/testdata/target.Registry.Symbols"), gen.Registrar("Register")},
+		{gen.Target("target")},
+	} {
+		if err := gen.Generate(io.Discard, pkg, options...); err == nil {
+			t.Errorf("expected an error generating with %d options", len(options))
+		}
+	}
+}
+
+func TestGenerateManifest(t *testing.T) {
+	pkg := loadTestdata(t, "basic")
+	filename := filepath.Join(t.TempDir(), "pkgsyms.json")
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, pkg, gen.Command("pkgsyms"), gen.Docs(true), gen.Manifest(filename)); err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, filepath.Join("testdata", "manifest.golden"), buf.Bytes())
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, filepath.Join("testdata", "manifest.json.golden"), data)
+}
+
+func TestGenerateManifestCBOR(t *testing.T) {
+	pkg := loadTestdata(t, "basic")
+	var manifests [2]pkgsyms.Manifest
+	for i, format := range []string{"json", "cbor"} {
+		filename := filepath.Join(t.TempDir(), "pkgsyms."+format)
+		if err := gen.Generate(io.Discard, pkg, gen.Docs(true), gen.Manifest(filename), gen.ManifestFormat(format)); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format == "json" {
+			err = json.Unmarshal(data, &manifests[i])
+		} else {
+			err = manifests[i].UnmarshalCBOR(data)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(manifests[0], manifests[1]) {
+		t.Fatalf("expected the CBOR manifest %#v to match the JSON manifest %#v", manifests[1], manifests[0])
+	}
+	if err := gen.Generate(io.Discard, pkg, gen.ManifestFormat("xml")); err == nil {
+		t.Fatal("expected an unknown manifest format to be an error")
+	}
+}
+
+func TestGenerateWarnings(t *testing.T) {
+	tests := []struct {
+		dir      string
+		warnings []string
+	}{
+		{dir: "collide", warnings: []string{
+			"Func Len shadows the Go builtin \"len\"",
+			"Var Pkg collides with the generated Pkg; use -varname",
+		}},
+		{dir: "syncvars", warnings: []string{
+			"Var Mu is a sync.Mutex",
+			"Var State contains a sync/atomic.Int64",
+		}},
+		{dir: "renames", warnings: []string{
+			`"Dial" is registered by Func NewClient at`,
+		}},
+		{dir: "bigvar", warnings: []string{
+			"Var Table is 1048576 bytes",
+		}},
+		{dir: "strict", warnings: []string{
+			"skipping Const Big: 1267650600228229401496703205376 overflows int",
+			"skipping Const Inf: 1e+400 overflows float64",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.dir, func(t *testing.T) {
+			pkg := loadTestdata(t, tc.dir)
+			var warnings []string
+			warnf := func(format string, args ...interface{}) {
+				warnings = append(warnings, fmt.Sprintf(format, args...))
+			}
+			var buf bytes.Buffer
+			if err := gen.Generate(&buf, pkg, gen.Command("pkgsyms"), gen.Alias(tc.dir+"syms"), gen.MaxVarSize(64<<10), gen.Warnf(warnf)); err != nil {
+				t.Fatal(err)
+			}
+			compareGolden(t, filepath.Join("testdata", tc.dir+".golden"), buf.Bytes())
+			for i, want := range tc.warnings {
+				if i >= len(warnings) || !strings.Contains(warnings[i], want) {
+					t.Fatalf("expected warning %d to contain %q, got %q", i, want, warnings)
+				}
+			}
+			if len(warnings) != len(tc.warnings) {
+				t.Fatalf("expected %d warnings but got %q", len(tc.warnings), warnings)
+			}
+		})
+	}
+}
+
+func TestGenerateCollisionError(t *testing.T) {
+	pkg := loadTestdata(t, "renames")
+	err := gen.Generate(io.Discard, pkg, gen.Collisions(pkgsyms.MergeError))
+	if err == nil || !strings.Contains(err.Error(), `"Dial": Func NewClient at`) {
+		t.Fatalf("expected an error about Dial, got %v", err)
+	}
+}
+
+func TestGenerateStrict(t *testing.T) {
+	pkg := loadTestdata(t, "strict")
+	err := gen.Generate(io.Discard, pkg, gen.Strict(true), gen.Warnf(t.Errorf))
+	if err == nil || !strings.Contains(err.Error(), "2 symbols can't be registered") {
+		t.Fatalf("expected an error about 2 symbols, got %v", err)
+	}
+	err = gen.Generate(io.Discard, loadTestdata(t, "renames"), gen.Strict(true), gen.Warnf(t.Errorf))
+	if err == nil || !strings.Contains(err.Error(), "skipping Func NewServer") {
+		t.Fatalf("expected an error about the dropped collision, got %v", err)
+	}
+}
+
+func TestGenerateTagged(t *testing.T) {
+	pkg := loadTestdata(t, "tagged")
+	var tagged bytes.Buffer
+	write := func(filename string, src []byte) error {
+		fmt.Fprintf(&tagged, "// %s\n\n", filepath.Base(filename))
+		tagged.Write(src)
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, pkg, gen.Command("pkgsyms"), gen.TaggedFiles("pkgsyms.go", write)); err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, filepath.Join("testdata", "tagged.golden"), buf.Bytes())
+	compareGolden(t, filepath.Join("testdata", "tagged_files.golden"), tagged.Bytes())
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) { return 0, errors.New("write failed") }
+
+func TestGenerateAtomic(t *testing.T) {
+	pkg := loadTestdata(t, "basic")
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "pkgsyms.go")
+	err := writeFile(filename, func(w io.Writer) error {
+		if _, err := w.Write([]byte("partial")); err != nil {
+			return err
+		}
+		return gen.Generate(errWriter{}, pkg, gen.Manifest(filepath.Join(dir, "pkgsyms.json")))
+	})
+	if err == nil {
+		t.Fatal("expected error from failing writer")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected no files after failure, got %v", entries)
+	}
+	if err := writeFile(filename, func(w io.Writer) error {
+		return gen.Generate(w, pkg, gen.Manifest(filepath.Join(dir, "pkgsyms.json")))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Fatalf("expected pkgsyms.go and pkgsyms.json, got %v", entries)
+	}
+}
+
+func TestOutputPath(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct{ output, want string }{
+		{"", filepath.Join("src", "pkgsyms.go")},
+		{"-", "-"},
+		{"gen.go", "gen.go"},
+		{"gen/", filepath.Join("gen", "pkgsyms.go")},
+		{dir, filepath.Join(dir, "pkgsyms.go")},
+	} {
+		if got := outputPath(tc.output, "src"); got != tc.want {
+			t.Errorf("outputPath(%q): expected %q but got %q", tc.output, tc.want, got)
+		}
+	}
+	filename := filepath.Join(dir, "a", "b", "pkgsyms.go")
+	if err := writeFile(filename, func(w io.Writer) error {
+		_, err := w.Write([]byte("package b\n"))
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filename); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateAppend(t *testing.T) {
+	existing, err := os.ReadFile(filepath.Join("testdata", "append.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const hand = "\t\tpkgsyms.MakeConst(\"Manual\", 1),\n"
+	existing = bytes.Replace(existing,
+		[]byte("\t\t// pkgsyms:end symbols\n"),
+		[]byte("\t\tpkgsyms.MakeConst(\"Stale\", 0),\n\t\t// pkgsyms:end symbols\n"+hand), 1)
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, loadTestdata(t, "basic"), gen.Command("pkgsyms"), gen.Append(existing)); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if !strings.Contains(got, hand) {
+		t.Fatalf("expected hand-registered symbol to be kept:\n%s", got)
+	}
+	if strings.Contains(got, "Stale") {
+		t.Fatalf("expected generated region to be regenerated:\n%s", got)
+	}
+	if err := gen.Generate(io.Discard, loadTestdata(t, "basic"), gen.Append([]byte("package basic\n"))); err == nil {
+		t.Fatal("expected error appending to a file without markers")
+	}
+
+	// The import block is outside of the markers.
+	existing = bytes.Replace(existing, []byte("\t\"github.com/skillian/pkgsyms\"\n"), []byte("\t\"os\"\n"), 1)
+	buf.Reset()
+	if err := gen.Generate(&buf, loadTestdata(t, "basic"), gen.Command("pkgsyms"), gen.Append(existing)); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.Contains(got, "\"github.com/skillian/pkgsyms\"") || strings.Contains(got, "\"os\"") {
+		t.Fatalf("expected the imports to be updated:\n%s", got)
+	}
+}
+
+// TestGenerateAppendAlias appends to a file that imports the package under
+// another name, which qualifies the registered names.
+func TestGenerateAppendAlias(t *testing.T) {
+	const path = "github.com/skillian/pkgsyms/pkgsyms/testdata/basic"
+	options := []gen.Option{gen.Command("pkgsyms"), gen.Alias("other"), gen.Qualify("alias")}
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, loadTestdata(t, "basic"), append(options, gen.Append(nil))...); err != nil {
+		t.Fatal(err)
+	}
+	existing := bytes.Replace(buf.Bytes(),
+		[]byte(fmt.Sprintf("\t%q\n", path)), []byte(fmt.Sprintf("\tb %q\n", path)), 1)
+	buf.Reset()
+	if err := gen.Generate(&buf, loadTestdata(t, "basic"), append(options, gen.Append(existing))...); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if strings.Contains(got, fmt.Sprintf("\t%q\n", path)) || !strings.Contains(got, fmt.Sprintf("\tb %q\n", path)) {
+		t.Fatalf("expected the package to be imported once as b:\n%s", got)
+	}
+	if strings.Contains(got, "basic.") || !strings.Contains(got, "\"b.") {
+		t.Fatalf("expected the names and references to use b:\n%s", got)
+	}
+}
+
+func TestGenerateAppendAgain(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("testdata", "basic", "basic.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"go.mod":   []byte("module example.com/basic\n"),
+		"basic.go": src,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var outputs []string
+	for i := 0; i < 2; i++ {
+		pkg, err := parsePackage(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		existing, _ := os.ReadFile(filepath.Join(dir, "pkgsyms.go"))
+		var buf bytes.Buffer
+		if err := gen.Generate(&buf, pkg, gen.Command("pkgsyms -append"), gen.Append(existing), gen.Warnf(t.Logf)); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "pkgsyms.go"), buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, buf.String())
+	}
+	if outputs[0] != outputs[1] {
+		t.Fatalf("expected appending again to change nothing; first:\n%s\nsecond:\n%s", outputs[0], outputs[1])
+	}
+}
+
+// TestGenerateExportData generates from type information alone, like when a
+// package is loaded from export data.
+func TestGenerateExportData(t *testing.T) {
+	pkg := loadTestdata(t, "basic")
+	pkg.Syntax, pkg.TypesInfo = nil, nil
+	var buf bytes.Buffer
+	if err := gen.Generate(&buf, pkg, gen.Command("pkgsyms"), gen.Docs(true)); err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, filepath.Join("testdata", "exportdata.golden"), buf.Bytes())
+}
+
+func TestCache(t *testing.T) {
+	if id := generatorID(); len(id) != 64 || id != generatorID() {
+		t.Fatalf("expected a stable hash identifying the generator, got %q", id)
+	}
+	c, err := openCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := cacheKey("./testdata/basic", []byte("-docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := cacheKey("./testdata/basic", []byte("-docs")); again != key {
+		t.Fatalf("expected the same key for the same inputs")
+	}
+	for _, other := range []struct {
+		dir  string
+		part string
+	}{{"./testdata/basic", "-methods"}, {"./testdata/generic", "-docs"}} {
+		if k, _ := cacheKey(other.dir, []byte(other.part)); k == key {
+			t.Fatalf("expected %s %s to have a different key", other.dir, other.part)
+		}
+	}
+	if _, ok := c.get(key); ok {
+		t.Fatal("expected empty cache")
+	}
+	want := cacheEntry{source: []byte("package basic\n"), warnings: []byte("warning\n")}
+	if err := c.put(key, want); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := c.get(key)
+	if !ok || !bytes.Equal(got.source, want.source) || !bytes.Equal(got.warnings, want.warnings) || got.manifest != nil {
+		t.Fatalf("expected %+v but got %+v", want, got)
+	}
+}
+
+func TestRunJobs(t *testing.T) {
+	defer func(old bool) { *nocache = old }(*nocache)
+	*nocache = true
+	dirs, err := packageDirs([]string{"./testdata/basic", "./testdata/generic", "./testdata/implements"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) < 2 {
+		t.Fatalf("expected several packages but got %v", dirs)
+	}
+	out := t.TempDir()
+	jobs := make([]job, len(dirs))
+	for i, dir := range dirs {
+		jobs[i] = job{srcdir: dir, output: filepath.Join(out, filepath.Base(dir), "pkgsyms.go")}
+	}
+	var events bytes.Buffer
+	prog := &jsonProgress{enc: json.NewEncoder(&events)}
+	if code := runJobs(jobs, 4, prog); code != exitOK {
+		t.Fatal("expected all jobs to succeed")
+	}
+	dec := json.NewDecoder(&events)
+	var starts, finishes int
+	for {
+		var ev progressEvent
+		if err := dec.Decode(&ev); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		switch ev.Event {
+		case "start":
+			starts++
+		case "finish":
+			finishes++
+			if ev.Done != finishes || ev.Total != len(jobs) {
+				t.Errorf("unexpected progress %d/%d after %d jobs", ev.Done, ev.Total, finishes)
+			}
+		}
+	}
+	if starts != len(jobs) || finishes != len(jobs) {
+		t.Fatalf("expected %d start and finish events, got %d and %d", len(jobs), starts, finishes)
+	}
+	for _, j := range jobs {
+		if _, err := os.Stat(j.output); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	dir := t.TempDir()
+	pkg := loadTestdata(t, "basic")
+	manifest := filepath.Join(dir, "pkgsyms.json")
+	if err := writeFile(filepath.Join(dir, "pkgsyms.go"), func(w io.Writer) error {
+		return gen.Generate(w, pkg, gen.Command("pkgsyms -manifest"), gen.Manifest(manifest))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFile(filepath.Join(dir, "appended.go"), func(w io.Writer) error {
+		return gen.Generate(w, pkg, gen.Command("pkgsyms -append"), gen.Append(nil))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hand.go"), []byte("package basic\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "pkgsyms.go"), manifest}
+	for _, dryRun := range []bool{true, false} {
+		removed, err := cleanDir(dir, dryRun)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(removed, ",") != strings.Join(want, ",") {
+			t.Fatalf("expected %v to be removed but got %v", want, removed)
+		}
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("expected appended.go and hand.go to be kept, got %v", entries)
+	}
+}
+
+func TestLint(t *testing.T) {
+	res := lint([]*packages.Package{loadTestdata(t, "lint")})
+	if len(res.unused) != 1 || !strings.HasSuffix(res.unused[0],
+		`"github.com/skillian/pkgsyms/pkgsyms/testdata/lint".Unused is registered but never looked up`) {
+		t.Fatalf("expected only Unused to be reported, got %q", res.unused)
+	}
+	if res.dynamic != 1 {
+		t.Fatalf("expected 1 dynamic lookup but got %d", res.dynamic)
+	}
+	res = lint([]*packages.Package{loadTestdata(t, "lintappend")})
+	if len(res.unused) != 1 || !strings.HasSuffix(res.unused[0],
+		`"github.com/skillian/pkgsyms/pkgsyms/testdata/lintappend".Unused is registered but never looked up`) {
+		t.Fatalf("expected only Unused to be reported from the appended registry, got %q", res.unused)
+	}
+}
+
+func TestSizeReport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the package twice")
+	}
+	pkg := loadTestdata(t, "lint")
+	report, err := sizeReport(
+		"./testdata/lint", pkg.PkgPath, "./testdata/lint/pkgsyms.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(report, "without") || !strings.Contains(report, "+") {
+		t.Fatalf("unexpected report: %q", report)
+	}
+	if _, err := sizeReport(
+		"./testdata/lint", pkg.PkgPath, "./testdata/pkgsyms.go",
+	); err == nil {
+		t.Fatal("expected an error for an output outside the package")
+	}
+}
+
+func TestLoadGOPATH(t *testing.T) {
+	gopath := t.TempDir()
+	t.Setenv("GOPATH", gopath)
+	dir := filepath.Join(gopath, "src", "example.com", "legacy")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := "package legacy\n\nconst Answer = 42\n"
+	if err := os.WriteFile(filepath.Join(dir, "legacy.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := parsePackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pkg.PkgPath != "example.com/legacy" {
+		t.Fatalf("expected example.com/legacy but got %q", pkg.PkgPath)
+	}
+	if _, err := parsePackage(t.TempDir()); err == nil ||
+		!strings.Contains(err.Error(), "neither in a module nor in a GOPATH") {
+		t.Fatalf("expected an error outside of GOPATH, got %v", err)
+	}
+}
+
+func TestLoadDiagnostics(t *testing.T) {
+	root := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":         "module example.com/m\n",
+		"parse/parse.go": "package parse\n\nfunc Broken( {\n",
+		"types/types.go": "package types\n\nvar X int = \"x\"\n\nconst Y = 1\n",
+		"empty/README":   "no Go files\n",
+	} {
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := parsePackage(filepath.Join(root, "parse"))
+	if err == nil || !strings.Contains(err.Error(), "parse.go:3:") ||
+		!strings.Contains(err.Error(), ": error: ") {
+		t.Fatalf("expected the parse error's position, got %v", err)
+	}
+	if _, err := parsePackage(filepath.Join(root, "empty")); err == nil ||
+		!strings.Contains(err.Error(), "no Go files") {
+		t.Fatalf("expected an error about missing Go files, got %v", err)
+	}
+	pkg, err := parsePackage(filepath.Join(root, "types"))
+	if err != nil {
+		t.Fatalf("expected type errors not to stop generation, got %v", err)
+	}
+	var warnings []string
+	warnf := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	if err := gen.Generate(io.Discard, pkg, gen.Warnf(warnf)); err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "types.go:3:") ||
+		!strings.Contains(warnings[0], ": warning: ") {
+		t.Fatalf("expected a warning about the type error, got %q", warnings)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if _, err := packageDirs([]string{"./empty/..."}); err == nil ||
+		!strings.Contains(err.Error(), "matched no packages") {
+		t.Fatalf("expected an error about no matched packages, got %v", err)
+	}
+}
+
+func TestLoadVariant(t *testing.T) {
+	dir := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":     "module example.com/v\n",
+		"v.go":       "package v\n\nconst A = 1\n",
+		"v_test.go":  "package v\n\nconst B = 2\n",
+		"x_test.go":  "package v_test\n\nconst C = 3\n",
+		"sub/sub.go": "package sub\n",
+	} {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(v string) { *variant = v }(*variant)
+	for _, tc := range []struct {
+		variant, pkgPath string
+		names            []string
+	}{
+		{"", "example.com/v", []string{"A"}},
+		{"test", "example.com/v", []string{"A", "B"}},
+		{"xtest", "example.com/v_test", []string{"C"}},
+	} {
+		*variant = tc.variant
+		pkg, err := parsePackage(dir)
+		if err != nil {
+			t.Fatalf("-variant=%q: %v", tc.variant, err)
+		}
+		names := pkg.Types.Scope().Names()
+		if pkg.PkgPath != tc.pkgPath || strings.Join(names, ",") != strings.Join(tc.names, ",") {
+			t.Errorf("-variant=%q: expected %s %q but got %s %q",
+				tc.variant, tc.pkgPath, tc.names, pkg.PkgPath, names)
+		}
+	}
+	defer func(nc bool) { *nocache = nc }(*nocache)
+	*nocache = true
+	for _, v := range []string{"", "test"} {
+		*variant = v
+		jobs := []job{{srcdir: dir, output: outputPath("", dir)}}
+		if code := runJobs(jobs, 1, noProgress{}); code != exitOK {
+			t.Fatalf("-variant=%q: expected exit code %d but got %d", v, exitOK, code)
+		}
+	}
+	src, err := os.ReadFile(filepath.Join(dir, "pkgsyms_test.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), "var TestPkg = ") || strings.Contains(string(src), "var Pkg = ") {
+		t.Fatalf("expected the test variant to declare TestPkg, not Pkg:\n%s", src)
+	}
+	*variant = "xtest"
+	if _, err := parsePackage(filepath.Join(dir, "sub")); err == nil ||
+		!strings.Contains(err.Error(), "no xtest variant") {
+		t.Fatalf("expected an error about the missing variant, got %v", err)
+	}
+	*variant = "bench"
+	if _, err := parsePackage(dir); err == nil ||
+		!strings.Contains(err.Error(), "unknown -variant") {
+		t.Fatalf("expected an error about the unknown variant, got %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	flags := recordedFlags(strings.Fields(
+		"-output pkgsyms.go -docs -varname Registry -removed -implements io.Reader ./..."))
+	if want := "-docs -varname Registry -implements io.Reader"; strings.Join(flags, " ") != want {
+		t.Fatalf("expected %q but got %q", want, flags)
+	}
+
+	dir := t.TempDir()
+	current := fmt.Sprintf("// Code generated by \"pkgsyms\"; DO NOT EDIT.\n"+
+		"// pkgsyms version v1.0.0, API version %d.\n\npackage m\n", pkgsyms.APIVersion)
+	for name, src := range map[string]string{
+		"old.go":            "// Code generated by \"pkgsyms.exe -docs ./...\"; DO NOT EDIT.\n\npackage m\n",
+		"new.go":            current,
+		"new_linux_tags.go": "// Code generated by \"pkgsyms\"; DO NOT EDIT.\n\npackage m\n",
+		"handwritten.go":    "package m\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ms, err := findMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || filepath.Base(ms[0].filename) != "old.go" || ms[0].api != 0 ||
+		strings.Join(ms[0].flags, " ") != "-docs" {
+		t.Fatalf("expected only old.go with -docs to be migrated, got %+v", ms)
+	}
+}
+
+func TestMock(t *testing.T) {
+	src, err := gen.Mock(loadTestdata(t, "basic"), "", "pkgsyms mock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareGolden(t, filepath.Join("testdata", "mock.golden"), src)
+	_, err = gen.Mock(loadTestdata(t, "strict"), "", "pkgsyms mock", gen.Strict(true))
+	if err == nil || !strings.Contains(err.Error(), "2 symbols") {
+		t.Fatalf("expected an error about 2 symbols without stand-ins, got %v", err)
+	}
+}
+
+func TestPackageDirsSymlink(t *testing.T) {
+	real, err := filepath.Abs(filepath.Join("testdata", "basic"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(t.TempDir(), "basic")
+	if err := os.Symlink(real, link); err != nil {
+		t.Skip(err)
+	}
+	dirs, err := packageDirs([]string{link})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, _ := filepath.EvalSymlinks(real); len(dirs) != 1 || dirs[0] != want {
+		t.Fatalf("expected %q but got %q", want, dirs)
+	}
+	pkg, err := parsePackage(dirs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "github.com/skillian/pkgsyms/pkgsyms/testdata/basic"; pkg.PkgPath != want {
+		t.Fatalf("expected %q but got %q", want, pkg.PkgPath)
+	}
+	if got := outputPath("", dirs[0]); filepath.Dir(got) != dirs[0] {
+		t.Fatalf("expected the output in %q but got %q", dirs[0], got)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	defer func(nc, c, fw bool) { *nocache, *check, *failwarn = nc, c, fw }(*nocache, *check, *failwarn)
+	*nocache = true
+	out := filepath.Join(t.TempDir(), "pkgsyms.go")
+	run := func(dir string) int {
+		jobs := []job{{srcdir: filepath.Join("testdata", dir), output: out}}
+		return runJobs(jobs, 1, noProgress{})
+	}
+	*check = true
+	if code := run("basic"); code != exitDrift {
+		t.Fatalf("expected exit code %d for a missing file but got %d", exitDrift, code)
+	}
+	*check = false
+	if code := run("basic"); code != exitOK {
+		t.Fatalf("expected exit code %d but got %d", exitOK, code)
+	}
+	*check = true
+	if code := run("basic"); code != exitOK {
+		t.Fatalf("expected exit code %d for an up-to-date file but got %d", exitOK, code)
+	}
+	*check = false
+	*failwarn = true
+	if code := run("strict"); code != exitWarnings {
+		t.Fatalf("expected exit code %d but got %d", exitWarnings, code)
+	}
+	if code := run("missing"); code != exitLoad {
+		t.Fatalf("expected exit code %d but got %d", exitLoad, code)
+	}
+}
+
+func TestCheckCommand(t *testing.T) {
+	defer func(nc, c bool, args []string) { *nocache, *check, os.Args = nc, c, args }(*nocache, *check, os.Args)
+	*nocache = true
+	dir := filepath.Join("testdata", "basic")
+	out := filepath.Join(t.TempDir(), "pkgsyms.go")
+	jobs := []job{{srcdir: dir, output: out}}
+	os.Args = []string{progname, "-docs", "-jobs", "2", "-progress=none", dir}
+	if code := runJobs(jobs, 1, noProgress{}); code != exitOK {
+		t.Fatalf("expected exit code %d but got %d", exitOK, code)
+	}
+	*check = true
+	os.Args = []string{progname, "-check", "-docs", "-no-cache", "-cache-dir", t.TempDir(), dir}
+	if code := runJobs(jobs, 1, noProgress{}); code != exitOK {
+		t.Fatalf("expected exit code %d checking with other run flags but got %d", exitOK, code)
+	}
+	if got, want := recordedCommand(os.Args[1:]), progname+" -docs "+dir; got != want {
+		t.Fatalf("expected the recorded command %q but got %q", want, got)
+	}
+}
+
+func TestStaleTagged(t *testing.T) {
+	defer func(nc, c bool) { *nocache, *check = nc, c }(*nocache, *check)
+	*nocache = true
+	tmp := t.TempDir()
+	out := filepath.Join(tmp, "pkgsyms.go")
+	stale := filepath.Join(tmp, "pkgsyms_windows_tags.go")
+	kept := filepath.Join(tmp, "pkgsyms_mine_tags.go")
+	if err := os.WriteFile(stale, []byte("// Code generated by \"pkgsyms\"; DO NOT EDIT.\n\npackage basic\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(kept, []byte("package basic\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	jobs := []job{{srcdir: filepath.Join("testdata", "basic"), output: out}}
+	if code := runJobs(jobs, 1, noProgress{}); code != exitOK {
+		t.Fatalf("expected exit code %d but got %d", exitOK, code)
+	}
+	*check = true
+	if code := runJobs(jobs, 1, noProgress{}); code != exitOK {
+		t.Fatalf("expected exit code %d after removing the stale file but got %d", exitOK, code)
+	}
+	if _, err := os.Stat(stale); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the stale tagged file to be removed, got %v", err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Fatalf("expected the hand-written file to be kept, got %v", err)
+	}
+	if err := os.WriteFile(stale, []byte("// Code generated by \"pkgsyms\"; DO NOT EDIT.\n\npackage basic\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if code := runJobs(jobs, 1, noProgress{}); code != exitDrift {
+		t.Fatalf("expected exit code %d for a stale tagged file but got %d", exitDrift, code)
+	}
+}
+
+func TestSkipDirs(t *testing.T) {
+	for rel, want := range map[string]bool{
+		"a/b":           false,
+		"a/testdata/b":  true,
+		"vendor/x":      true,
+		"gen/fixtures":  true,
+		"internal/keep": false,
+	} {
+		if got := skipped(rel, []string{"fixtures"}); got != want {
+			t.Errorf("expected skipped(%q) to be %v", rel, want)
+		}
+	}
+
+	root := t.TempDir()
+	for name, src := range map[string]string{
+		"go.mod":              "module example.com/m\n",
+		"a/a.go":              "package a\n",
+		"gen/fixtures/f.go":   "package fixtures\n\nfunc broken( {\n",
+		"skipme/skipme.go":    "package skipme\n",
+		"skipme/keep/keep.go": "package keep\n",
+	} {
+		name = filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	defer func(s stringsFlag) { skipdirs = s }(skipdirs)
+	skipdirs = stringsFlag{"fixtures", "skipme"}
+	dirs, err := packageDirs([]string{"./..."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 1 || filepath.Base(dirs[0]) != "a" {
+		t.Fatalf("expected only a but got %q", dirs)
+	}
+	if dirs, err = packageDirs([]string{"./skipme/...", "./a"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 3 {
+		t.Fatalf("expected the named directories not to be skipped, got %q", dirs)
+	}
+}
+
+func loadTestdata(t *testing.T, dir string) *packages.Package {
+	t.Helper()
+	pkg, err := parsePackage("./testdata/" + dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func compareGolden(t *testing.T, filename string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile(filename, got, 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("%s mismatch; got:\n%s\nwant:\n%s", filename, got, want)
+	}
+}
+
+func TestREPLHTTP(t *testing.T) {
+	const name = "example.com/replhttp"
+	level := "info"
+	pkgsyms.Of(name).Add(
+		pkgsyms.MakeFunc("Join", strings.Join),
+		pkgsyms.MakeVar("Level", &level),
+	)
+	srv := httptest.NewServer(httpsyms.Handler())
+	defer srv.Close()
+	u, err := url.Parse(srv.URL + defaultQueryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := strings.NewReader("use " + name + "\nshow Level\ncall Join [\"a\", \"b\"] \"-\"\nset Level \"debug\"\n")
+	var out strings.Builder
+	if err := replsyms.Run(httpRegistry{base: *u}, in, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := `> > var Level string
+= "info"
+> ["a-b"]
+> error: variables can't be set over HTTP
+> 
+`
+	if out.String() != want {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", want, out.String())
+	}
+}
+
+func TestComplete(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"go.mod", "a/a.go", "a/b/b.go", "a/testdata/t.go", "nested/go.mod", "nested/n.go", "docs/README"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package x\n"), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	const name = "example.com/complete"
+	pkgsyms.Of(name).Add(
+		pkgsyms.MakeFunc("Join", strings.Join),
+		pkgsyms.MakeFunc("Split", strings.Split),
+	)
+	srv := httptest.NewServer(httpsyms.Handler())
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	for _, tc := range []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"que"}, "query"},
+		{[]string{"-fail-on"}, "-fail-on-warning"},
+		{[]string{"query", "-"}, "-addr -call -path"},
+		{[]string{"mock", "-output", ""}, ""},
+		{[]string{""}, ". ./... ./a ./a/b clean completion lint migrate mock query repl"},
+		{[]string{"./a/"}, "./a/b"},
+		{[]string{"clean", "-n", "a"}, "a a/b"},
+		{[]string{"completion", "z"}, "zsh"},
+		{[]string{"query", "-addr", addr, "example.com/comp"}, name},
+		{[]string{"query", "-addr", addr, name, "J"}, "Join"},
+	} {
+		got := complete(tc.words)
+		sort.Strings(got)
+		if strings.Join(got, " ") != tc.want {
+			t.Errorf("expected %q to complete to %q but got %q", tc.words, tc.want, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		buf.Reset()
+		if err := writeCompletion(&buf, shell); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(buf.String(), progname+" "+completeCommand) {
+			t.Errorf("expected the %s script to run %s %s:\n%s", shell, progname, completeCommand, buf.String())
+		}
+	}
+	if err := writeCompletion(&buf, "tcsh"); err == nil {
+		t.Error("expected an error writing a completion for tcsh")
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/pkgsyms/gen"
+)
+
+func migrateUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, `Regenerate files generated by older versions of %s.
+
+Usage of %s migrate:
+	%s migrate [flags] [directory | packages]
+
+Generated files whose API version is older than %d, including the files
+written before API versions were recorded, are regenerated in the current
+style with the flags recorded in their headers.  The pkgsyms package keeps
+supporting older generated files, so they only need to be migrated to get
+the features of newer versions.  Files written with -append are left alone.
+
+Flags:
+`, progname, progname, progname, pkgsyms.APIVersion)
+		fs.PrintDefaults()
+	}
+}
+
+// migrateFlags defines the flags of the migrate subcommand.
+func migrateFlags() (fs *flag.FlagSet, dryRun *bool) {
+	fs = flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun = fs.Bool("n", false, "print the files that would be regenerated without regenerating them")
+	fs.Usage = migrateUsage(fs)
+	return
+}
+
+func migrateMain(args []string) {
+	fs, dryRun := migrateFlags()
+	fs.Parse(args)
+
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	dirs, err := packageDirs(patterns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, dir := range dirs {
+		ms, err := findMigrations(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, m := range ms {
+			fmt.Printf("%s (API version %d)\n", m.filename, m.api)
+			if *dryRun {
+				continue
+			}
+			if err := m.regenerate(); err != nil {
+				log.Fatalf("%s: %v", m.filename, err)
+			}
+		}
+	}
+}
+
+// versionLine matches the line after the header of generated files that
+// versionComment writes.
+var versionLine = regexp.MustCompile(`^// pkgsyms version \S+, API version (\d+)\.$`)
+
+// migration is a generated file to regenerate.
+type migration struct {
+	filename string
+
+	// api is the file's API version.  It's 0 for files written before
+	// API versions were recorded.
+	api int
+
+	// flags are the generator's flags recorded in the file's header.
+	flags []string
+}
+
+// findMigrations gets the generated files in dir whose API version is older
+// than pkgsyms.APIVersion.  The files for build constraints are regenerated
+// along with their main file, so they aren't returned.
+func findMigrations(dir string) ([]migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var ms []migration
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") ||
+			strings.HasSuffix(e.Name(), "_tags.go") {
+			continue
+		}
+		m, ok, err := readMigration(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return ms, err
+		}
+		if ok && m.api < pkgsyms.APIVersion {
+			ms = append(ms, m)
+		}
+	}
+	return ms, nil
+}
+
+// readMigration reads the command line and API version from the header of a
+// file generated by pkgsyms.
+func readMigration(name string) (m migration, ok bool, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return m, false, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	if !sc.Scan() {
+		return m, false, sc.Err()
+	}
+	h := gen.Header.FindStringSubmatch(sc.Text())
+	if h == nil {
+		return m, false, nil
+	}
+	m.filename = name
+	m.flags = recordedFlags(strings.Fields(h[1]))
+	if sc.Scan() {
+		if v := versionLine.FindStringSubmatch(sc.Text()); v != nil {
+			m.api, _ = strconv.Atoi(v[1])
+		}
+	}
+	return m, true, sc.Err()
+}
+
+// recordedFlags gets the flags from a command line recorded in a header,
+// without the packages it was run with, so that it can be rerun for a single
+// file.  Flags that are no longer defined and -output are dropped.
+func recordedFlags(args []string) []string {
+	var flags []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			// The rest are packages.
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		name, _, hasValue := strings.Cut(name, "=")
+		f := flag.CommandLine.Lookup(name)
+		if f == nil || name == "output" {
+			// regenerate sets -output.
+			if f != nil && !hasValue {
+				i++
+			}
+			continue
+		}
+		flags = append(flags, arg)
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); hasValue || ok && bf.IsBoolFlag() {
+			continue
+		}
+		if i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return flags
+}
+
+// regenerate runs the generator with the recorded flags to rewrite the file.
+func (m migration) regenerate() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := append(m.flags[:len(m.flags):len(m.flags)],
+		"-output="+filepath.Base(m.filename), ".")
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = filepath.Dir(m.filename)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
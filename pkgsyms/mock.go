@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/skillian/pkgsyms/pkgsyms/gen"
+)
+
+func mockUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, `Generate a stand-in registry for a package.
+
+Usage of %s mock:
+	%s mock [flags] [directory]
+
+The generated package registers the exported symbols of the package in the
+directory (. by default) with stand-ins instead of the real ones, so that
+code that looks the package's symbols up can be tested without linking it:
+Types are redeclared with the same underlying types but without methods,
+functions are stubs that record their calls and return zero values,
+variables start as zero values and constants keep their values.  Symbols
+whose types can't be redeclared, like generic ones, are listed in a comment
+instead, or fail the command with -strict.  Register the stand-ins with Register, for example into a
+pkgsyms.Registry:
+
+	reg.Register(mypkgmock.PkgPath, mypkgmock.Register)
+
+Flags:
+`, progname, progname)
+		fs.PrintDefaults()
+	}
+}
+
+// mockFlags defines the flags of the mock subcommand.
+func mockFlags() (fs *flag.FlagSet, out, name *string, strict *bool) {
+	fs = flag.NewFlagSet("mock", flag.ExitOnError)
+	out = fs.String("output", "-", "output filename; - writes to standard output")
+	name = fs.String("package", "", "package name of the generated file; default is the package's name followed by mock")
+	strict = fs.Bool("strict", false, "fail instead of listing the symbols that have no stand-ins")
+	fs.Usage = mockUsage(fs)
+	return
+}
+
+func mockMain(args []string) {
+	fs, out, name, strict := mockFlags()
+	fs.Parse(args)
+
+	dir := "."
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		dir = fs.Arg(0)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	pkg, err := parsePackage(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cmdline := strings.Join(append([]string{progname, "mock"}, args...), " ")
+	src, err := gen.Mock(pkg, *name, cmdline, gen.Strict(*strict))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "-" {
+		os.Stdout.Write(src)
+		return
+	}
+	if err := writeFile(*out, func(w io.Writer) error {
+		_, err := w.Write(src)
+		return err
+	}); err != nil {
+		log.Fatal(err)
+	}
+}
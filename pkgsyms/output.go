@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// staged files are written to temporary files next to their destinations and
+// renamed into place when they're committed so that an interrupted or failed
+// run never leaves a half-written file behind.
+type staged struct {
+	files []stagedFile
+}
+
+type stagedFile struct {
+	tmp, name string
+}
+
+// create a temporary file that becomes filename when the files are
+// committed.  Missing parent directories are created.
+func (st *staged) create(filename string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(
+		filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	st.files = append(st.files, stagedFile{tmp: f.Name(), name: filename})
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// write the file by staging it and calling write with it.
+func (st *staged) write(filename string, write func(w io.Writer) error) error {
+	f, err := st.create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", filename, err)
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %q: %w", filename, err)
+	}
+	return nil
+}
+
+// commit renames the staged files into place.
+func (st *staged) commit() error {
+	for len(st.files) > 0 {
+		sf := st.files[0]
+		if err := os.Rename(sf.tmp, sf.name); err != nil {
+			return fmt.Errorf(
+				"failed to rename %q to %q: %w", sf.tmp, sf.name, err)
+		}
+		st.files = st.files[1:]
+	}
+	return nil
+}
+
+// cleanup removes the temporary files that weren't committed.
+func (st *staged) cleanup() {
+	for _, sf := range st.files {
+		os.Remove(sf.tmp)
+	}
+	st.files = nil
+}
+
+// writeFile writes a single file through a staged temporary file.
+func writeFile(filename string, write func(w io.Writer) error) error {
+	var st staged
+	defer st.cleanup()
+	if err := st.write(filename, write); err != nil {
+		return err
+	}
+	return st.commit()
+}
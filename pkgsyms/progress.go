@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// progressReporter reports the progress of batch runs.
+type progressReporter interface {
+	// started is called when a job starts.
+	started(j *job, done, total int)
+
+	// finished is called when a job finishes.
+	finished(j *job, done, total int, err error)
+
+	// end is called after every job finished.
+	end()
+}
+
+// newProgress creates the progressReporter selected by the -progress flag.
+// "auto" shows a progress bar when more than one package is generated and
+// stderr is a terminal.
+func newProgress(mode string, total int) (progressReporter, error) {
+	switch mode {
+	case "auto":
+		if total < 2 || !isTerminal(os.Stderr) {
+			return noProgress{}, nil
+		}
+		fallthrough
+	case "bar":
+		return &barProgress{w: os.Stderr}, nil
+	case "json":
+		return &jsonProgress{enc: json.NewEncoder(os.Stdout)}, nil
+	case "none":
+		return noProgress{}, nil
+	}
+	return nil, fmt.Errorf("unknown progress mode %q", mode)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+type noProgress struct{}
+
+func (noProgress) started(*job, int, int)         {}
+func (noProgress) finished(*job, int, int, error) {}
+func (noProgress) end()                           {}
+
+// progressEvent is written by -progress=json for each job that starts or
+// finishes.
+type progressEvent struct {
+	// Event is "start" or "finish".
+	Event   string `json:"event"`
+	Package string `json:"package"`
+	Output  string `json:"output"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Error   string `json:"error,omitempty"`
+}
+
+// jsonProgress writes progressEvents as JSON lines.
+type jsonProgress struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (p *jsonProgress) write(ev progressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enc.Encode(ev)
+}
+
+func (p *jsonProgress) started(j *job, done, total int) {
+	p.write(progressEvent{Event: "start", Package: j.srcdir, Output: j.output, Done: done, Total: total})
+}
+
+func (p *jsonProgress) finished(j *job, done, total int, err error) {
+	ev := progressEvent{Event: "finish", Package: j.srcdir, Output: j.output, Done: done, Total: total}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	p.write(ev)
+}
+
+func (p *jsonProgress) end() {}
+
+// barProgress draws a progress bar on a terminal.
+type barProgress struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+const barWidth = 30
+
+func (p *barProgress) draw(current string, done, total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := barWidth * done / total
+	fmt.Fprintf(p.w, "\r\x1b[K[%s%s] %d/%d %s",
+		strings.Repeat("=", n), strings.Repeat(" ", barWidth-n),
+		done, total, current)
+}
+
+func (p *barProgress) started(j *job, done, total int) { p.draw(j.srcdir, done, total) }
+
+func (p *barProgress) finished(j *job, done, total int, err error) {
+	p.draw(j.srcdir, done, total)
+}
+
+func (p *barProgress) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Fprint(p.w, "\r\x1b[K")
+}
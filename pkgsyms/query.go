@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// defaultQueryPath is where hosts usually mount the httpsyms handler.
+const defaultQueryPath = "/debug/pkgsyms/"
+
+func queryUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, `Query the registry of a running process.
+
+Usage of %s query:
+	%s query [flags] PKG [SYM]
+
+Without SYM, the symbols of package PKG are listed.  With SYM, the symbol is
+described or, if -call is given, the function is called with the JSON array
+of arguments and its results are printed.  PKG and SYM can also be given as
+a single identity string like '"github.com/acme/foo".Handler'.
+
+Flags:
+`, progname, progname)
+		fs.PrintDefaults()
+	}
+}
+
+// queryFlags defines the flags of the query subcommand.
+func queryFlags() (fs *flag.FlagSet, addr, urlPath, call *string) {
+	fs = flag.NewFlagSet("query", flag.ExitOnError)
+	addr = fs.String("addr", "localhost:8080", "host:port of the process to query")
+	urlPath = fs.String("path", defaultQueryPath, "path the process serves its registry on")
+	call = fs.String("call", "", "JSON array of arguments to call SYM with")
+	fs.Usage = queryUsage(fs)
+	return
+}
+
+func queryMain(args []string) {
+	fs, addr, urlPath, call := queryFlags()
+	fs.Parse(args)
+
+	args = fs.Args()
+	if len(args) == 1 && strings.HasPrefix(args[0], `"`) {
+		pkg, sym, err := pkgsyms.ParseID(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if args = []string{pkg}; sym != "" {
+			args = append(args, sym)
+		}
+	}
+	if len(args) < 1 || len(args) > 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	q := url.Values{"pkg": {args[0]}, "format": {"json"}}
+	if len(args) == 2 {
+		q.Set("sym", args[1])
+	}
+	u := url.URL{Scheme: "http", Host: *addr, Path: *urlPath, RawQuery: q.Encode()}
+
+	var res *http.Response
+	var err error
+	if *call != "" {
+		if len(args) != 2 {
+			log.Fatal("-call requires a symbol name")
+		}
+		res, err = http.Post(u.String(), "application/json", strings.NewReader(*call))
+	} else {
+		res, err = http.Get(u.String())
+	}
+	if err != nil {
+		log.Fatal(fmt.Errorf("failed to query %v: %w", *addr, err))
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		log.Fatal(fmt.Errorf("failed to read response: %w", err))
+	}
+	if res.StatusCode != http.StatusOK {
+		log.Fatal(fmt.Errorf(
+			"%s: %s", res.Status, strings.TrimSpace(string(body))))
+	}
+	if err := printQueryResult(os.Stdout, body, *call != "", len(args) == 2); err != nil {
+		log.Fatal(err)
+	}
+}
+
+type querySymbol struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Signature string `json:"signature"`
+	Doc       string `json:"doc"`
+}
+
+func printQueryResult(w io.Writer, body []byte, called, sym bool) error {
+	switch {
+	case called:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, body, "", "\t"); err != nil {
+			return err
+		}
+		_, err := buf.WriteTo(w)
+		return err
+	case sym:
+		var s querySymbol
+		if err := json.Unmarshal(body, &s); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s %s %s\n", strings.ToLower(s.Kind), s.Name, s.Signature)
+		if s.Doc != "" {
+			fmt.Fprintf(w, "\n%s\n", s.Doc)
+		}
+		return nil
+	}
+	var p struct {
+		Name    string        `json:"name"`
+		Doc     string        `json:"doc"`
+		Symbols []querySymbol `json:"symbols"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "package %s\n\n", p.Name)
+	if p.Doc != "" {
+		fmt.Fprintf(w, "%s\n\n", p.Doc)
+	}
+	for _, s := range p.Symbols {
+		fmt.Fprintf(w, "%s %s %s\n", strings.ToLower(s.Kind), s.Name, s.Signature)
+	}
+	return nil
+}
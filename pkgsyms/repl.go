@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/skillian/pkgsyms/replsyms"
+)
+
+func replUsage(fs *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, `Explore the registry of a running process in a console.
+
+Usage of %s repl:
+	%s repl [flags]
+
+The process must serve its registry with httpsyms like for "%s query".
+Type help in the console for its commands.  Variables can't be set over
+HTTP; link github.com/skillian/pkgsyms/replsyms into the process to set
+them.
+
+Flags:
+`, progname, progname, progname)
+		fs.PrintDefaults()
+	}
+}
+
+// replFlags defines the flags of the repl subcommand.
+func replFlags() (fs *flag.FlagSet, addr, urlPath *string) {
+	fs = flag.NewFlagSet("repl", flag.ExitOnError)
+	addr = fs.String("addr", "localhost:8080", "host:port of the process")
+	urlPath = fs.String("path", defaultQueryPath, "path the process serves its registry on")
+	fs.Usage = replUsage(fs)
+	return
+}
+
+func replMain(args []string) {
+	fs, addr, urlPath := replFlags()
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	reg := httpRegistry{base: url.URL{Scheme: "http", Host: *addr, Path: *urlPath}}
+	if err := replsyms.Run(reg, os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// httpRegistry is a replsyms.Registry of a process serving its registry with
+// httpsyms.
+type httpRegistry struct {
+	base   url.URL
+	client http.Client
+}
+
+// do sends a request with the query and decodes the JSON response into v.
+func (h httpRegistry) do(q url.Values, body []byte, v interface{}) error {
+	q.Set("format", "json")
+	u := h.base
+	u.RawQuery = q.Encode()
+	var res *http.Response
+	var err error
+	if body != nil {
+		res, err = h.client.Post(u.String(), "application/json", bytes.NewReader(body))
+	} else {
+		res, err = h.client.Get(u.String())
+	}
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(data)))
+	}
+	return json.Unmarshal(data, v)
+}
+
+type httpSymbol struct {
+	querySymbol
+	Value json.RawMessage `json:"value"`
+}
+
+func (s httpSymbol) info(pkg string) replsyms.Info {
+	return replsyms.Info{
+		Package:   pkg,
+		Name:      s.Name,
+		Kind:      s.Kind,
+		Signature: s.Signature,
+		Doc:       s.Doc,
+		Value:     s.Value,
+	}
+}
+
+func (h httpRegistry) Packages() ([]string, error) {
+	var pkgs []struct {
+		Name string `json:"name"`
+	}
+	if err := h.do(url.Values{}, nil, &pkgs); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(pkgs))
+	for i, p := range pkgs {
+		names[i] = p.Name
+	}
+	return names, nil
+}
+
+func (h httpRegistry) Symbols(pkg string) ([]replsyms.Info, error) {
+	var p struct {
+		Symbols []httpSymbol `json:"symbols"`
+	}
+	if err := h.do(url.Values{"pkg": {pkg}}, nil, &p); err != nil {
+		return nil, err
+	}
+	infos := make([]replsyms.Info, len(p.Symbols))
+	for i, s := range p.Symbols {
+		infos[i] = s.info(pkg)
+	}
+	return infos, nil
+}
+
+func (h httpRegistry) Symbol(pkg, name string) (replsyms.Info, error) {
+	var s httpSymbol
+	if err := h.do(url.Values{"pkg": {pkg}, "sym": {name}}, nil, &s); err != nil {
+		return replsyms.Info{}, err
+	}
+	return s.info(pkg), nil
+}
+
+func (h httpRegistry) Set(pkg, name string, value []byte) error {
+	return errors.New("variables can't be set over HTTP")
+}
+
+func (h httpRegistry) Call(pkg, name string, args []byte) ([]byte, error) {
+	var results json.RawMessage
+	if err := h.do(url.Values{"pkg": {pkg}, "sym": {name}}, args, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
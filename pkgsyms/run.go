@@ -0,0 +1,515 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/skillian/pkgsyms/pkgsyms/gen"
+	"github.com/skillian/pkgsyms/pkgsyms/internal/load"
+	"golang.org/x/tools/go/packages"
+)
+
+// job generates the registry of one package.
+type job struct {
+	srcdir string
+	output string
+
+	// log collects the job's messages so that they're printed in the
+	// order of the jobs rather than the order they ran in.
+	log bytes.Buffer
+
+	// warnings counts the warnings about the package.
+	warnings int
+}
+
+// packageDirs gets the directories of the packages matched by the patterns
+// with symbolic links resolved.  A single pattern that's a plain directory
+// is used without loading anything.  Otherwise, the directories that
+// skipDirs skips are left out, and so are their errors.
+func packageDirs(patterns []string) ([]string, error) {
+	if len(patterns) == 1 && !strings.Contains(patterns[0], "...") {
+		dir, err := realDir(patterns[0])
+		if err != nil {
+			return nil, err
+		}
+		return []string{dir}, nil
+	}
+	cfg, err := load.Config(".", packages.NeedName|packages.NeedFiles, *gopath)
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	errs := make(map[string]error)
+	for _, p := range pkgs {
+		if len(p.GoFiles) == 0 {
+			if len(p.Errors) > 0 {
+				return nil, fmt.Errorf("%s:\n\t%s", p.PkgPath, load.Diagnostics(p.Errors, "error"))
+			}
+			continue
+		}
+		dir, err := realDir(filepath.Dir(p.GoFiles[0]))
+		if err != nil {
+			return nil, err
+		}
+		if len(p.Errors) > 0 {
+			errs[dir] = fmt.Errorf("%s:\n\t%s", p.PkgPath, load.Diagnostics(p.Errors, "error"))
+		}
+		dirs = append(dirs, dir)
+	}
+	if dirs, err = skipDirs(dirs, patterns); err != nil {
+		return nil, err
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf(
+			"%s matched no packages; patterns are relative to the "+
+				"working directory and -skip-dirs, testdata and vendor "+
+				"directories are skipped", strings.Join(patterns, " "))
+	}
+	for _, dir := range dirs {
+		if err := errs[dir]; err != nil {
+			return nil, err
+		}
+	}
+	return dirs, nil
+}
+
+// realDir gets the absolute path of dir with symbolic links resolved so that
+// a package in a symlinked checkout is loaded, and gets its import path and
+// default output file, from the same directory however it was named.
+func realDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+// runJobs runs the jobs with up to n of them at a time and gets the exit
+// code of the first one that failed.  Each job's messages are printed once it
+// and the jobs before it are done.  The progress of the jobs is reported to
+// prog.
+func runJobs(jobs []job, n int, prog progressReporter) int {
+	if n < 1 {
+		n = 1
+	}
+	var mu sync.Mutex
+	finished := 0
+	errs := make([]error, len(jobs))
+	done := make([]chan struct{}, len(jobs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	var wg sync.WaitGroup
+	next := make(chan int)
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				mu.Lock()
+				prog.started(&jobs[i], finished, len(jobs))
+				mu.Unlock()
+				errs[i] = jobs[i].run()
+				if errs[i] == nil && *failwarn && jobs[i].warnings > 0 {
+					errs[i] = warningsError{jobs[i].warnings}
+				}
+				mu.Lock()
+				finished++
+				prog.finished(&jobs[i], finished, len(jobs), errs[i])
+				mu.Unlock()
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range jobs {
+			next <- i
+		}
+		close(next)
+	}()
+	code := exitOK
+	for i := range jobs {
+		<-done[i]
+		if jobs[i].log.Len() == 0 && errs[i] == nil {
+			continue
+		}
+		mu.Lock()
+		prog.end()
+		os.Stderr.Write(jobs[i].log.Bytes())
+		if errs[i] != nil {
+			log.Print(errs[i])
+			if code == exitOK {
+				code = exitCode(errs[i])
+			}
+		}
+		mu.Unlock()
+	}
+	wg.Wait()
+	prog.end()
+	return code
+}
+
+// logf writes a message to the job's log.
+func (j *job) logf(format string, args ...interface{}) {
+	fmt.Fprintf(&j.log, pkgsymsPkgName+": "+format+"\n", args...)
+}
+
+// runFlags change how the command runs but not what it generates, so
+// they're left out of the command recorded in the output.  Otherwise -check
+// would always find the output of a run without it out of date.
+var runFlags = map[string]bool{
+	"check":           true,
+	"no-cache":        true,
+	"cache-dir":       true,
+	"progress":        true,
+	"jobs":            true,
+	"fail-on-warning": true,
+	"size-report":     true,
+}
+
+// recordedCommand gets the command line recorded in the output of a run with
+// the arguments args.
+func recordedCommand(args []string) string {
+	words := []string{progname}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") {
+			words = append(words, args[i:]...)
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		// The value of a flag that isn't boolean may be the next
+		// argument.
+		n := 1
+		if f := flag.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			n = 2
+		}
+		if !runFlags[name] {
+			words = append(words, args[i:i+n]...)
+		}
+		i += n - 1
+	}
+	return strings.Join(words, " ")
+}
+
+// isBoolFlag reports whether f is a boolean flag, which doesn't take the
+// next argument as its value.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// testVarName is the default variable name of the package symbols with
+// -variant=test.  The file is compiled into the package itself, next to the
+// file generated without -variant, so it can't declare Pkg again.
+const testVarName = "TestPkg"
+
+// outputVarName gets the variable name of the package symbols: the -varname
+// flag, or testVarName if -variant=test and -varname isn't given.
+func outputVarName() string {
+	if *variant != variantTest {
+		return *varname
+	}
+	name := testVarName
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "varname" {
+			name = *varname
+		}
+	})
+	return name
+}
+
+// run generates the job's package.
+func (j *job) run() error {
+	// Files besides the output, like the manifest, are staged and renamed
+	// into place after the output, like writeCached does.
+	var sidecars staged
+	defer sidecars.cleanup()
+	options := []gen.Option{
+		gen.VarName(outputVarName()),
+		gen.Docs(*docs),
+		gen.FuncVars(*fvars),
+		gen.SafeContainers(*safecont),
+		gen.Qualify(*qualify),
+		gen.ReadOnly(rdonly...),
+		gen.ReadOnlySync(*rosync),
+		gen.LocalImplements(*localimp),
+		gen.Methods(*methods),
+		gen.Schema(*schema),
+		gen.Strict(*strict),
+		gen.Registrar(*registr),
+		gen.Target(*target),
+		gen.MaxVarSize(*maxvar),
+		gen.GOPATH(*gopath),
+		gen.Command(recordedCommand(os.Args[1:])),
+		gen.FileSink(func(filename string, data []byte) error {
+			return sidecars.write(filename, func(w io.Writer) error {
+				_, err := w.Write(data)
+				return err
+			})
+		}),
+	}
+	if *pkgname != "" {
+		options = append(options, gen.Alias(*pkgname))
+	}
+	policy, err := parseMergePolicy(*collide)
+	if err != nil {
+		return err
+	}
+	options = append(options, gen.Collisions(policy))
+	if *implmts != "" {
+		options = append(options, gen.Implements(strings.Split(*implmts, ",")...))
+	}
+	for _, pattern := range exclude {
+		options = append(options, gen.ExcludeType(pattern))
+	}
+	if *mfest {
+		if j.output == "-" {
+			return errors.New("-manifest requires an output file")
+		}
+		if *check {
+			return errors.New("-check can't be used with -manifest")
+		}
+		options = append(options, gen.Manifest(j.manifestPath()), gen.ManifestFormat(*mfmt))
+	}
+	var existing []byte
+	if *appendf {
+		if j.output == "-" {
+			return errors.New("-append requires an output file")
+		}
+		var err error
+		existing, err = os.ReadFile(j.output)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		options = append(options, gen.Append(existing))
+	}
+	// Files with build constraints make the output depend on files that
+	// the cache key doesn't cover, so their output isn't cached.
+	var tagged staged
+	defer tagged.cleanup()
+	ntagged := 0
+	written := make(map[string]bool)
+	if j.output != "-" {
+		options = append(options, gen.TaggedFiles(j.output, func(filename string, src []byte) error {
+			ntagged++
+			written[filename] = true
+			if *check {
+				return checkFile(filename, src)
+			}
+			return tagged.write(filename, func(w io.Writer) error {
+				_, err := w.Write(src)
+				return err
+			})
+		}))
+	}
+	var warnings bytes.Buffer
+	options = append(options, gen.Warnf(func(format string, args ...interface{}) {
+		fmt.Fprintf(&warnings, format+"\n", args...)
+		j.warnings++
+		j.logf(format, args...)
+	}))
+
+	var c *cache
+	var key string
+	if !*nocache {
+		var err error
+		if c, err = openCache(*cachedir); err == nil {
+			key, err = cacheKey(j.srcdir, []byte(strings.Join(os.Args[1:], "\x00")), []byte(j.output), existing)
+		}
+		if err != nil {
+			j.logf("not caching: %v", err)
+			c = nil
+		}
+	}
+	if c != nil {
+		if e, ok := c.get(key); ok {
+			if err := j.writeCached(e); err != nil {
+				return err
+			}
+			if err := j.removeStaleTagged(nil); err != nil {
+				return err
+			}
+			return j.reportSize(nil)
+		}
+	}
+
+	var pkg *packages.Package
+	if *expdata {
+		pkg, err = loadPackage(j.srcdir, load.ExportDataNeeds)
+	} else {
+		pkg, err = parsePackage(j.srcdir)
+	}
+	if err != nil {
+		return loadError{err}
+	}
+	var source bytes.Buffer
+	if err := j.writeOutput(func(w io.Writer) error {
+		return gen.Generate(io.MultiWriter(w, &source), pkg, options...)
+	}); err != nil {
+		return err
+	}
+	if err := tagged.commit(); err != nil {
+		return err
+	}
+	if err := sidecars.commit(); err != nil {
+		return err
+	}
+	if err := j.removeStaleTagged(written); err != nil {
+		return err
+	}
+	if c != nil && ntagged == 0 {
+		e := cacheEntry{source: source.Bytes(), warnings: warnings.Bytes()}
+		if *mfest {
+			if e.manifest, err = os.ReadFile(j.manifestPath()); err != nil {
+				return err
+			}
+		}
+		if err := c.put(key, e); err != nil {
+			j.logf("failed to cache output: %v", err)
+		}
+	}
+	return j.reportSize(pkg)
+}
+
+// removeStaleTagged removes the generated files for build constraints (see
+// TaggedFiles) next to the output file that weren't written, because their
+// constraints no longer declare any symbols, so that they don't register the
+// symbols again.  With -check, the first of them is reported as a
+// driftError instead.
+func (j *job) removeStaleTagged(written map[string]bool) error {
+	if j.output == "-" {
+		return nil
+	}
+	names, err := filepath.Glob(strings.TrimSuffix(j.output, ".go") + "_*_tags.go")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if written[name] {
+			continue
+		}
+		if _, ok, err := generatedFile(name); err != nil || !ok {
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if *check {
+			return driftError{name}
+		}
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+		j.logf("removed %s", name)
+	}
+	return nil
+}
+
+// reportSize logs the binary size report if -size-report is set.  If pkg is
+// nil, only its name is loaded.
+func (j *job) reportSize(pkg *packages.Package) error {
+	if !*sizerep {
+		return nil
+	}
+	if j.output == "-" {
+		return errors.New("-size-report requires an output file")
+	}
+	if pkg == nil {
+		var err error
+		if pkg, err = loadPackage(j.srcdir, packages.NeedName); err != nil {
+			return err
+		}
+	}
+	report, err := sizeReport(j.srcdir, pkg.PkgPath, j.output)
+	if err != nil {
+		return err
+	}
+	j.logf("%s", report)
+	return nil
+}
+
+// manifestPath is the filename of the manifest written with -manifest.  Its
+// extension is the -manifest-format.
+func (j *job) manifestPath() string {
+	return strings.TrimSuffix(j.output, ".go") + "." + *mfmt
+}
+
+// writeOutput calls write with the output file.  Files are written to a
+// temporary file first and then renamed so that a failure never leaves a
+// partially written file behind.  With -check, the output is compared to the
+// file instead.
+func (j *job) writeOutput(write func(w io.Writer) error) error {
+	if *check {
+		return j.checkOutput(write)
+	}
+	if j.output == "-" {
+		return write(os.Stdout)
+	}
+	return writeFile(j.output, write)
+}
+
+// checkOutput returns a driftError if the output file doesn't have the
+// content that write writes.
+func (j *job) checkOutput(write func(w io.Writer) error) error {
+	if j.output == "-" {
+		return errors.New("-check requires an output file")
+	}
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		return err
+	}
+	return checkFile(j.output, buf.Bytes())
+}
+
+// checkFile returns a driftError if the file's content isn't src.
+func checkFile(filename string, src []byte) error {
+	existing, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if !bytes.Equal(existing, src) {
+		return driftError{filename}
+	}
+	return nil
+}
+
+// writeCached writes the output from the cache as if it was generated.
+func (j *job) writeCached(e cacheEntry) error {
+	if len(e.warnings) > 0 {
+		for _, w := range strings.Split(strings.TrimSuffix(string(e.warnings), "\n"), "\n") {
+			j.warnings++
+			j.logf("%s", w)
+		}
+	}
+	var st staged
+	defer st.cleanup()
+	if *mfest {
+		if err := st.write(j.manifestPath(), func(w io.Writer) error {
+			_, err := w.Write(e.manifest)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	if err := j.writeOutput(func(w io.Writer) error {
+		_, err := w.Write(e.source)
+		return err
+	}); err != nil {
+		return err
+	}
+	return st.commit()
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// sizeReport builds a program that imports the package in srcdir with and
+// without the registrations in the generated file and describes the
+// difference in binary size.  The program and the replacement for the
+// generated file only exist in a build overlay, so nothing is written next to
+// the package.
+func sizeReport(srcdir, pkgPath, output string) (string, error) {
+	srcdir, err := filepath.Abs(srcdir)
+	if err != nil {
+		return "", err
+	}
+	if output, err = filepath.Abs(output); err != nil {
+		return "", err
+	}
+	if filepath.Dir(output) != srcdir {
+		return "", fmt.Errorf(
+			"-size-report requires the output to be in the package's directory")
+	}
+	tmp, err := os.MkdirTemp("", pkgsymsPkgName+"-size")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	stripped, err := stripRegistry(output)
+	if err != nil {
+		return "", err
+	}
+	files := map[string][]byte{
+		"main.go": []byte(fmt.Sprintf(
+			"package main\n\nimport _ %q\n\nfunc main() {}\n", pkgPath)),
+		"stripped.go": stripped,
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(tmp, name), src, 0644); err != nil {
+			return "", err
+		}
+	}
+	mainDir := filepath.Join(srcdir, "_"+pkgsymsPkgName+"_size")
+	build := func(name string, withRegistry bool) (int64, error) {
+		replace := map[string]string{
+			filepath.Join(mainDir, "main.go"): filepath.Join(tmp, "main.go"),
+		}
+		if !withRegistry {
+			replace[output] = filepath.Join(tmp, "stripped.go")
+		}
+		data, err := json.Marshal(struct{ Replace map[string]string }{replace})
+		if err != nil {
+			return 0, err
+		}
+		overlay := filepath.Join(tmp, name+".json")
+		if err := os.WriteFile(overlay, data, 0644); err != nil {
+			return 0, err
+		}
+		bin := filepath.Join(tmp, name)
+		cmd := exec.Command(
+			"go", "build", "-overlay", overlay, "-o", bin,
+			"./"+filepath.Base(mainDir))
+		cmd.Dir = srcdir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return 0, fmt.Errorf(
+				"failed to build %s: %v: %s",
+				name, err, strings.TrimSpace(string(out)))
+		}
+		fi, err := os.Stat(bin)
+		if err != nil {
+			return 0, err
+		}
+		return fi.Size(), nil
+	}
+	with, err := build("with", true)
+	if err != nil {
+		return "", err
+	}
+	without, err := build("without", false)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(
+		"%s: binary size with registry %d bytes, without %d bytes: +%d bytes (%.1f%%)",
+		pkgPath, with, without, with-without,
+		100*float64(with-without)/float64(without)), nil
+}
+
+// stripRegistry returns the source of the generated file without its init
+// functions so that the package's declarations, like its Package variable,
+// are still defined but nothing is registered.
+func stripRegistry(filename string) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	decls := f.Decls[:0]
+	for _, d := range f.Decls {
+		if fd, ok := d.(*ast.FuncDecl); ok && fd.Recv == nil && fd.Name.Name == "init" {
+			continue
+		}
+		decls = append(decls, d)
+	}
+	f.Decls = decls
+	f.Comments = nil
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !astutil.UsesImport(f, path) {
+			name := ""
+			if spec.Name != nil {
+				name = spec.Name.Name
+			}
+			astutil.DeleteNamedImport(fset, f, name, path)
+		}
+	}
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// skippedElems are the directories that packages matched by patterns aren't
+// generated in unless a pattern names them, like ./testdata/... does.
+var skippedElems = []string{"testdata", "vendor"}
+
+// skipDirs removes the directories that are below a testdata or vendor
+// directory that the patterns don't name, that match a -skip-dirs glob or
+// that git ignores.
+func skipDirs(dirs, patterns []string) ([]string, error) {
+	cwd, err := realDir(".")
+	if err != nil {
+		return nil, err
+	}
+	var roots []string
+	for _, p := range patterns {
+		root := p
+		if i := strings.Index(root, "..."); i >= 0 {
+			root = root[:i]
+		}
+		if root, err = realDir(root); err == nil {
+			roots = append(roots, root)
+		}
+	}
+	kept := dirs[:0]
+	var rels []string
+	for _, dir := range dirs {
+		if skipped(belowRoot(dir, roots), skipdirs) {
+			continue
+		}
+		kept = append(kept, dir)
+		if rel, err := filepath.Rel(cwd, dir); err == nil {
+			rels = append(rels, rel)
+		}
+	}
+	ignored := gitIgnored(rels)
+	if len(ignored) == 0 {
+		return kept, nil
+	}
+	dirs = kept[:0]
+	for _, dir := range kept {
+		if rel, err := filepath.Rel(cwd, dir); err != nil || !ignored[rel] {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// belowRoot gets the part of dir below the longest of the roots that
+// contains it, or all of dir if none of them do.
+func belowRoot(dir string, roots []string) string {
+	below := dir
+	for _, root := range roots {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(rel) < len(below) {
+			below = rel
+		}
+	}
+	return below
+}
+
+// skipped reports whether the relative path has a testdata or vendor
+// element or matches one of the globs, either as a whole or by one of its
+// elements.
+func skipped(rel string, globs []string) bool {
+	elems := strings.Split(filepath.ToSlash(rel), "/")
+	for _, elem := range elems {
+		for _, s := range skippedElems {
+			if elem == s {
+				return true
+			}
+		}
+	}
+	for _, glob := range globs {
+		if ok, _ := filepath.Match(glob, rel); ok {
+			return true
+		}
+		for _, elem := range elems {
+			if ok, _ := filepath.Match(glob, elem); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// gitIgnored gets the paths that git ignores.  Outside of a git work tree or
+// without git, nothing is ignored.
+func gitIgnored(paths []string) map[string]bool {
+	if len(paths) == 0 {
+		return nil
+	}
+	cmd := exec.Command("git", "check-ignore", "--stdin")
+	cmd.Stdin = strings.NewReader(strings.Join(paths, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil && len(out) == 0 {
+		// git exits with 1 when nothing is ignored.
+		return nil
+	}
+	ignored := make(map[string]bool)
+	for _, line := range bytes.Split(bytes.TrimSpace(out), []byte("\n")) {
+		if len(line) > 0 {
+			ignored[filepath.Clean(string(line))] = true
+		}
+	}
+	return ignored
+}
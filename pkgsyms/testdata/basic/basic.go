@@ -0,0 +1,50 @@
+// Package basic exercises the shapes of declarations the generator handles.
+package basic
+
+import "io"
+
+// Answer is an untyped constant.
+const Answer = 42
+
+// Mode is a named type used by typed constants.
+type Mode int
+
+// Modes enumerated with iota.
+const (
+	// Fast mode.
+	Fast Mode = iota
+	Slow
+	unexportedMode
+)
+
+// Pi is a typed constant.
+const Pi float32 = 3.14
+
+var (
+	// Greeting is a variable with an inferred type.
+	Greeting = "hello"
+
+	// Out is a variable with an explicit type.
+	Out io.Writer
+
+	// OnGreet is a variable of function type.
+	OnGreet = func(name string) string { return Greeting + ", " + name }
+)
+
+var hidden int
+
+// Handler is a function type.
+type Handler func(name string) error
+
+// Greeter is an interface type.
+type Greeter interface {
+	Greet() string
+}
+
+// Hello returns the greeting.
+func Hello() string { return Greeting }
+
+// Greet is a method and isn't registered.
+func (m Mode) Greet() string { return Greeting }
+
+func unexported() {}
@@ -0,0 +1,11 @@
+// Package bigvar has a variable too large to register without a warning.
+package bigvar
+
+// Table is a large lookup table.
+var Table [1 << 20]byte
+
+// Small is a small lookup table.
+var Small [8]byte
+
+// Data is only as large as its slice header.
+var Data = make([]byte, 1<<20)
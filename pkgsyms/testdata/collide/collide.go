@@ -0,0 +1,17 @@
+// Package collide has exported names that collide with generated code and
+// with Go's keywords and builtins.
+package collide
+
+// Pkg collides with the generated package variable.
+var Pkg = 1
+
+// Len shadows the len builtin.
+func Len() int { return 0 }
+
+// Type shadows the type keyword.
+//
+//pkgsyms:name Kind
+type Type int
+
+// Value doesn't collide with anything.
+const Value = 1
@@ -0,0 +1,20 @@
+// Package containers has variables of map and channel types.
+package containers
+
+// Counts is a map.
+var Counts = map[string]int{}
+
+// Events is a channel.
+var Events = make(chan string, 8)
+
+// Done is a receive-only channel.
+var Done <-chan struct{}
+
+// Table is a named map type.
+type Table map[int]string
+
+// Names is a variable of a named map type.
+var Names Table
+
+// List is a slice, so it isn't guarded.
+var List []string
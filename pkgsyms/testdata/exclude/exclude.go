@@ -0,0 +1,19 @@
+// Package exclude has symbols that are excluded by their types.
+package exclude
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// Helper is a test helper.
+func Helper(t *testing.T) {}
+
+// Ptr references unsafe.Pointer.
+var Ptr unsafe.Pointer
+
+// Addr has unsafe.Pointer as its underlying type.
+type Addr unsafe.Pointer
+
+// Count is kept.
+var Count int
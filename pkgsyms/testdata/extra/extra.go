@@ -0,0 +1,5 @@
+// Package extra adds hand-written symbols to its registry.
+package extra
+
+// Version is registered by the generator.
+const Version = "1.0"
@@ -0,0 +1,8 @@
+package extra
+
+import "github.com/skillian/pkgsyms"
+
+// extraSymbols registers a computed symbol.
+func extraSymbols() []pkgsyms.Symbol {
+	return []pkgsyms.Symbol{pkgsyms.MakeConst("MajorVersion", Version[:1])}
+}
@@ -0,0 +1,31 @@
+// Package generic exercises generic types, functions and constraints.
+package generic
+
+import "fmt"
+
+// Number is a constraint interface.
+type Number interface {
+	~int | ~float64
+}
+
+// Stringer is an ordinary interface used as a constraint.
+type Stringer interface {
+	fmt.Stringer
+}
+
+// Pair is a generic type.
+type Pair[K comparable, V Number] struct {
+	Key   K
+	Value V
+}
+
+// Sum is a generic function.
+func Sum[T Number](ts ...T) (sum T) {
+	for _, t := range ts {
+		sum += t
+	}
+	return
+}
+
+// Join is a generic function with a constraint from another package.
+func Join[T fmt.Stringer](ts []T) string { return fmt.Sprint(ts) }
@@ -0,0 +1,29 @@
+// Package implements has types that implement some standard interfaces.
+package implements
+
+// Reader implements io.Reader.
+type Reader struct{}
+
+func (Reader) Read(p []byte) (int, error) { return 0, nil }
+
+// Buffer implements io.Reader and io.Writer through a pointer.
+type Buffer struct{}
+
+func (*Buffer) Read(p []byte) (int, error)  { return 0, nil }
+func (*Buffer) Write(p []byte) (int, error) { return len(p), nil }
+
+// Closer implements neither.
+type Closer struct{}
+
+func (Closer) Close() error { return nil }
+
+// Source is implemented by Reader and Buffer.
+type Source interface {
+	Read(p []byte) (int, error)
+}
+
+// Stream is implemented by Buffer.
+type Stream interface {
+	Source
+	Write(p []byte) (int, error)
+}
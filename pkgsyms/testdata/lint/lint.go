@@ -0,0 +1,17 @@
+// Package lint looks some of its registered symbols up.
+package lint
+
+import "github.com/skillian/pkgsyms"
+
+// Used is looked up by name.
+func Used() {}
+
+// Unused is never looked up.
+func Unused() {}
+
+// Find looks symbols up.
+func Find(name string) {
+	Pkg.LookupFunc("Used")
+	Pkg.Lookup(name)
+	pkgsyms.Resolve(`"github.com/skillian/pkgsyms/pkgsyms/testdata/lint".Find`)
+}
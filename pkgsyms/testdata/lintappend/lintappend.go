@@ -0,0 +1,8 @@
+// Package lintappend has a registry generated with -append.
+package lintappend
+
+// Used is looked up by name.
+func Used() {}
+
+// Unused is never looked up.
+func Unused() {}
@@ -0,0 +1,31 @@
+// The code between the pkgsyms:begin and pkgsyms:end markers is
+// generated by "pkgsyms".  Edit outside of the markers.
+// pkgsyms version (devel), API version 1.
+
+package lintappend
+
+import (
+	"github.com/skillian/pkgsyms"
+)
+
+// Fails to compile against versions of pkgsyms that don't support the
+// generated code.
+const _ = pkgsyms.SupportsAPIVersion1
+
+var Pkg = pkgsyms.Of("github.com/skillian/pkgsyms/pkgsyms/testdata/lintappend")
+
+func init() {
+	Pkg.SetGenerator("(devel)", 1)
+	Pkg.Add(
+		// pkgsyms:begin symbols
+		pkgsyms.MakeFunc("Unused", Unused),
+		pkgsyms.MakeFunc("Used", Used),
+		// pkgsyms:end symbols
+	)
+	Pkg.MarkReady()
+}
+
+// used is hand-written and looks Used up.
+func used() {
+	Pkg.LookupFunc("Used")
+}
@@ -0,0 +1,12 @@
+// Package renames has functions renamed to the same name.
+package renames
+
+// NewClient creates a client.
+//
+//pkgsyms:name Dial
+func NewClient() {}
+
+// NewServer creates a server.
+//
+//pkgsyms:name Dial
+func NewServer() {}
@@ -0,0 +1,29 @@
+// Package schema has struct types used as configuration.
+package schema
+
+import "time"
+
+// Base is embedded into Config.
+type Base struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// Config configures a server.
+type Config struct {
+	Base
+	Addr    string        `json:"addr,omitempty" required:"true"`
+	Timeout time.Duration `json:"timeout"`
+	Tags    []string      `json:"tags,omitempty"`
+	Limits  *Limits       `json:"limits"`
+	Ignored int           `json:"-"`
+	Plain   bool
+	secret  string
+}
+
+// Limits are nested in Config.
+type Limits struct {
+	Max uint8 `json:"max"`
+}
+
+// Mode isn't a struct, so it has no schema.
+type Mode int
@@ -0,0 +1,11 @@
+// Package strict has constants that can't be registered.
+package strict
+
+// Big overflows int.
+const Big = 1 << 100
+
+// Small fits in an int.
+const Small = 1
+
+// Inf overflows float64.
+const Inf = 1e400
@@ -0,0 +1,27 @@
+// Package syncvars has variables that contain synchronization primitives.
+package syncvars
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Mu is a mutex.
+var Mu sync.Mutex
+
+// State contains an atomic counter.
+var State struct {
+	Name  string
+	Count atomic.Int64
+}
+
+// Once is a pointer, so setting it doesn't copy over the sync.Once.
+var Once *sync.Once
+
+// Name is an ordinary variable.
+var Name string
+
+// Limit is registered read-only by a directive.
+//
+//pkgsyms:readonly
+var Limit = 10
@@ -0,0 +1,5 @@
+// Package tagged declares symbols in files with build constraints.
+package tagged
+
+// Common is declared everywhere.
+func Common() {}
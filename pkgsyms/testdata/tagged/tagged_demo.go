@@ -0,0 +1,6 @@
+//go:build pkgsymsdemo && !windows
+
+package tagged
+
+// Demo is declared with the pkgsymsdemo tag.
+var Demo = "demo"
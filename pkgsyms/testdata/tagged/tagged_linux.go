@@ -0,0 +1,4 @@
+package tagged
+
+// Linux is only declared on Linux.
+func Linux() {}
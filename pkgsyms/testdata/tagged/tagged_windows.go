@@ -0,0 +1,13 @@
+package tagged
+
+// Handle is a Windows handle.
+type Handle uintptr
+
+// MaxPath is the maximum length of a path.
+const MaxPath = 260
+
+// Windows is only declared on Windows.
+func Windows() {}
+
+// Map is generic.
+func Map[T any, U comparable](ts []T, f func(T) U) []U { return nil }
@@ -0,0 +1,16 @@
+// Package target keeps its symbols in a field of its own struct.
+package target
+
+import "github.com/skillian/pkgsyms"
+
+// App is a framework's application.
+type App struct {
+	Name    string
+	Symbols pkgsyms.Symbols
+}
+
+// Registry is the application whose Symbols are the target.
+var Registry = &App{Name: "target"}
+
+// Hello returns the greeting.
+func Hello() string { return "hello" }
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Package variants that -variant selects.
+const (
+	// variantPrimary is the package without its tests.
+	variantPrimary = ""
+
+	// variantTest is the package compiled with its _test.go files.
+	variantTest = "test"
+
+	// variantXTest is the package's external test package.
+	variantXTest = "xtest"
+)
+
+// selectVariant keeps the packages of the variant from the packages loaded
+// with packages.Config.Tests, sorted by their IDs so that the selection
+// doesn't depend on the order go list reports them in.  The test binaries'
+// main packages are never selected.
+func selectVariant(pkgs []*packages.Package, variant string) ([]*packages.Package, error) {
+	var keep func(p *packages.Package) bool
+	switch variant {
+	case variantPrimary:
+		keep = func(p *packages.Package) bool {
+			return !isTestVariant(p) && !strings.HasSuffix(p.PkgPath, "_test")
+		}
+	case variantTest:
+		keep = func(p *packages.Package) bool {
+			return isTestVariant(p) && !strings.HasSuffix(p.PkgPath, "_test")
+		}
+	case variantXTest:
+		keep = func(p *packages.Package) bool {
+			return strings.HasSuffix(p.PkgPath, "_test")
+		}
+	default:
+		return nil, fmt.Errorf(
+			"unknown -variant %q; expected \"test\" or \"xtest\"", variant)
+	}
+	var selected []*packages.Package
+	for _, p := range pkgs {
+		if !strings.HasSuffix(p.PkgPath, ".test") && keep(p) {
+			selected = append(selected, p)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].ID < selected[j].ID
+	})
+	return selected, nil
+}
+
+// isTestVariant reports whether p was compiled for a test binary, which go
+// list marks by appending the binary's name in brackets to the ID.
+func isTestVariant(p *packages.Package) bool {
+	return strings.HasSuffix(p.ID, ".test]")
+}
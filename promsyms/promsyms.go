@@ -0,0 +1,107 @@
+// Package promsyms exports metrics of the pkgsyms registry to Prometheus so
+// that the dynamic dispatch of a production binary can be monitored:
+//
+//	c := promsyms.NewCollector(nil)
+//	pkgsyms.SetLogger(c.Log)
+//	prometheus.MustRegister(c)
+//
+// The number of symbols in each package is read from the registry when the
+// metrics are collected.  Lookups and calls are counted from the registry's
+// events, so they're only counted while the Collector's Log is the logger.
+package promsyms
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/skillian/pkgsyms"
+)
+
+// Collector is a prometheus.Collector of a registry's metrics:
+//
+//   - pkgsyms_symbols: gauge of the symbols registered in each package.
+//   - pkgsyms_lookups_total: counter of the lookups in each package, with a
+//     "result" label of "hit" or "miss".
+//   - pkgsyms_call_duration_seconds: histogram of the durations of the calls
+//     of each function with (pkgsyms.Func).Call.  Only Funcs looked up in a
+//     Package have a "package" label.
+type Collector struct {
+	registry *pkgsyms.Registry
+	next     func(pkgsyms.Event)
+	symbols  *prometheus.Desc
+	lookups  *prometheus.CounterVec
+	calls    *prometheus.HistogramVec
+}
+
+// Option configures a Collector.
+type Option func(c *Collector)
+
+// Next passes the events that the Collector's Log gets on to log, so that the
+// Collector can be installed with pkgsyms.SetLogger alongside another logger.
+func Next(log func(pkgsyms.Event)) Option {
+	return func(c *Collector) {
+		c.next = log
+	}
+}
+
+// NewCollector creates a Collector of the metrics of reg, or of the global
+// registry if reg is nil.
+func NewCollector(reg *pkgsyms.Registry, options ...Option) *Collector {
+	c := &Collector{
+		registry: reg,
+		symbols: prometheus.NewDesc(
+			"pkgsyms_symbols",
+			"Number of symbols registered in the package.",
+			[]string{"package"}, nil),
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pkgsyms_lookups_total",
+			Help: "Number of symbol lookups in the package.",
+		}, []string{"package", "result"}),
+		calls: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pkgsyms_call_duration_seconds",
+			Help:    "Durations of dynamic calls of the function.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"package", "symbol"}),
+	}
+	for _, o := range options {
+		o(c)
+	}
+	return c
+}
+
+// Log counts lookups and calls from the registry's events.  Install it with
+// pkgsyms.SetLogger.
+func (c *Collector) Log(e pkgsyms.Event) {
+	switch e.Kind {
+	case pkgsyms.EventLookupHit:
+		c.lookups.WithLabelValues(e.Pkg, "hit").Inc()
+	case pkgsyms.EventLookupMiss:
+		c.lookups.WithLabelValues(e.Pkg, "miss").Inc()
+	case pkgsyms.EventCall:
+		c.calls.WithLabelValues(e.Pkg, e.Sym).Observe(e.Duration.Seconds())
+	}
+	if c.next != nil {
+		c.next(e)
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.symbols
+	c.lookups.Describe(ch)
+	c.calls.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	var pkgs []*pkgsyms.Package
+	if c.registry == nil {
+		pkgs = pkgsyms.Packages()
+	} else {
+		pkgs = c.registry.Packages()
+	}
+	for _, p := range pkgs {
+		ch <- prometheus.MustNewConstMetric(
+			c.symbols, prometheus.GaugeValue, float64(p.Len()), p.Name)
+	}
+	c.lookups.Collect(ch)
+	c.calls.Collect(ch)
+}
@@ -0,0 +1,52 @@
+package promsyms_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/promsyms"
+)
+
+func TestCollector(t *testing.T) {
+	const name = "github.com/skillian/pkgsyms/promsyms_test"
+	reg := pkgsyms.NewRegistry()
+	p := reg.Of(name)
+	p.Add(pkgsyms.MakeFunc("ToUpper", strings.ToUpper), pkgsyms.MakeConst("Answer", 42))
+	var forwarded int
+	c := promsyms.NewCollector(reg, promsyms.Next(func(pkgsyms.Event) { forwarded++ }))
+	pkgsyms.SetLogger(c.Log)
+	defer pkgsyms.SetLogger(nil)
+
+	f, err := p.LookupFunc("ToUpper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Call("x")
+	p.Lookup("Missing")
+	pkgsyms.SetLogger(nil)
+
+	want := `
+# HELP pkgsyms_lookups_total Number of symbol lookups in the package.
+# TYPE pkgsyms_lookups_total counter
+pkgsyms_lookups_total{package="` + name + `",result="hit"} 1
+pkgsyms_lookups_total{package="` + name + `",result="miss"} 1
+# HELP pkgsyms_symbols Number of symbols registered in the package.
+# TYPE pkgsyms_symbols gauge
+pkgsyms_symbols{package="` + name + `"} 2
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(want), "pkgsyms_lookups_total", "pkgsyms_symbols"); err != nil {
+		t.Fatal(err)
+	}
+	if n := testutil.CollectAndCount(c, "pkgsyms_call_duration_seconds"); n != 1 {
+		t.Fatalf("expected the duration of 1 function but got %d", n)
+	}
+	if forwarded != 3 {
+		t.Fatalf("expected 3 events to be passed on, not %d", forwarded)
+	}
+	if err := prometheus.NewPedanticRegistry().Register(c); err != nil {
+		t.Fatal(err)
+	}
+}
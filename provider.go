@@ -0,0 +1,111 @@
+package pkgsyms
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Provider provides symbols that weren't registered by generated code, like
+// symbols loaded from Go plugins, remote registries or debug information.
+// Providers are consulted the first time a lookup misses.
+type Provider interface {
+	// Provide the symbols of the package with the given name.  A Provider
+	// that doesn't know the package should return no symbols and a nil
+	// error.
+	Provide(pkg string) ([]Symbol, error)
+}
+
+// ProviderFunc adapts a function to the Provider interface.
+type ProviderFunc func(pkg string) ([]Symbol, error)
+
+// Provide calls f.
+func (f ProviderFunc) Provide(pkg string) ([]Symbol, error) { return f(pkg) }
+
+var providers struct {
+	sync.Mutex
+	slice []Provider
+}
+
+// RegisterProvider adds a Provider that backs part of the registry.  Providers
+// are consulted in the order they're registered.  Each provider is consulted
+// at most once per package, on the first lookup miss in that package after
+// the provider is registered.
+func RegisterProvider(pr Provider) {
+	providers.Lock()
+	defer providers.Unlock()
+	providers.slice = append(providers.slice, pr)
+}
+
+// provide consults the providers that haven't been consulted for the package
+// yet and adds their symbols.  It reports whether any provider was consulted.
+// A provider that fails is consulted again on the next miss.
+func (p *Package) provide() (bool, error) {
+	providers.Lock()
+	prs := providers.slice
+	providers.Unlock()
+	p.providedMu.Lock()
+	defer p.providedMu.Unlock()
+	consulted := false
+	for ; p.provided < len(prs); p.provided++ {
+		ss, err := prs[p.provided].Provide(p.Name)
+		if err != nil {
+			return consulted, fmt.Errorf(
+				"package %q: provider %T: %w",
+				p.Name, prs[p.provided], err)
+		}
+		p.Add(ss...)
+		consulted = true
+	}
+	return consulted, nil
+}
+
+// Lookup a symbol in the package.  If the symbol isn't found, providers that
+// haven't yet been consulted for this package are asked for its symbols
+// before giving up.  If the package has an Authorizer, it's consulted with a
+// nil caller.
+func (p *Package) Lookup(name string) (Symbol, error) {
+	return p.LookupAs(nil, name)
+}
+
+// lookup a symbol in the package without authorizing it.
+func (p *Package) lookup(name string) (Symbol, error) {
+	s, err := p.Symbols.Lookup(name)
+	if err == nil {
+		logEvent(EventLookupHit, p.Name, name)
+		return s, nil
+	}
+	consulted, perr := p.provide()
+	if perr != nil {
+		return nil, perr
+	}
+	if !consulted {
+		logEvent(EventLookupMiss, p.Name, name)
+		return nil, NotFound{Pkg: p.Name, Sym: name}
+	}
+	if s, err = p.Symbols.Lookup(name); err != nil {
+		logEvent(EventLookupMiss, p.Name, name)
+		return nil, NotFound{Pkg: p.Name, Sym: name}
+	}
+	logEvent(EventLookupHit, p.Name, name)
+	return s, nil
+}
+
+// lookupProvided looks for a package that isn't registered in r yet in the
+// providers.
+func (r *Registry) lookupProvided(name string) (*Package, error) {
+	providers.Lock()
+	n := len(providers.slice)
+	providers.Unlock()
+	if n == 0 {
+		return nil, NotFound{Pkg: name}
+	}
+	p := &Package{Name: name, Symbols: Symbols{pkg: name}, registry: r}
+	if _, err := p.provide(); err != nil {
+		return nil, err
+	}
+	if len(p.slice) == 0 {
+		return nil, NotFound{Pkg: name}
+	}
+	v, _ := r.pkgs.LoadOrStore(name, p)
+	return v.(*Package), nil
+}
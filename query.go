@@ -0,0 +1,141 @@
+package pkgsyms
+
+import "reflect"
+
+// Select gets the symbols in the set for which pred returns true, in the
+// order they were added in.  The predicates in this file, like ByKind, can be
+// combined with And, Or and Not.  There's no predicate for tags because
+// symbols don't record any; match on another property, like a Const's Group,
+// with a predicate of your own.
+func (syms *Symbols) Select(pred func(Symbol) bool) []Symbol {
+	var res []Symbol
+	for _, s := range syms.snapshot() {
+		if pred(s) {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// ByKind matches symbols of any of the kinds.
+func ByKind(kinds ...Kind) func(Symbol) bool {
+	return func(s Symbol) bool {
+		k := KindOf(s)
+		for _, want := range kinds {
+			if k == want {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ByTypeAssignableTo matches symbols whose values are assignable to t:  Consts
+// and Funcs by the types of their values, Vars by the types of the variables
+// and Types by the types they define.  Generics and Constraints have no
+// types, so they never match.
+func ByTypeAssignableTo(t reflect.Type) func(Symbol) bool {
+	return func(s Symbol) bool {
+		st := symbolType(s)
+		return st != nil && st.AssignableTo(t)
+	}
+}
+
+// symbolType gets the type that ByTypeAssignableTo checks.
+func symbolType(s Symbol) reflect.Type {
+	switch s := s.(type) {
+	case Type:
+		return s.Type()
+	case Var:
+		return s.Type()
+	case Generic, Constraint:
+		return nil
+	}
+	return reflect.TypeOf(s.Get())
+}
+
+// And matches symbols that all of the predicates match.
+func And(preds ...func(Symbol) bool) func(Symbol) bool {
+	return func(s Symbol) bool {
+		for _, pred := range preds {
+			if !pred(s) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches symbols that any of the predicates match.
+func Or(preds ...func(Symbol) bool) func(Symbol) bool {
+	return func(s Symbol) bool {
+		for _, pred := range preds {
+			if pred(s) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches symbols that pred doesn't match.
+func Not(pred func(Symbol) bool) func(Symbol) bool {
+	return func(s Symbol) bool { return !pred(s) }
+}
+
+// Funcs gets the Funcs in the set, including the Funcs of Callable
+// variables, in the order they were added in.
+func (syms *Symbols) Funcs() []Func {
+	var fs []Func
+	for _, s := range syms.snapshot() {
+		if f, ok := AsFunc(s); ok {
+			fs = append(fs, f)
+		}
+	}
+	return fs
+}
+
+// Types gets the Types in the set in the order they were added in.
+func (syms *Symbols) Types() []Type {
+	var ts []Type
+	for _, s := range syms.snapshot() {
+		if t, ok := s.(Type); ok {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+// Consts gets the Consts in the set in the order they were added in.
+func (syms *Symbols) Consts() []Const {
+	var cs []Const
+	for _, s := range syms.snapshot() {
+		if c, ok := s.(Const); ok {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// ConstGroup gets the Consts in the set whose Group is group in the order
+// they were added in, which is the order they're declared in.
+func (syms *Symbols) ConstGroup(group string) []Const {
+	var cs []Const
+	for _, s := range syms.snapshot() {
+		if c, ok := s.(Const); ok && group != "" && c.group == group {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// Vars gets the Vars in the set in the order they were added in.
+func (syms *Symbols) Vars() []Var {
+	var vs []Var
+	for _, s := range syms.snapshot() {
+		if v, ok := s.(Var); ok {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
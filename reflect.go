@@ -0,0 +1,54 @@
+//go:build !tinygo
+
+package pkgsyms
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// elemType gets the type that pval points to.
+func elemType(pval interface{}) reflect.Type { return reflect.TypeOf(pval).Elem() }
+
+// getVar gets the value that addr points to.
+func getVar(addr interface{}) interface{} {
+	return reflect.ValueOf(addr).Elem().Interface()
+}
+
+// setVar sets the value that addr points to.
+func setVar(addr, val interface{}) {
+	reflect.ValueOf(addr).Elem().Set(reflect.ValueOf(val))
+}
+
+// forwardFunc creates a function that forwards calls to the function that
+// addr, the address of the variable name, points to.  It returns an error
+// if addr isn't a non-nil pointer to a function.  Calls while the variable
+// is nil return a NilSymbol error if the function's last result is an error
+// and panic with it otherwise.
+func forwardFunc(name string, addr interface{}) (interface{}, error) {
+	pv := reflect.ValueOf(addr)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() || pv.Elem().Kind() != reflect.Func {
+		return nil, fmt.Errorf("cannot make %T callable", addr)
+	}
+	elem := pv.Elem()
+	ft := elem.Type()
+	return reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		if elem.IsNil() {
+			err := NilSymbol{Sym: name, Kind: VarKind}
+			n := ft.NumOut()
+			if n == 0 || ft.Out(n-1) != errorType {
+				panic(err)
+			}
+			out := make([]reflect.Value, n)
+			for i := range out {
+				out[i] = reflect.Zero(ft.Out(i))
+			}
+			out[n-1] = reflect.ValueOf(error(err))
+			return out
+		}
+		if ft.IsVariadic() {
+			return elem.CallSlice(args)
+		}
+		return elem.Call(args)
+	}).Interface(), nil
+}
@@ -0,0 +1,72 @@
+//go:build tinygo
+
+package pkgsyms
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// elemType gets the type that pval points to.
+func elemType(pval interface{}) reflect.Type { return reflect.TypeOf(pval).Elem() }
+
+// getVar gets the value that addr points to.  Variables of predeclared types
+// are handled without reflection.
+func getVar(addr interface{}) interface{} {
+	switch p := addr.(type) {
+	case *bool:
+		return *p
+	case *int:
+		return *p
+	case *int64:
+		return *p
+	case *uint:
+		return *p
+	case *uint64:
+		return *p
+	case *float64:
+		return *p
+	case *string:
+		return *p
+	case *interface{}:
+		return *p
+	}
+	return reflect.ValueOf(addr).Elem().Interface()
+}
+
+// setVar sets the value that addr points to.  Variables of predeclared types
+// are handled without reflection.
+func setVar(addr, val interface{}) {
+	switch p := addr.(type) {
+	case *bool:
+		*p = val.(bool)
+	case *int:
+		*p = val.(int)
+	case *int64:
+		*p = val.(int64)
+	case *uint:
+		*p = val.(uint)
+	case *uint64:
+		*p = val.(uint64)
+	case *float64:
+		*p = val.(float64)
+	case *string:
+		*p = val.(string)
+	case *interface{}:
+		*p = val
+	default:
+		reflect.ValueOf(addr).Elem().Set(reflect.ValueOf(val))
+	}
+}
+
+// forwardFunc gets the function that addr, the address of the variable name,
+// points to.  It returns an error if addr isn't a non-nil pointer to a
+// function.  TinyGo doesn't implement reflect.MakeFunc, so later changes to
+// the variable aren't forwarded.
+func forwardFunc(name string, addr interface{}) (interface{}, error) {
+	pv := reflect.ValueOf(addr)
+	if pv.Kind() != reflect.Ptr || pv.IsNil() || pv.Elem().Kind() != reflect.Func {
+		return nil, fmt.Errorf("cannot make %T callable", addr)
+	}
+	return pv.Elem().Interface(), nil
+}
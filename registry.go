@@ -0,0 +1,85 @@
+package pkgsyms
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry is a set of packages.  The package-level functions like Of and
+// Lookup use a global Registry that generated init functions register into.
+// Tests can create their own with NewRegistry and register into it with the
+// functions generated with the pkgsyms command's -registrar flag, without
+// touching the global registry.  The Providers are shared by every Registry.
+type Registry struct {
+	// pkgs is a mapping of package names to their *Packages.
+	pkgs sync.Map
+
+	// strictNil is set by SetStrictNil.
+	strictNil int32
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry { return &Registry{} }
+
+// Of gets the Package with the given name in the registry, creating it if
+// it doesn't exist.  See the package-level Of.
+func (r *Registry) Of(name string) *Package {
+	v, loaded := r.pkgs.Load(name)
+	if loaded {
+		return v.(*Package)
+	}
+	pkg := &Package{Name: name, Symbols: Symbols{pkg: name}, registry: r}
+	v, loaded = r.pkgs.LoadOrStore(name, pkg)
+	if loaded {
+		return v.(*Package)
+	}
+	return pkg
+}
+
+// SetStrictNil turns strict nil checks of the registry's symbols on or off.
+// With them on, the Vars and Funcs looked up in the registry's packages
+// check for nil pointers and functions when their values are gotten:  GetE
+// returns a NilSymbol error, Get returns nil instead of panicking and an
+// EventNil is logged, so that a missing value is reported where it's gotten
+// instead of blowing up later.
+func (r *Registry) SetStrictNil(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&r.strictNil, v)
+}
+
+// Register calls a registrar generated with -registrar with the registry's
+// package with the given name and returns the package.  For example:
+//
+//	reg := pkgsyms.NewRegistry()
+//	p := reg.Register("example.com/mypkg", mypkg.RegisterSymbols)
+func (r *Registry) Register(name string, registrar func(p *Package)) *Package {
+	p := r.Of(name)
+	registrar(p)
+	return p
+}
+
+// Packages gets every package in the registry, sorted by name.
+func (r *Registry) Packages() []*Package {
+	var ps []*Package
+	r.pkgs.Range(func(_, v interface{}) bool {
+		ps = append(ps, v.(*Package))
+		return true
+	})
+	sort.Slice(ps, func(i, j int) bool { return ps[i].Name < ps[j].Name })
+	return ps
+}
+
+// Lookup a package in the registry by its name.  If the package isn't
+// registered, the registered Providers are asked for its symbols before
+// giving up.
+func (r *Registry) Lookup(name string) (*Package, error) {
+	v, ok := r.pkgs.Load(name)
+	if !ok {
+		return r.lookupProvided(name)
+	}
+	return v.(*Package), nil
+}
@@ -0,0 +1,79 @@
+package pkgsyms
+
+// Replace atomically replaces the symbols in the set with the symbols in
+// with, so that lookups see either all of the old symbols or all of the new
+// ones.  Symbols that are only in the old set are removed, symbols that are
+// only in with are added, and symbols in both are replaced, which Watch
+// reports as a ChangeRemove followed by a ChangeAdd unless the symbols are
+// Equal.  Ranges over the set that already started keep seeing the old
+// symbols.
+func (syms *Symbols) Replace(with *Symbols) {
+	var ss []Symbol
+	if with != nil {
+		// Take the snapshot first so that replacing a set with
+		// itself doesn't deadlock.
+		ss = with.snapshot()
+	}
+	syms.mutex.Lock()
+	defer syms.mutex.Unlock()
+	syms.loadManifests()
+	names := make(map[string]int, len(ss))
+	slice := make([]Symbol, 0, len(ss))
+	for _, s := range ss {
+		if _, ok := names[s.Name()]; !ok {
+			names[s.Name()] = len(slice)
+			slice = append(slice, s)
+		}
+	}
+	// Symbols that are replaced by Equal ones don't change.
+	unchanged := make(map[string]bool)
+	for _, s := range syms.slice {
+		if i, ok := names[s.Name()]; ok && Equal(s, slice[i]) {
+			unchanged[s.Name()] = true
+			continue
+		}
+		logEvent(EventRemove, syms.pkg, s.Name())
+		syms.notify(ChangeRemove, s)
+	}
+	syms.names, syms.slice = names, slice
+	syms.sorted = nil
+	for _, s := range slice {
+		if unchanged[s.Name()] {
+			continue
+		}
+		logEvent(EventAdd, syms.pkg, s.Name())
+		syms.notify(ChangeAdd, s)
+	}
+}
+
+// ReplacePackage replaces the symbols of the registered package with the
+// given name with newSyms (see (*Symbols).Replace), for long-running hosts
+// that rebuild and reload plugins on the fly:
+//
+//	// After loading the rebuilt plugin into newSyms:
+//	if err := pkgsyms.ReplacePackage("example.com/plugin", newSyms); err != nil {
+//		// ...
+//	}
+//
+// Watchers of the package are notified of every change.  A nil newSyms
+// removes every symbol.  It returns NotFound if the package isn't
+// registered; Providers aren't consulted.
+func ReplacePackage(path string, newSyms *Symbols) error {
+	return global.ReplacePackage(path, newSyms)
+}
+
+// ReplacePackage replaces the symbols of a package in the registry.  See the
+// package-level ReplacePackage.
+func (r *Registry) ReplacePackage(path string, newSyms *Symbols) error {
+	v, ok := r.pkgs.Load(path)
+	if !ok {
+		return NotFound{Pkg: path}
+	}
+	p := v.(*Package)
+	p.Replace(newSyms)
+	// The new symbols weren't added with AddConditional.
+	p.conditionalMu.Lock()
+	p.conditional = nil
+	p.conditionalMu.Unlock()
+	return nil
+}
@@ -0,0 +1,363 @@
+// Package replsyms is a line-based console for inspecting and using a
+// registry: listing packages, describing symbols, reading and setting
+// variables and calling functions with literal arguments.  Link it into a
+// binary with generated registries and run it on a debug port or terminal:
+//
+//	replsyms.Run(replsyms.Local(nil), os.Stdin, os.Stdout)
+//
+// The pkgsyms command's repl subcommand runs it against a process that
+// serves its registry with httpsyms.
+package replsyms
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// Info describes a symbol.
+type Info struct {
+	Package   string
+	Name      string
+	Kind      string
+	Signature string
+	Doc       string
+
+	// Value is the JSON encoding of a constant's or variable's value, if
+	// it's known.
+	Value json.RawMessage
+}
+
+// Registry is the registry a console works with.  Arguments, results and
+// values are encoded as JSON.
+type Registry interface {
+	Packages() ([]string, error)
+	Symbols(pkg string) ([]Info, error)
+	Symbol(pkg, name string) (Info, error)
+	Set(pkg, name string, value []byte) error
+	Call(pkg, name string, args []byte) ([]byte, error)
+}
+
+// Local gets the Registry of reg in this process, or of the global registry
+// if reg is nil.
+func Local(reg *pkgsyms.Registry) Registry { return local{reg} }
+
+type local struct {
+	reg *pkgsyms.Registry
+}
+
+func (l local) lookup(pkg string) (*pkgsyms.Package, error) {
+	if l.reg == nil {
+		return pkgsyms.Lookup(pkg)
+	}
+	return l.reg.Lookup(pkg)
+}
+
+func (l local) Packages() ([]string, error) {
+	ps := pkgsyms.Packages()
+	if l.reg != nil {
+		ps = l.reg.Packages()
+	}
+	names := make([]string, len(ps))
+	for i, p := range ps {
+		names[i] = p.Name
+	}
+	return names, nil
+}
+
+func (l local) Symbols(pkg string) ([]Info, error) {
+	p, err := l.lookup(pkg)
+	if err != nil {
+		return nil, err
+	}
+	var infos []Info
+	p.RangeAs(nil, func(s pkgsyms.Symbol) bool {
+		infos = append(infos, infoOf(p.Name, s))
+		return true
+	})
+	return infos, nil
+}
+
+func (l local) Symbol(pkg, name string) (Info, error) {
+	p, err := l.lookup(pkg)
+	if err != nil {
+		return Info{}, err
+	}
+	s, err := p.Lookup(name)
+	if err != nil {
+		return Info{}, err
+	}
+	return infoOf(p.Name, s), nil
+}
+
+func (l local) Set(pkg, name string, value []byte) error {
+	p, err := l.lookup(pkg)
+	if err != nil {
+		return err
+	}
+	v, err := p.LookupVar(name)
+	if err != nil {
+		return err
+	}
+	pv := reflect.New(v.Type())
+	if err := json.Unmarshal(value, pv.Interface()); err != nil {
+		return fmt.Errorf("%s: %w", pkgsyms.ID(pkg, name), err)
+	}
+	return v.SetE(pv.Elem().Interface())
+}
+
+func (l local) Call(pkg, name string, args []byte) ([]byte, error) {
+	p, err := l.lookup(pkg)
+	if err != nil {
+		return nil, err
+	}
+	f, err := p.LookupFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.CallJSON(args)
+}
+
+func infoOf(pkg string, s pkgsyms.Symbol) Info {
+	info := Info{
+		Package: pkg,
+		Name:    s.Name(),
+		Kind:    pkgsyms.KindOf(s).String(),
+		Doc:     pkgsyms.Doc(s),
+	}
+	switch s := s.(type) {
+	case pkgsyms.Type:
+		info.Signature = s.Underlying()
+	case pkgsyms.Var:
+		info.Signature = s.Type().String()
+	case pkgsyms.Generic:
+		info.Signature = s.String()
+	case pkgsyms.Constraint:
+		info.Signature = s.Get().(string)
+	default:
+		if v := s.Get(); v != nil {
+			info.Signature = reflect.TypeOf(v).String()
+		}
+	}
+	switch s.(type) {
+	case pkgsyms.Const, pkgsyms.Var:
+		if v, err := pkgsyms.GetValue(s); err == nil {
+			info.Value, _ = json.Marshal(v)
+		}
+	}
+	return info
+}
+
+const help = `Commands:
+	packages               list the packages
+	use PKG                look up symbols without a package in PKG
+	ls [PKG]               list the symbols of PKG or the used package
+	show SYM               describe a symbol
+	get SYM                print the value of a constant or variable
+	set SYM VALUE          set a variable
+	call SYM [ARG...]      call a function
+	help                   print this help
+	quit                   exit
+Symbols are NAME in the used package, PKG.NAME or "PKG".NAME.  Values and
+arguments are JSON literals like 42, "text" or ["a", "b"].
+`
+
+// Console is the state of a console session.
+type Console struct {
+	reg Registry
+	out io.Writer
+	pkg string
+}
+
+// NewConsole creates a console of reg that writes to out.
+func NewConsole(reg Registry, out io.Writer) *Console {
+	return &Console{reg: reg, out: out}
+}
+
+// errQuit is returned by Exec for the quit command.
+var errQuit = errors.New("quit")
+
+// Run reads commands from in and writes their output and a "> " prompt to out
+// until in ends or the quit command.  Errors of commands are written to out
+// and don't stop the console.
+func Run(reg Registry, in io.Reader, out io.Writer) error {
+	c := NewConsole(reg, out)
+	sc := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "> ")
+		if !sc.Scan() {
+			fmt.Fprintln(out)
+			return sc.Err()
+		}
+		switch err := c.Exec(sc.Text()); err {
+		case nil:
+		case errQuit:
+			return nil
+		default:
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+}
+
+// Exec runs one command line.
+func (c *Console) Exec(line string) error {
+	cmd, rest := cut(strings.TrimSpace(line))
+	switch cmd {
+	case "":
+		return nil
+	case "help", "?":
+		_, err := io.WriteString(c.out, help)
+		return err
+	case "quit", "exit":
+		return errQuit
+	case "packages":
+		names, err := c.reg.Packages()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Fprintln(c.out, name)
+		}
+		return nil
+	case "use":
+		if rest == "" {
+			return errors.New("use requires a package")
+		}
+		if _, err := c.reg.Symbols(rest); err != nil {
+			return err
+		}
+		c.pkg = rest
+		return nil
+	case "ls":
+		pkg := rest
+		if pkg == "" {
+			pkg = c.pkg
+		}
+		if pkg == "" {
+			return errors.New("ls requires a package without use")
+		}
+		infos, err := c.reg.Symbols(pkg)
+		if err != nil {
+			return err
+		}
+		for _, info := range infos {
+			fmt.Fprintf(c.out, "%s %s %s\n", strings.ToLower(info.Kind), info.Name, info.Signature)
+		}
+		return nil
+	}
+	ref, args := cut(rest)
+	if ref == "" {
+		return fmt.Errorf("unknown command %q; try help", cmd)
+	}
+	pkg, name, err := c.resolve(ref)
+	if err != nil {
+		return err
+	}
+	switch cmd {
+	case "show", "get":
+		info, err := c.reg.Symbol(pkg, name)
+		if err != nil {
+			return err
+		}
+		if cmd == "get" {
+			if info.Value == nil {
+				return fmt.Errorf("%s has no value", pkgsyms.ID(pkg, name))
+			}
+			return c.printJSON(info.Value)
+		}
+		fmt.Fprintf(c.out, "%s %s %s\n", strings.ToLower(info.Kind), info.Name, info.Signature)
+		if info.Value != nil {
+			fmt.Fprintf(c.out, "= %s\n", info.Value)
+		}
+		if info.Doc != "" {
+			fmt.Fprintf(c.out, "\n%s\n", info.Doc)
+		}
+		return nil
+	case "set":
+		values, err := literals(args)
+		if err != nil {
+			return err
+		}
+		if len(values) != 1 {
+			return errors.New("set requires one value")
+		}
+		return c.reg.Set(pkg, name, values[0])
+	case "call":
+		values, err := literals(args)
+		if err != nil {
+			return err
+		}
+		if values == nil {
+			values = []json.RawMessage{}
+		}
+		data, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		results, err := c.reg.Call(pkg, name, data)
+		if err != nil {
+			return err
+		}
+		return c.printJSON(results)
+	}
+	return fmt.Errorf("unknown command %q; try help", cmd)
+}
+
+// resolve a symbol reference into its package and name.
+func (c *Console) resolve(ref string) (pkg, name string, err error) {
+	if strings.HasPrefix(ref, `"`) {
+		if pkg, name, err = pkgsyms.ParseID(ref); err == nil && name == "" {
+			err = fmt.Errorf("%s names a package, not a symbol", ref)
+		}
+		return
+	}
+	slash := strings.LastIndex(ref, "/")
+	if dot := strings.LastIndex(ref, "."); dot > slash {
+		return ref[:dot], ref[dot+1:], nil
+	}
+	if c.pkg == "" {
+		return "", "", fmt.Errorf("%s needs a package without use", ref)
+	}
+	return c.pkg, ref, nil
+}
+
+// printJSON prints JSON data on one line.
+func (c *Console) printJSON(data []byte) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(c.out)
+	return err
+}
+
+// cut splits off the first word of s.
+func cut(s string) (word, rest string) {
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i+1:])
+	}
+	return s, ""
+}
+
+// literals parses the JSON literals separated by whitespace in s.
+func literals(s string) ([]json.RawMessage, error) {
+	var values []json.RawMessage
+	dec := json.NewDecoder(strings.NewReader(s))
+	for {
+		var v json.RawMessage
+		if err := dec.Decode(&v); err == io.EOF {
+			return values, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("arguments must be JSON literals: %w", err)
+		}
+		values = append(values, v)
+	}
+}
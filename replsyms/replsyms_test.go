@@ -0,0 +1,54 @@
+package replsyms_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/replsyms"
+)
+
+func TestRun(t *testing.T) {
+	const name = "example.com/repl"
+	reg := pkgsyms.NewRegistry()
+	level := "info"
+	reg.Of(name).Add(
+		pkgsyms.MakeFunc("Join", strings.Join).WithDoc("Join joins strings."),
+		pkgsyms.MakeVar("Level", &level),
+	)
+	in := strings.NewReader(`packages
+use example.com/repl
+ls
+show Join
+call Join ["a", "b"] "-"
+set Level "debug"
+get "example.com/repl".Level
+get example.com/repl.Level
+call Missing
+bogus x
+quit
+ls
+`)
+	var out strings.Builder
+	if err := replsyms.Run(replsyms.Local(reg), in, &out); err != nil {
+		t.Fatal(err)
+	}
+	want := `> example.com/repl
+> > func Join func([]string, string) string
+var Level string
+> func Join func([]string, string) string
+
+Join joins strings.
+> ["a-b"]
+> > "debug"
+> "debug"
+> error: "example.com/repl".Missing not found
+> error: unknown command "bogus"; try help
+> `
+	if out.String() != want {
+		t.Fatalf("expected:\n%s\nbut got:\n%s", want, out.String())
+	}
+	if level != "debug" {
+		t.Fatalf("expected Level to be set to debug, not %q", level)
+	}
+}
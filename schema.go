@@ -0,0 +1,123 @@
+package pkgsyms
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Field describes a field of a struct Type's schema.
+type Field struct {
+	// Name of the field in input, from its json tag if it has one.
+	Name string
+
+	// GoName is the name of the struct field.
+	GoName string
+
+	// Kind of the field's type with pointers dereferenced, named like
+	// reflect.Kind, e.g. "string", "int64", "slice" or "struct".
+	Kind string
+
+	// Type of the field as written in Go, like "[]string".
+	Type string
+
+	// Required is set for fields tagged with validate:"required" or
+	// required:"true".
+	Required bool
+}
+
+// Schema gets a copy of the fields recorded by WithSchema.
+func (t Type) Schema() []Field {
+	return append([]Field(nil), t.schema...)
+}
+
+// WithSchema returns a copy of the type with its schema set to fields.
+func (t Type) WithSchema(fields ...Field) Type {
+	t.schema = fields
+	return t
+}
+
+// Validate checks input, like a JSON object decoded into a map, against the
+// type's schema before it's used to create a value of the type.  Required
+// fields must be present and not nil, values must be of a kind that can be
+// decoded into their fields and there must be no unknown fields.  Numbers of
+// any kind are accepted for fields of any numeric kind, and fields that decode
+// themselves, like time.Time, []byte and encoding.TextUnmarshaler or
+// json.Unmarshaler implementations, accept input of any kind.  All of the
+// problems found are returned as SchemaErrors.
+func (t Type) Validate(input map[string]interface{}) error {
+	var errs SchemaErrors
+	known := make(map[string]bool, len(t.schema))
+	for _, f := range t.schema {
+		known[f.Name] = true
+		v, ok := input[f.Name]
+		switch {
+		case !ok || v == nil:
+			if f.Required {
+				errs = append(errs, SchemaError{t.name, f.Name, "is required"})
+			}
+		case f.Kind != reflect.Interface.String() && !t.decodesItself(f) &&
+			kindClass(reflect.TypeOf(v).Kind().String()) != kindClass(f.Kind):
+			errs = append(errs, SchemaError{t.name, f.Name, fmt.Sprintf(
+				"expected %s, not %T", f.Type, v)})
+		}
+	}
+	var unknown []string
+	for name := range input {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, SchemaError{t.name, name, "is unknown"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// decodesItself reports whether field f of the type decodes from input of
+// other kinds than its own, like time.Time and []byte from strings and types
+// implementing encoding.TextUnmarshaler or json.Unmarshaler from anything.
+func (t Type) decodesItself(f Field) bool {
+	st := t.rtyp
+	for st != nil && st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st == nil || st.Kind() != reflect.Struct {
+		return false
+	}
+	sf, ok := st.FieldByName(f.GoName)
+	if !ok {
+		return false
+	}
+	ft := sf.Type
+	for ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	switch {
+	case ft == timeType:
+		return true
+	case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Uint8:
+		return true
+	}
+	pt := reflect.PtrTo(ft)
+	return pt.Implements(textUnmarshalerType) || pt.Implements(jsonUnmarshalerType)
+}
+
+// kindClass groups the kinds that decode from the same kinds of input.
+func kindClass(kind string) string {
+	switch kind {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"float32", "float64":
+		return "number"
+	case "slice", "array":
+		return "list"
+	case "map", "struct":
+		return "object"
+	}
+	return kind
+}
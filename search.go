@@ -0,0 +1,38 @@
+package pkgsyms
+
+import (
+	"path"
+	"strings"
+)
+
+// ResolvedSymbol is a Symbol along with the Package it's defined in.
+type ResolvedSymbol struct {
+	Package *Package
+	Symbol  Symbol
+}
+
+// Search every registered package for symbols whose names match the query.
+// If the query contains any of the glob metacharacters *, ? or [, symbol
+// names must match it as a path.Match pattern.  Otherwise, names match if
+// they contain the query, ignoring case.  Results are ordered by package name
+// and then by the order the symbols were added in.
+func Search(query string) []ResolvedSymbol {
+	match := func(name string) bool {
+		return strings.Contains(strings.ToLower(name), strings.ToLower(query))
+	}
+	if strings.ContainsAny(query, "*?[") {
+		match = func(name string) bool {
+			ok, _ := path.Match(query, name)
+			return ok
+		}
+	}
+	var res []ResolvedSymbol
+	for _, p := range Packages() {
+		for _, s := range p.snapshot() {
+			if match(s.Name()) {
+				res = append(res, ResolvedSymbol{Package: p, Symbol: s})
+			}
+		}
+	}
+	return res
+}
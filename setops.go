@@ -0,0 +1,79 @@
+package pkgsyms
+
+// snapshot gets the symbols currently in the set.
+func (syms *Symbols) snapshot() []Symbol {
+	syms.mutex.Lock()
+	defer syms.mutex.Unlock()
+	syms.loadManifests()
+	return syms.slice
+}
+
+// filtered creates a new set of the symbols in syms for which keep returns
+// true.
+func (syms *Symbols) filtered(keep func(s Symbol) bool) *Symbols {
+	ss := syms.snapshot()
+	res := MakeSymbols(len(ss))
+	for _, s := range ss {
+		if keep(s) {
+			res.add(s)
+		}
+	}
+	return &res
+}
+
+// Intersect creates a new set of the symbols in syms whose names are also
+// defined in other.
+func (syms *Symbols) Intersect(other *Symbols) *Symbols {
+	return syms.filtered(func(s Symbol) bool {
+		_, err := other.Lookup(s.Name())
+		return err == nil
+	})
+}
+
+// Subtract creates a new set of the symbols in syms whose names are not
+// defined in other.
+func (syms *Symbols) Subtract(other *Symbols) *Symbols {
+	return syms.filtered(func(s Symbol) bool {
+		_, err := other.Lookup(s.Name())
+		return err != nil
+	})
+}
+
+// Len gets the number of symbols in the set.
+func (syms *Symbols) Len() int { return len(syms.snapshot()) }
+
+// View is a read-only set of symbols.  Hosts embedding untrusted code can hand
+// out a View as a capability:  the code can look up the symbols in it but
+// can't add to it.  Note that a View doesn't stop Var symbols from being Set.
+type View struct {
+	syms *Symbols
+}
+
+// ReadOnlyView creates a View of the symbols currently in the set.  Symbols
+// added to the set afterwards aren't visible through the View.
+func (syms *Symbols) ReadOnlyView() View {
+	return View{syms: syms.filtered(func(Symbol) bool { return true })}
+}
+
+// Lookup a symbol in the view.
+func (v View) Lookup(name string) (Symbol, error) {
+	if v.syms == nil {
+		return nil, NotFound{Sym: name}
+	}
+	return v.syms.Lookup(name)
+}
+
+// Range calls f with each symbol in the view until f returns false.
+func (v View) Range(f func(s Symbol) bool) {
+	if v.syms != nil {
+		v.syms.Range(f)
+	}
+}
+
+// Len gets the number of symbols in the view.
+func (v View) Len() int {
+	if v.syms == nil {
+		return 0
+	}
+	return v.syms.Len()
+}
@@ -0,0 +1,429 @@
+// Package starlarksyms exposes the symbols registered in a pkgsyms.Package to
+// Starlark scripts so that embedding go.starlark.net over a generated registry
+// doesn't need any per-package glue.
+package starlarksyms
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+
+	"github.com/skillian/pkgsyms"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// StringDict converts the Consts, Funcs and Vars in p into a
+// starlark.StringDict that can be used as a script's predeclared names.
+// Types are skipped because Starlark has no way to use them, and so are the
+// symbols that the package's Authorizer denies.  Other symbols are converted
+// if they implement pkgsyms.GetterE, and their errors are returned.
+//
+// Values are converted with ToValue, so those without a Starlark equivalent
+// are wrapped in a GoValue rather than failing the package.
+//
+// Vars are converted with the value they hold when StringDict is called;
+// later changes to the variable aren't seen by the script.  Callable Vars
+// are the exception: they become builtins that call whatever function the
+// variable holds at the time of the call.
+func StringDict(p *pkgsyms.Package) (starlark.StringDict, error) {
+	d := make(starlark.StringDict)
+	var err error
+	p.RangeAs(nil, func(s pkgsyms.Symbol) bool {
+		var v starlark.Value
+		f, isFunc := pkgsyms.AsFunc(s)
+		switch s := s.(type) {
+		case pkgsyms.Func:
+			v, err = Builtin(f)
+		case pkgsyms.Var:
+			if isFunc {
+				v, err = Builtin(f)
+			} else {
+				v, err = ToValue(s.Get())
+			}
+		case pkgsyms.Const:
+			v, err = ToValue(s.Get())
+		case pkgsyms.GetterE:
+			var val interface{}
+			if val, err = s.GetE(); err == nil {
+				v, err = ToValue(val)
+			}
+		default:
+			return true
+		}
+		if err != nil {
+			err = fmt.Errorf(
+				"package %q: symbol %q: %w", p.Name, s.Name(), err)
+			return false
+		}
+		d[s.Name()] = v
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Module is like StringDict but wraps the members in a Starlark module named
+// after the last element of the package's path.
+func Module(p *pkgsyms.Package) (*starlarkstruct.Module, error) {
+	d, err := StringDict(p)
+	if err != nil {
+		return nil, err
+	}
+	return &starlarkstruct.Module{Name: path.Base(p.Name), Members: d}, nil
+}
+
+// Builtin wraps a Func symbol in a Starlark builtin function.  Arguments are
+// converted to the function's parameter types with FromValue and results are
+// converted with ToValue.  A function with no results returns None, one
+// result is returned as-is and more than one result is returned as a tuple.
+// If the function's last result is an error, a non-nil error fails the call
+// and the error itself isn't included in the results.  A panic in the
+// function fails the call with a pkgsyms.InvokeError.
+func Builtin(f pkgsyms.Func) (*starlark.Builtin, error) {
+	fv := reflect.ValueOf(f.Get())
+	if !fv.IsValid() || fv.Kind() != reflect.Func {
+		return nil, fmt.Errorf("expected function, not %T", f.Get())
+	}
+	ft := fv.Type()
+	return starlark.NewBuiltin(f.Name(), func(
+		thread *starlark.Thread, b *starlark.Builtin,
+		args starlark.Tuple, kwargs []starlark.Tuple,
+	) (starlark.Value, error) {
+		if len(kwargs) > 0 {
+			return nil, fmt.Errorf(
+				"%s: keyword arguments are not supported", b.Name())
+		}
+		in, err := callArgs(ft, args)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.Name(), err)
+		}
+		vals := make([]interface{}, len(in))
+		for i, v := range in {
+			vals[i] = v.Interface()
+		}
+		out, err := f.Call(vals...)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(out); n > 0 && ft.Out(n-1) == errorType {
+			if err, _ := out[n-1].(error); err != nil {
+				return nil, fmt.Errorf("%s: %w", b.Name(), err)
+			}
+			out = out[:n-1]
+		}
+		switch len(out) {
+		case 0:
+			return starlark.None, nil
+		case 1:
+			return ToValue(out[0])
+		}
+		t := make(starlark.Tuple, len(out))
+		for i, o := range out {
+			if t[i], err = ToValue(o); err != nil {
+				return nil, fmt.Errorf(
+					"%s: result %d: %w", b.Name(), i, err)
+			}
+		}
+		return t, nil
+	}), nil
+}
+
+func callArgs(ft reflect.Type, args starlark.Tuple) ([]reflect.Value, error) {
+	numIn := ft.NumIn()
+	if ft.IsVariadic() {
+		if len(args) < numIn-1 {
+			return nil, fmt.Errorf(
+				"expected at least %d arguments, not %d",
+				numIn-1, len(args))
+		}
+	} else if len(args) != numIn {
+		return nil, fmt.Errorf(
+			"expected %d arguments, not %d", numIn, len(args))
+	}
+	in := make([]reflect.Value, len(args))
+	for i, a := range args {
+		var t reflect.Type
+		if ft.IsVariadic() && i >= numIn-1 {
+			t = ft.In(numIn - 1).Elem()
+		} else {
+			t = ft.In(i)
+		}
+		v, err := FromValue(a, t)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i, err)
+		}
+		in[i] = v
+	}
+	return in, nil
+}
+
+// ToValue converts a Go value into a Starlark value.  Booleans, numbers,
+// strings, byte slices, slices, arrays, maps and functions are converted and
+// other values, like structs, are wrapped in a GoValue.
+func ToValue(v interface{}) (starlark.Value, error) {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case starlark.Value:
+		return v, nil
+	case []byte:
+		return starlark.Bytes(v), nil
+	}
+	return toValue(reflect.ValueOf(v))
+}
+
+func toValue(rv reflect.Value) (starlark.Value, error) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return starlark.Bool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return starlark.MakeInt64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return starlark.MakeUint64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return starlark.Float(rv.Float()), nil
+	case reflect.String:
+		return starlark.String(rv.String()), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return starlark.None, nil
+		}
+		elems := make([]starlark.Value, rv.Len())
+		for i := range elems {
+			e, err := toValue(rv.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			elems[i] = e
+		}
+		return starlark.NewList(elems), nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return starlark.None, nil
+		}
+		d := starlark.NewDict(rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k, err := toValue(iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			v, err := toValue(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("key %v: %w", k, err)
+			}
+			if err = d.SetKey(k, v); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	case reflect.Func:
+		if rv.IsNil() {
+			return starlark.None, nil
+		}
+		return Builtin(pkgsyms.MakeFunc(rv.Type().String(), rv.Interface()))
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return starlark.None, nil
+		}
+		if rv.Kind() == reflect.Ptr && rv.Elem().Kind() == reflect.Struct {
+			// Keep the pointer so that it's the same struct that's
+			// passed back to Go.
+			break
+		}
+		return toValue(rv.Elem())
+	case reflect.Invalid:
+		return starlark.None, nil
+	}
+	if !rv.CanInterface() {
+		return nil, fmt.Errorf("cannot convert %v to a Starlark value", rv.Type())
+	}
+	return GoValue{v: rv.Interface()}, nil
+}
+
+// GoValue is a Starlark value holding a Go value that ToValue has no
+// Starlark equivalent for, like a struct or a pointer to one.  Scripts can
+// print it and pass it back to Go functions, which get the original value.
+type GoValue struct{ v interface{} }
+
+var _ starlark.Value = GoValue{}
+
+// Interface gets the Go value.
+func (g GoValue) Interface() interface{} { return g.v }
+
+func (g GoValue) String() string        { return fmt.Sprint(g.v) }
+func (g GoValue) Type() string          { return reflect.TypeOf(g.v).String() }
+func (g GoValue) Freeze()               {}
+func (g GoValue) Truth() starlark.Bool  { return starlark.True }
+func (g GoValue) Hash() (uint32, error) { return 0, fmt.Errorf("unhashable type: %s", g.Type()) }
+
+// FromValue converts a Starlark value into a Go value of type t.
+func FromValue(v starlark.Value, t reflect.Type) (reflect.Value, error) {
+	if v == starlark.None {
+		switch t.Kind() {
+		case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+			return reflect.Zero(t), nil
+		}
+	}
+	rv := reflect.New(t).Elem()
+	if g, ok := v.(GoValue); ok {
+		gv := reflect.ValueOf(g.v)
+		if !gv.Type().AssignableTo(t) {
+			return rv, fmt.Errorf("cannot convert %s to %v", g.Type(), t)
+		}
+		rv.Set(gv)
+		return rv, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := v.(starlark.Bool)
+		if !ok {
+			break
+		}
+		rv.SetBool(bool(b))
+		return rv, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := v.(starlark.Int)
+		if !ok {
+			break
+		}
+		n, ok := i.Int64()
+		if !ok || rv.OverflowInt(n) {
+			return rv, fmt.Errorf("%v overflows %v", v, t)
+		}
+		rv.SetInt(n)
+		return rv, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := v.(starlark.Int)
+		if !ok {
+			break
+		}
+		n, ok := i.Uint64()
+		if !ok || rv.OverflowUint(n) {
+			return rv, fmt.Errorf("%v overflows %v", v, t)
+		}
+		rv.SetUint(n)
+		return rv, nil
+	case reflect.Float32, reflect.Float64:
+		f, ok := starlark.AsFloat(v)
+		if !ok {
+			break
+		}
+		rv.SetFloat(f)
+		return rv, nil
+	case reflect.String:
+		s, ok := starlark.AsString(v)
+		if !ok {
+			break
+		}
+		rv.SetString(s)
+		return rv, nil
+	case reflect.Slice:
+		if b, ok := v.(starlark.Bytes); ok && t.Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes([]byte(b))
+			return rv, nil
+		}
+		seq, ok := v.(starlark.Indexable)
+		if !ok {
+			break
+		}
+		rv.Set(reflect.MakeSlice(t, seq.Len(), seq.Len()))
+		for i := 0; i < seq.Len(); i++ {
+			e, err := FromValue(seq.Index(i), t.Elem())
+			if err != nil {
+				return rv, fmt.Errorf("index %d: %w", i, err)
+			}
+			rv.Index(i).Set(e)
+		}
+		return rv, nil
+	case reflect.Map:
+		d, ok := v.(*starlark.Dict)
+		if !ok {
+			break
+		}
+		rv.Set(reflect.MakeMapWithSize(t, d.Len()))
+		for _, kv := range d.Items() {
+			k, err := FromValue(kv[0], t.Key())
+			if err != nil {
+				return rv, err
+			}
+			e, err := FromValue(kv[1], t.Elem())
+			if err != nil {
+				return rv, fmt.Errorf("key %v: %w", kv[0], err)
+			}
+			rv.SetMapIndex(k, e)
+		}
+		return rv, nil
+	case reflect.Interface:
+		g, err := toGo(v)
+		if err != nil {
+			return rv, err
+		}
+		if g == nil {
+			return rv, nil
+		}
+		gv := reflect.ValueOf(g)
+		if !gv.Type().AssignableTo(t) {
+			break
+		}
+		rv.Set(gv)
+		return rv, nil
+	}
+	return rv, fmt.Errorf("cannot convert %s to %v", v.Type(), t)
+}
+
+// toGo converts a Starlark value to its natural Go representation for when
+// the destination type is an interface.
+func toGo(v starlark.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(v), nil
+	case starlark.Int:
+		if n, ok := v.Int64(); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("%v overflows int64", v)
+	case starlark.Float:
+		return float64(v), nil
+	case starlark.String:
+		return string(v), nil
+	case starlark.Bytes:
+		return []byte(v), nil
+	case GoValue:
+		return v.v, nil
+	case starlark.Indexable:
+		s := make([]interface{}, v.Len())
+		for i := range s {
+			e, err := toGo(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			s[i] = e
+		}
+		return s, nil
+	case *starlark.Dict:
+		m := make(map[string]interface{}, v.Len())
+		for _, kv := range v.Items() {
+			k, ok := starlark.AsString(kv[0])
+			if !ok {
+				return nil, fmt.Errorf(
+					"cannot convert %s dict key to string",
+					kv[0].Type())
+			}
+			e, err := toGo(kv[1])
+			if err != nil {
+				return nil, err
+			}
+			m[k] = e
+		}
+		return m, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,101 @@
+package starlarksyms_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/starlarksyms"
+	"go.starlark.net/starlark"
+)
+
+var greeting = "hello"
+
+func TestStringDict(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/starlarksyms_test")
+	p.Add(
+		pkgsyms.MakeConst("Answer", 42),
+		pkgsyms.MakeVar("Greeting", &greeting),
+		pkgsyms.MakeFunc("Join", strings.Join),
+	)
+	predeclared, err := starlarksyms.StringDict(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globals, err := starlark.ExecFile(
+		new(starlark.Thread), "test.star",
+		`x = Join([Greeting, str(Answer)], " ")`, predeclared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := starlark.AsString(globals["x"]); s != "hello 42" {
+		t.Fatalf("expected %q but got %v", "hello 42", globals["x"])
+	}
+}
+
+type point struct{ X, Y int }
+
+var (
+	origin = point{}
+	cursor = &point{X: 1, Y: 2}
+)
+
+func TestStringDictGoValue(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/starlarksyms_test/govalue")
+	p.Add(
+		pkgsyms.MakeVar("Origin", &origin),
+		pkgsyms.MakeVar("Cursor", &cursor),
+		pkgsyms.MakeFunc("Sum", func(p *point) int { return p.X + p.Y }),
+		pkgsyms.MakeFunc("IsOrigin", func(p point) bool { return p == origin }),
+	)
+	predeclared, err := starlarksyms.StringDict(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globals, err := starlark.ExecFile(
+		new(starlark.Thread), "test.star",
+		"x = Sum(Cursor)\ny = IsOrigin(Origin)", predeclared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := starlark.AsInt32(globals["x"]); x != 3 || globals["y"] != starlark.True {
+		t.Fatalf("expected 3 and True but got %v and %v", globals["x"], globals["y"])
+	}
+}
+
+func TestBuiltinPanic(t *testing.T) {
+	b, err := starlarksyms.Builtin(pkgsyms.MakeFunc("Panic", func() { panic("oops") }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = starlark.Call(new(starlark.Thread), b, nil, nil)
+	var ie pkgsyms.InvokeError
+	if !errors.As(err, &ie) {
+		t.Fatalf("expected %T but got %v", ie, err)
+	}
+}
+
+func TestStringDictAuthorizer(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/starlarksyms_test/authorizer")
+	p.Add(
+		pkgsyms.MakeConst("Public", 1),
+		pkgsyms.MakeConst("Secret", 2),
+	)
+	p.SetAuthorizer(func(sym pkgsyms.Symbol, caller interface{}) error {
+		if sym.Name() == "Secret" {
+			return errors.New("secret")
+		}
+		return nil
+	})
+	d, err := starlarksyms.StringDict(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d["Secret"]; ok {
+		t.Fatal("expected the denied symbol to be left out")
+	}
+	if _, ok := d["Public"]; !ok {
+		t.Fatal("expected the allowed symbol to be converted")
+	}
+}
@@ -10,17 +10,28 @@
 // run a go generate command that essentially subverts the compiler's ability to
 // eliminate dead code by making all exported names accessible outside of
 // the package.
+//
+// The registry itself (adding, looking up and ranging over symbols) avoids
+// the parts of the reflect package that TinyGo doesn't implement, so it can be
+// used from TinyGo and js/wasm programs.  Helpers that need full reflection,
+// like RegisterGob, are unavailable or degraded when built with TinyGo.
 package pkgsyms
 
 import (
+	"context"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 //go:generate pkgsyms -output=testsyms_test.go
 var (
-	// pkgs is a mapping of package names to their *Packages.
-	pkgs sync.Map
+	// global is the registry of Of and Lookup.
+	global = NewRegistry()
 )
 
 // Package defines a package.  It includes the package name and its exported
@@ -31,31 +42,128 @@ type Package struct {
 
 	// Symbols exported by the package
 	Symbols
+
+	readyInit sync.Once
+	readyOnce sync.Once
+	ready     chan struct{}
+
+	// provided is the number of registered Providers that have been
+	// consulted for this package's symbols.
+	providedMu sync.Mutex
+	provided   int
+
+	// docMu also guards the generator's version set by SetGenerator.
+	docMu      sync.Mutex
+	doc        string
+	genVersion string
+	genAPI     int
+
+	// conditional are the names of the symbols added with AddConditional.
+	conditionalMu sync.Mutex
+	conditional   map[string]bool
+
+	// authorizer holds the Authorizer set with SetAuthorizer.
+	authorizer atomic.Value
+
+	// usage counts lookups and calls while TrackUsage is on.
+	usageMu sync.Mutex
+	usage   *usageTable
+
+	// registry is the Registry the package is in.
+	registry *Registry
 }
 
 // Of gets the Package definition of the package with the given name.
-func Of(name string) *Package {
-	v, loaded := pkgs.Load(name)
-	if loaded {
-		return v.(*Package)
+//
+// Of is safe to call concurrently, including from the init functions of
+// several generated files that register symbols into the same package:  Every
+// caller of Of with the same name observes the same *Package.
+func Of(name string) *Package { return global.Of(name) }
+
+// WaitReady waits for the package with the given name to be marked ready and
+// then returns it.  This is meant for consumers that may run before all of a
+// package's registrations complete, like lazily loaded plugins.
+func WaitReady(name string) *Package {
+	p := Of(name)
+	<-p.Ready()
+	return p
+}
+
+// MarkReady marks the package's registrations as complete.  Files generated
+// by the pkgsyms command call MarkReady at the end of their init functions.
+// Marking a package ready more than once has no effect.
+func (p *Package) MarkReady() {
+	ch := p.readyChan()
+	p.readyOnce.Do(func() {
+		close(ch)
+		logEvent(EventReady, p.Name, "")
+	})
+}
+
+// Ready returns a channel that's closed when the package is marked ready.
+func (p *Package) Ready() <-chan struct{} { return p.readyChan() }
+
+func (p *Package) readyChan() chan struct{} {
+	p.readyInit.Do(func() { p.ready = make(chan struct{}) })
+	return p.ready
+}
+
+// Doc gets the package's doc comment, if one was recorded.
+func (p *Package) Doc() string {
+	p.docMu.Lock()
+	defer p.docMu.Unlock()
+	return p.doc
+}
+
+// SetDoc sets the package's doc comment.  Files generated by the pkgsyms
+// command with -docs set it to the package clause's doc comment.
+func (p *Package) SetDoc(doc string) {
+	p.docMu.Lock()
+	defer p.docMu.Unlock()
+	p.doc = doc
+}
+
+// AddConditional adds symbols that the pkgsyms command can't account for
+// when it generates the package's checksum, like symbols that are only
+// declared when some build constraints are satisfied or that are computed
+// by the package's extraSymbols function.  They're added like with Add, but
+// they're left out of the package's Checksum.
+func (p *Package) AddConditional(ss ...Symbol) {
+	p.conditionalMu.Lock()
+	if p.conditional == nil {
+		p.conditional = make(map[string]bool, len(ss))
 	}
-	pkg := &Package{Name: name}
-	v, loaded = pkgs.LoadOrStore(name, pkg)
-	if loaded {
-		return v.(*Package)
+	for _, s := range ss {
+		p.conditional[s.Name()] = true
 	}
-	return pkg
+	p.conditionalMu.Unlock()
+	p.Add(ss...)
 }
 
-// Lookup a package by its name.
-func Lookup(name string) (*Package, error) {
-	v, ok := pkgs.Load(name)
-	if !ok {
-		return nil, NotFound{Pkg: name}
-	}
-	return v.(*Package), nil
+// isConditional reports whether the named symbol was added with
+// AddConditional.
+func (p *Package) isConditional(name string) bool {
+	p.conditionalMu.Lock()
+	defer p.conditionalMu.Unlock()
+	return p.conditional[name]
+}
+
+// SetStrictNil turns strict nil checks of the symbols in the global registry
+// on or off.  See (*Registry).SetStrictNil.
+func SetStrictNil(on bool) { global.SetStrictNil(on) }
+
+// strictNil reports whether the package's registry has strict nil checks on.
+func (p *Package) strictNil() bool {
+	return p.registry != nil && atomic.LoadInt32(&p.registry.strictNil) != 0
 }
 
+// Packages gets every package defined so far, sorted by name.
+func Packages() []*Package { return global.Packages() }
+
+// Lookup a package by its name.  If the package isn't registered, the
+// registered Providers are asked for its symbols before giving up.
+func Lookup(name string) (*Package, error) { return global.Lookup(name) }
+
 // Symbol is an exported constant, function, type or variable.
 //
 // Symbols have names and values.  What you get by calling their Get function
@@ -70,6 +178,87 @@ type Symbol interface {
 	Get() interface{}
 }
 
+// GetterE is implemented by Symbols whose values can fail to materialize,
+// like symbols that a Provider loads lazily or from a remote registry.  Their
+// Get returns nil when GetE would return an error.
+type GetterE interface {
+	GetE() (interface{}, error)
+}
+
+// GetValue gets the value of s with GetE if s implements GetterE and with Get
+// otherwise.
+func GetValue(s Symbol) (interface{}, error) {
+	if g, ok := s.(GetterE); ok {
+		return g.GetE()
+	}
+	return s.Get(), nil
+}
+
+// Kind identifies which of the Symbol implementations in this package a
+// Symbol is.
+type Kind int
+
+const (
+	// BadKind is the Kind of Symbols not implemented by this package.
+	BadKind Kind = iota
+	ConstKind
+	TypeKind
+	FuncKind
+	VarKind
+	GenericKind
+	ConstraintKind
+)
+
+var kindStrings = []string{
+	"<bad kind>",
+	"Const",
+	"Type",
+	"Func",
+	"Var",
+	"Generic",
+	"Constraint",
+}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindStrings) {
+		return kindStrings[BadKind]
+	}
+	return kindStrings[k]
+}
+
+// KindOf gets the Kind of a Symbol.
+func KindOf(s Symbol) Kind {
+	switch s.(type) {
+	case Const:
+		return ConstKind
+	case Type:
+		return TypeKind
+	case Func:
+		return FuncKind
+	case Var:
+		return VarKind
+	case Generic:
+		return GenericKind
+	case Constraint:
+		return ConstraintKind
+	}
+	return BadKind
+}
+
+// docer is implemented by symbols that carry their documentation.
+type docer interface {
+	Doc() string
+}
+
+// Doc gets the documentation recorded for a Symbol or an empty string if the
+// symbol has no documentation.
+func Doc(s Symbol) string {
+	if d, ok := s.(docer); ok {
+		return d.Doc()
+	}
+	return ""
+}
+
 // Symbols are exported names in a package which can include things like
 // constants, functions, types and variables.
 type Symbols struct {
@@ -83,6 +272,19 @@ type Symbols struct {
 
 	// slice is the collection of exposed symbols in a Package.
 	slice []Symbol
+
+	// manifests holds manifests that haven't been loaded yet.
+	manifests [][]byte
+
+	// pkg is the name of the package the set belongs to, for Events.
+	pkg string
+
+	// watchers are notified of changes to the set by Watch.
+	watchers []*watcher
+
+	// sorted caches the symbols in the orders that Page has sorted them
+	// in.  It's reset whenever the set changes.
+	sorted map[SortOrder][]Symbol
 }
 
 // MakeSymbols creates a collection of symbols
@@ -101,6 +303,7 @@ func MakeSymbols(capacity int) Symbols {
 func (syms *Symbols) Lookup(name string) (Symbol, error) {
 	syms.mutex.Lock()
 	defer syms.mutex.Unlock()
+	syms.loadManifests()
 	if syms.names == nil {
 		return nil, NotFound{Sym: name}
 	}
@@ -111,6 +314,24 @@ func (syms *Symbols) Lookup(name string) (Symbol, error) {
 	return syms.slice[i], nil
 }
 
+// LookupMany looks up several symbols in the set at once, locking it only
+// once.  It returns the symbols it found by name and the names it didn't
+// find, in the order they were given.
+func (syms *Symbols) LookupMany(names ...string) (found map[string]Symbol, missing []string) {
+	found = make(map[string]Symbol, len(names))
+	syms.mutex.Lock()
+	defer syms.mutex.Unlock()
+	syms.loadManifests()
+	for _, name := range names {
+		if i, ok := syms.names[name]; ok {
+			found[name] = syms.slice[i]
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	return found, missing
+}
+
 // Add zero or more symbols to the set.  Symbols are only added if they haven't
 // already been defined.
 func (syms *Symbols) Add(ss ...Symbol) {
@@ -121,12 +342,120 @@ func (syms *Symbols) Add(ss ...Symbol) {
 		syms.slice = make([]Symbol, 0, cap(ss))
 	}
 	for _, s := range ss {
-		name := s.Name()
-		if _, ok := syms.names[name]; ok {
-			continue
+		syms.add(s)
+	}
+}
+
+// AddFrom adds a symbol for each entry in m, choosing the kind of Symbol from
+// the entry's value:
+//
+//   - Symbols are renamed to their keys.
+//   - reflect.Types and nil pointers like (*T)(nil) become Types.
+//   - Other pointers become Vars of the variables they point to.
+//   - Functions become Funcs.
+//   - Anything else becomes a Const.
+//
+// Symbols are added in order of their names.  As with Add, names that are
+// already defined are skipped.  AddFrom panics if a Symbol implemented
+// outside of this package is named differently than its key because it
+// can't be renamed.
+func (syms *Symbols) AddFrom(m map[string]interface{}) {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ss := make([]Symbol, len(names))
+	for i, name := range names {
+		ss[i] = symbolOf(name, m[name])
+	}
+	syms.Add(ss...)
+}
+
+// symbolOf creates a Symbol from a value as described by AddFrom.
+func symbolOf(name string, v interface{}) Symbol {
+	switch v := v.(type) {
+	case Symbol:
+		if v.Name() == name {
+			return v
+		}
+		r, ok := renamed(v, name)
+		if !ok {
+			panic(fmt.Sprintf(
+				"pkgsyms: can't rename %T %q to %q", v, v.Name(), name))
+		}
+		return r
+	case reflect.Type:
+		return Type{name: name, rtyp: v}
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return MakeType(name, v)
+		}
+		return MakeVar(name, v)
+	case reflect.Func:
+		if !rv.IsNil() {
+			return MakeFunc(name, v)
+		}
+	}
+	return MakeConst(name, v)
+}
+
+// Remove the symbols with the given names from the set and return how many
+// were removed.
+func (syms *Symbols) Remove(names ...string) int {
+	syms.mutex.Lock()
+	defer syms.mutex.Unlock()
+	syms.loadManifests()
+	removed := 0
+	for _, name := range names {
+		if i, ok := syms.names[name]; ok {
+			delete(syms.names, name)
+			removed++
+			logEvent(EventRemove, syms.pkg, name)
+			syms.notify(ChangeRemove, syms.slice[i])
+		}
+	}
+	if removed == 0 {
+		return 0
+	}
+	// Range may be iterating over the old slice, so build a new one.
+	slice := make([]Symbol, 0, len(syms.slice)-removed)
+	for _, s := range syms.slice {
+		if _, ok := syms.names[s.Name()]; ok {
+			syms.names[s.Name()] = len(slice)
+			slice = append(slice, s)
+		}
+	}
+	syms.slice = slice
+	syms.sorted = nil
+	return removed
+}
+
+// add a symbol to the set if it isn't already defined.  The caller must hold
+// the mutex.
+func (syms *Symbols) add(s Symbol) {
+	name := s.Name()
+	if _, ok := syms.names[name]; ok {
+		return
+	}
+	syms.names[name] = len(syms.slice)
+	syms.slice = append(syms.slice, s)
+	syms.sorted = nil
+	logEvent(EventAdd, syms.pkg, name)
+	syms.notify(ChangeAdd, s)
+}
+
+// Range calls f with each symbol in the set in the order they were added
+// until f returns false.  The set is not locked while f runs, so f may look up
+// or add symbols.
+func (syms *Symbols) Range(f func(s Symbol) bool) {
+	for _, s := range syms.snapshot() {
+		if !f(s) {
+			return
 		}
-		syms.names[name] = len(syms.slice)
-		syms.slice = append(syms.slice, s)
 	}
 }
 
@@ -136,6 +465,8 @@ func (syms *Symbols) Add(ss ...Symbol) {
 type Const struct {
 	name  string
 	value interface{}
+	doc   string
+	group string
 }
 
 // MakeConst creates a Const Symbol.
@@ -149,33 +480,273 @@ func (c Const) Name() string { return c.name }
 // Get the value of the constant.
 func (c Const) Get() interface{} { return c.value }
 
+// Doc gets the constant's documentation, if any was recorded.
+func (c Const) Doc() string { return c.doc }
+
+// WithDoc returns a copy of the constant with its documentation set to doc.
+func (c Const) WithDoc(doc string) Const {
+	c.doc = doc
+	return c
+}
+
+// Group gets the name of the constant's group:  The pkgsyms command groups
+// the constants declared together in a parenthesized const block, like the
+// values of an enum, under the name of the block's first registered
+// constant.  It's empty for constants that weren't declared in a group.
+func (c Const) Group() string { return c.group }
+
+// WithGroup returns a copy of the constant in the group.
+func (c Const) WithGroup(group string) Const {
+	c.group = group
+	return c
+}
+
 // Func is a global function Symbol.
 type Func struct {
 	name string
 	fval interface{}
+	doc  string
+
+	// pkg is the name of the package the Func was looked up in, for
+	// EventCall.
+	pkg string
+
+	// usage counts the calls of Funcs looked up while TrackUsage is on.
+	usage *usageCounter
+
+	// strictNil is set for Funcs looked up in a registry with strict nil
+	// checks.
+	strictNil bool
+
+	// id identifies the Func made by MakeFunc that this is a copy of, so
+	// that Equal can tell copies of a closure from other closures.
+	id uint64
 }
 
+// funcIDs counts the Funcs made by MakeFunc.
+var funcIDs uint64
+
 // MakeFunc creates a Func Symbol.
 func MakeFunc(name string, fval interface{}) Func {
-	return Func{name: name, fval: fval}
+	return Func{name: name, fval: fval, id: atomic.AddUint64(&funcIDs, 1)}
 }
 
 // Name of the function
 func (f Func) Name() string { return f.name }
 
 // Get the function value
-func (f Func) Get() interface{} { return f.fval }
+func (f Func) Get() interface{} {
+	if f.strictNil {
+		v, _ := f.GetE()
+		return v
+	}
+	return f.fval
+}
+
+// GetE gets the function value.  In a registry with strict nil checks, it
+// returns a NilSymbol error if the function is nil.
+func (f Func) GetE() (interface{}, error) {
+	if f.strictNil && isNil(f.fval) {
+		logEvent(EventNil, f.pkg, f.name)
+		return nil, NilSymbol{Pkg: f.pkg, Sym: f.name, Kind: FuncKind}
+	}
+	return f.fval, nil
+}
+
+// Call the function with the given arguments and return its results.  Nil
+// arguments are passed as the zero value of their parameter's type.  An error
+// is returned instead of panicking if the arguments don't match the
+// function's parameters.  If the function panics, the panic is recovered and
+// returned as an InvokeError.
+func (f Func) Call(args ...interface{}) (results []interface{}, err error) {
+	defer recoverInvoke(f.name, &err)
+	if log := loadLogger(); log != nil {
+		start := time.Now()
+		defer func() {
+			log(Event{Kind: EventCall, Pkg: f.pkg, Sym: f.name, Duration: time.Since(start)})
+		}()
+	}
+	fv := reflect.ValueOf(f.fval)
+	if fv.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s: cannot call %T", f.name, f.fval)
+	}
+	if f.usage != nil {
+		atomic.AddInt64(&f.usage.calls, 1)
+	}
+	ft := fv.Type()
+	if err := checkArity(f.name, ft, len(args)); err != nil {
+		return nil, err
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		t := paramType(ft, i)
+		if arg == nil {
+			in[i] = reflect.Zero(t)
+			continue
+		}
+		in[i] = reflect.ValueOf(arg)
+		if !in[i].Type().AssignableTo(t) {
+			return nil, ArgumentError{Sym: f.name, Err: fmt.Errorf(
+				"argument %d: cannot use %T as %v", i, arg, t)}
+		}
+	}
+	out := fv.Call(in)
+	results = make([]interface{}, len(out))
+	for i, o := range out {
+		results[i] = o.Interface()
+	}
+	return results, nil
+}
+
+// CanCall checks whether f could be called with arguments of the given types
+// without calling it.  It returns the error that Call would return if it
+// couldn't call f with them.  A nil type stands for a nil argument, which
+// Call passes as its parameter's zero value.
+func CanCall(f Func, argTypes ...reflect.Type) error {
+	ft := reflect.TypeOf(f.fval)
+	if ft == nil || ft.Kind() != reflect.Func {
+		return fmt.Errorf("%s: cannot call %T", f.name, f.fval)
+	}
+	if err := checkArity(f.name, ft, len(argTypes)); err != nil {
+		return err
+	}
+	for i, at := range argTypes {
+		if t := paramType(ft, i); at != nil && !at.AssignableTo(t) {
+			return ArgumentError{Sym: f.name, Err: fmt.Errorf(
+				"argument %d: cannot use %v as %v", i, at, t)}
+		}
+	}
+	return nil
+}
+
+// checkArity checks that a function of type ft can be called with n
+// arguments.
+func checkArity(name string, ft reflect.Type, n int) error {
+	in := ft.NumIn()
+	if ft.IsVariadic() && n < in-1 || !ft.IsVariadic() && n != in {
+		return ArgumentError{Sym: name, Err: fmt.Errorf(
+			"expected %d arguments, not %d", in, n)}
+	}
+	return nil
+}
+
+// contextType is the type of context.Context.
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// TakesContext reports whether the function's first parameter is a
+// context.Context.
+func (f Func) TakesContext() bool {
+	ft := reflect.TypeOf(f.fval)
+	return ft != nil && ft.Kind() == reflect.Func && ft.NumIn() > 0 &&
+		ft.In(0) == contextType
+}
+
+// CallCtx calls the function like Call, passing ctx as its first argument if
+// the function takes a context.Context (see TakesContext).  Functions that
+// don't take one are called with just args.  ctx's error is returned without
+// calling the function if ctx is already done.
+func (f Func) CallCtx(ctx context.Context, args ...interface{}) ([]interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.name, err)
+	}
+	if f.TakesContext() {
+		args = append([]interface{}{ctx}, args...)
+	}
+	return f.Call(args...)
+}
+
+// CallWithTimeout calls the function like CallCtx in a new goroutine and
+// returns early with ctx's error if ctx is done or d elapses first.  A d of
+// zero or less doesn't add a timeout.
+//
+// Go can't stop a goroutine, so an abandoned call keeps running in the
+// background until the function returns, and its results are discarded.
+// Functions that take a context.Context get one that's canceled when the
+// call is abandoned, so they can stop early; functions that don't may keep
+// holding locks or resources, and their side effects still happen.
+func (f Func) CallWithTimeout(ctx context.Context, d time.Duration, args ...interface{}) ([]interface{}, error) {
+	var cancel context.CancelFunc
+	if d > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+	type result struct {
+		results []interface{}
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		results, err := f.CallCtx(ctx, args...)
+		done <- result{results, err}
+	}()
+	select {
+	case r := <-done:
+		return r.results, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s: %w", f.name, ctx.Err())
+	}
+}
+
+// paramType gets the type of the i'th argument passed to a function of type
+// ft, accounting for variadic parameters.
+func paramType(ft reflect.Type, i int) reflect.Type {
+	if n := ft.NumIn(); ft.IsVariadic() && i >= n-1 {
+		return ft.In(n - 1).Elem()
+	}
+	return ft.In(i)
+}
+
+// Doc gets the function's documentation, if any was recorded.
+func (f Func) Doc() string { return f.doc }
+
+// WithDoc returns a copy of the function with its documentation set to doc.
+func (f Func) WithDoc(doc string) Func {
+	f.doc = doc
+	return f
+}
 
 // Type holds a reflect.Type defined in the package.
 type Type struct {
 	name string
 	rtyp reflect.Type
+	doc  string
+
+	// underlying is the definition of the underlying type as written in
+	// the package, if it was recorded.
+	underlying string
+
+	// implements are the names of the interfaces the type was found to
+	// implement when it was generated.
+	implements []string
+
+	// methods are the exported methods recorded when the type was
+	// generated.
+	methods []Method
+
+	// schema are the fields of a struct type recorded when the type was
+	// generated.
+	schema []Field
+}
+
+// Method describes an exported method of a Type.
+type Method struct {
+	Name string
+
+	// Signature of the method without its receiver, like
+	// "func(p []byte) (n int, err error)".
+	Signature string
+
+	// Pointer is set if the method has a pointer receiver, so it's only
+	// in the method set of a pointer to the type.
+	Pointer bool
 }
 
 // MakeType creates a Type from a pointer to a value of the proper type.  For
 // example:
 //
-// 	MakeType("MyInterface", (*MyInterface)(nil))
+//	MakeType("MyInterface", (*MyInterface)(nil))
 //
 // creates a Type that references the unwrapped MyInterface and not a pointer
 // to MyInterface.  The pointer is necessary because of how interfaces work in
@@ -183,7 +754,7 @@ type Type struct {
 func MakeType(name string, pval interface{}) Type {
 	return Type{
 		name: name,
-		rtyp: reflect.TypeOf(pval).Elem(),
+		rtyp: elemType(pval),
 	}
 }
 
@@ -196,17 +767,215 @@ func (t Type) Get() interface{} { return t.rtyp }
 // Type is like Get, but keeps it as a reflect.Type.
 func (t Type) Type() reflect.Type { return t.rtyp }
 
+// Doc gets the type's documentation, if any was recorded.
+func (t Type) Doc() string { return t.doc }
+
+// WithDoc returns a copy of the type with its documentation set to doc.
+func (t Type) WithDoc(doc string) Type {
+	t.doc = doc
+	return t
+}
+
+// Underlying describes the type's underlying type, like
+// "func(name string) error" or "map[string]int".  The definition recorded by
+// WithUnderlying is used if there is one.  Otherwise it's described from the
+// reflect.Type, so parameter names are lost and struct and interface types
+// are only described by their kind.
+func (t Type) Underlying() string {
+	if t.underlying != "" {
+		return t.underlying
+	}
+	return describeUnderlying(t.rtyp)
+}
+
+// WithUnderlying returns a copy of the type with the definition of its
+// underlying type set to def.
+func (t Type) WithUnderlying(def string) Type {
+	t.underlying = def
+	return t
+}
+
+// Implements reports whether the type, or a pointer to it, was recorded as
+// implementing the named interface, like "io.Reader", or the name of an
+// interface registered in the same package.  The interfaces are checked by
+// the pkgsyms command's -implements and -local-implements flags, so no
+// reflection is needed at run time.
+func (t Type) Implements(name string) bool {
+	for _, impl := range t.implements {
+		if impl == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Interfaces gets a copy of the names of the interfaces recorded by
+// WithImplements.
+func (t Type) Interfaces() []string {
+	return append([]string(nil), t.implements...)
+}
+
+// WithImplements returns a copy of the type that's recorded as implementing
+// the named interfaces.
+func (t Type) WithImplements(names ...string) Type {
+	t.implements = names
+	return t
+}
+
+// Implementers gets the types in the package that were recorded as
+// implementing the named interface (see Type.Implements).
+func (p *Package) Implementers(iface string) []Type {
+	var ts []Type
+	for _, t := range p.Types() {
+		if t.Implements(iface) {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+// Methods gets a copy of the methods recorded by WithMethods.  Unlike
+// reflect.Type's Method, the methods are available without reflection and
+// include the names of their parameters.
+func (t Type) Methods() []Method {
+	return append([]Method(nil), t.methods...)
+}
+
+// WithMethods returns a copy of the type with its exported methods set to
+// methods.
+func (t Type) WithMethods(methods ...Method) Type {
+	t.methods = methods
+	return t
+}
+
+// NewSlice makes a new value of a slice type with the given length and
+// capacity, like make(T, length, capacity).
+func (t Type) NewSlice(length, capacity int) (interface{}, error) {
+	if err := t.wantKind(reflect.Slice); err != nil {
+		return nil, err
+	}
+	if length < 0 || capacity < length {
+		return nil, fmt.Errorf(
+			"type %s: invalid slice length %d and capacity %d",
+			t.name, length, capacity)
+	}
+	return reflect.MakeSlice(t.rtyp, length, capacity).Interface(), nil
+}
+
+// NewMap makes a new, empty value of a map type, like make(T).
+func (t Type) NewMap() (interface{}, error) {
+	if err := t.wantKind(reflect.Map); err != nil {
+		return nil, err
+	}
+	return reflect.MakeMap(t.rtyp).Interface(), nil
+}
+
+// NewChan makes a new value of a channel type with a buffer of the given
+// size, like make(T, buffer).  The type must be bidirectional.
+func (t Type) NewChan(buffer int) (interface{}, error) {
+	if err := t.wantKind(reflect.Chan); err != nil {
+		return nil, err
+	}
+	if t.rtyp.ChanDir() != reflect.BothDir {
+		return nil, fmt.Errorf("type %s: can't make a %v", t.name, t.rtyp)
+	}
+	if buffer < 0 {
+		return nil, fmt.Errorf("type %s: invalid channel buffer %d", t.name, buffer)
+	}
+	return reflect.MakeChan(t.rtyp, buffer).Interface(), nil
+}
+
+// AssignableTo reports whether values of the type can be assigned to
+// variables of the other type.
+func (t Type) AssignableTo(other Type) bool {
+	return t.rtyp != nil && other.rtyp != nil && t.rtyp.AssignableTo(other.rtyp)
+}
+
+// ConvertibleTo reports whether values of the type can be converted to the
+// other type.
+func (t Type) ConvertibleTo(other Type) bool {
+	return t.rtyp != nil && other.rtyp != nil && t.rtyp.ConvertibleTo(other.rtyp)
+}
+
+// wantKind checks that the type is of the given kind.
+func (t Type) wantKind(k reflect.Kind) error {
+	if t.rtyp == nil || t.rtyp.Kind() != k {
+		return fmt.Errorf("type %s is not a %v type", t.name, k)
+	}
+	return nil
+}
+
+// describeUnderlying describes the underlying type of t in Go syntax.
+func describeUnderlying(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%v", t.Len(), t.Elem())
+	case reflect.Chan:
+		switch t.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + t.Elem().String()
+		case reflect.SendDir:
+			return "chan<- " + t.Elem().String()
+		}
+		return "chan " + t.Elem().String()
+	case reflect.Func:
+		ins := make([]string, t.NumIn())
+		for i := range ins {
+			ins[i] = t.In(i).String()
+		}
+		if t.IsVariadic() {
+			ins[len(ins)-1] = "..." + t.In(len(ins)-1).Elem().String()
+		}
+		outs := make([]string, t.NumOut())
+		for i := range outs {
+			outs[i] = t.Out(i).String()
+		}
+		s := "func(" + strings.Join(ins, ", ") + ")"
+		switch len(outs) {
+		case 0:
+			return s
+		case 1:
+			return s + " " + outs[0]
+		}
+		return s + " (" + strings.Join(outs, ", ") + ")"
+	case reflect.Map:
+		return fmt.Sprintf("map[%v]%v", t.Key(), t.Elem())
+	case reflect.Ptr:
+		return "*" + t.Elem().String()
+	case reflect.Slice:
+		return "[]" + t.Elem().String()
+	}
+	return t.Kind().String()
+}
+
 // Var is a Symbol that wraps a variable.
 type Var struct {
 	name string
 
 	// addr is a pointer to the variable.
 	addr interface{}
+
+	doc string
+
+	// fval forwards calls to the function held by the variable.  It's only
+	// set by Callable.
+	fval interface{}
+
+	// readOnly is set by ReadOnly.
+	readOnly bool
+
+	// pkg is the name of the package the Var was looked up in and
+	// strictNil is set if its registry has strict nil checks.
+	pkg       string
+	strictNil bool
+
+	// guard locks the map or channel of a variable made Guarded.
+	guard *sync.RWMutex
 }
 
 // MakeVar creates a variable symbol
 func MakeVar(name string, addr interface{}) Var {
-	return Var{name, addr}
+	return Var{name: name, addr: addr}
 }
 
 // Name of the variable
@@ -214,10 +983,112 @@ func (v Var) Name() string { return v.name }
 
 // Get the value of the variable
 func (v Var) Get() interface{} {
-	return reflect.ValueOf(v.addr).Elem().Interface()
+	if v.strictNil {
+		val, _ := v.GetE()
+		return val
+	}
+	return v.load()
+}
+
+// GetE gets the value of the variable.  In a registry with strict nil checks,
+// it returns a NilSymbol error if the variable's pointer is nil.
+func (v Var) GetE() (interface{}, error) {
+	if v.strictNil && isNil(v.addr) {
+		logEvent(EventNil, v.pkg, v.name)
+		return nil, NilSymbol{Pkg: v.pkg, Sym: v.name, Kind: VarKind}
+	}
+	return v.load(), nil
 }
 
-// Set the value of the variable.
+// isNil reports whether v is nil or a nil pointer or function.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Func:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// Type of the variable.
+func (v Var) Type() reflect.Type { return reflect.TypeOf(v.addr).Elem() }
+
+// Set the value of the variable.  It panics where SetE would return an
+// error.
 func (v Var) Set(val interface{}) {
-	reflect.ValueOf(v.addr).Elem().Set(reflect.ValueOf(val))
+	if err := v.SetE(val); err != nil {
+		panic(err)
+	}
+}
+
+// SetE sets the value of the variable like Set, but setting a read-only
+// variable returns a ReadOnlyError, and a value that can't be assigned to
+// the variable returns an InvokeError instead of panicking.
+func (v Var) SetE(val interface{}) (err error) {
+	if v.readOnly {
+		return ReadOnlyError{Sym: v.name}
+	}
+	defer recoverInvoke(v.name, &err)
+	if v.guard != nil {
+		v.guard.Lock()
+		defer v.guard.Unlock()
+	}
+	setVar(v.addr, val)
+	return nil
+}
+
+// ReadOnly returns a copy of the variable that can't be set through the
+// registry, so that hosts can expose state without letting scripts mutate
+// it.
+func (v Var) ReadOnly() Var {
+	v.readOnly = true
+	return v
+}
+
+// IsReadOnly reports whether the variable was made read-only.
+func (v Var) IsReadOnly() bool { return v.readOnly }
+
+// Doc gets the variable's documentation, if any was recorded.
+func (v Var) Doc() string { return v.doc }
+
+// WithDoc returns a copy of the variable with its documentation set to doc.
+func (v Var) WithDoc(doc string) Var {
+	v.doc = doc
+	return v
+}
+
+// Callable returns a copy of a variable of function type that can also be
+// used as a Func, so that dynamic callers can call it like any other
+// function.  The Func forwards calls to whatever function the variable holds
+// when it's called and is listed by Funcs and found by LookupFunc and AsFunc.
+// Callable panics if the variable isn't a function.
+func (v Var) Callable() Var {
+	fval, err := forwardFunc(v.name, v.addr)
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", v.name, err))
+	}
+	v.fval = fval
+	return v
+}
+
+// Func gets the Func that forwards calls to the variable if the variable was
+// made Callable.
+func (v Var) Func() (Func, bool) {
+	if v.fval == nil {
+		return Func{}, false
+	}
+	return Func{name: v.name, fval: v.fval, doc: v.doc}, true
+}
+
+// AsFunc gets s as a Func if it's a Func or a Var made Callable.
+func AsFunc(s Symbol) (Func, bool) {
+	switch s := s.(type) {
+	case Func:
+		return s, true
+	case Var:
+		return s.Func()
+	}
+	return Func{}, false
 }
@@ -1,8 +1,24 @@
 package pkgsyms_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"path"
 	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unsafe"
 
 	"github.com/skillian/pkgsyms"
 )
@@ -18,3 +34,1759 @@ func TestDynamic(t *testing.T) {
 		t.Fatalf("expected %T but got %T", (*pkgsyms.Package)(nil), v)
 	}
 }
+
+type testMessage struct {
+	Text string
+}
+
+func TestUnmarshalTyped(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test")
+	p.Add(pkgsyms.MakeType("testMessage", (*testMessage)(nil)))
+	v, err := p.UnmarshalTyped("testMessage", []byte(`{"Text":"hi"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := v.(*testMessage)
+	if !ok || m.Text != "hi" {
+		t.Fatalf("expected &{hi} but got %#v", v)
+	}
+	if _, err = p.UnmarshalTyped("missing", nil); err == nil {
+		t.Fatal("expected error for missing type")
+	}
+}
+
+func TestRegisterGob(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test")
+	p.Add(pkgsyms.MakeType("testMessage", (*testMessage)(nil)))
+	if err := pkgsyms.RegisterGob(p); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	var in interface{} = testMessage{Text: "hi"}
+	if err := gob.NewEncoder(&buf).Encode(&in); err != nil {
+		t.Fatal(err)
+	}
+	var out interface{}
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Fatalf("expected %#v but got %#v", in, out)
+	}
+}
+
+var (
+	testVerbose bool
+	testTimeout = time.Second
+)
+
+func TestBindFlags(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test")
+	p.Add(
+		pkgsyms.MakeVar("Verbose", &testVerbose),
+		pkgsyms.MakeVar("Timeout", &testTimeout),
+		pkgsyms.MakeVar("Nil", (*int)(nil)),
+	)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("Verbose", 0, "defined before")
+	if err := pkgsyms.BindFlags(fs, p); err == nil || !strings.Contains(err.Error(), "Verbose") {
+		t.Fatalf("expected an error about Verbose, got %v", err)
+	}
+	if fs.Lookup("Nil") != nil {
+		t.Fatal("expected no flag for a nil variable")
+	}
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	if err := pkgsyms.BindFlags(fs, p); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"-Verbose", "-Timeout=5s"}); err != nil {
+		t.Fatal(err)
+	}
+	if !testVerbose || testTimeout != 5*time.Second {
+		t.Fatalf("flags not bound: %v, %v", testVerbose, testTimeout)
+	}
+}
+
+var testRetries = 3
+
+func TestBindEnv(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test")
+	p.Add(
+		pkgsyms.MakeVar("Retries", &testRetries),
+		pkgsyms.MakeVar("Verbose", &testVerbose),
+	)
+	t.Setenv("TEST_RETRIES", "7")
+	t.Setenv("TEST_VERBOSE", "maybe")
+	err := pkgsyms.BindEnv(p, "test")
+	if testRetries != 7 {
+		t.Fatalf("expected 7 retries, not %d", testRetries)
+	}
+	errs, ok := err.(pkgsyms.EnvErrors)
+	if !ok || len(errs) != 1 || errs[0].Name != "TEST_VERBOSE" {
+		t.Fatalf("expected TEST_VERBOSE error, not %v", err)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/checksum")
+	p.Add(
+		pkgsyms.MakeFunc("Join", strings.Join),
+		pkgsyms.MakeConst("Answer", 42),
+	)
+	want := pkgsyms.ChecksumOf([]string{"Const Answer", "Func Join"})
+	if err := p.Verify(want); err != nil {
+		t.Fatal(err)
+	}
+	p.Add(pkgsyms.MakeVar("Retries", &testRetries))
+	if _, ok := p.Verify(want).(pkgsyms.ChecksumMismatch); !ok {
+		t.Fatal("expected checksum mismatch after adding a symbol")
+	}
+}
+
+func TestAddManifest(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/manifest")
+	p.Add(pkgsyms.MakeFunc("Join", strings.Join))
+	p.AddManifest([]byte(`{
+		"consts": [{"name": "Answer", "type": "int", "value": "42"}],
+		"docs": {"Join": "Join joins strings."}
+	}`))
+	s, err := p.Lookup("Answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := s.Get(); v != 42 {
+		t.Fatalf("expected 42 but got %#v", v)
+	}
+	if s, _ = p.Lookup("Join"); pkgsyms.Doc(s) != "Join joins strings." {
+		t.Fatalf("expected doc on Join, not %q", pkgsyms.Doc(s))
+	}
+}
+
+func TestManifestCBOR(t *testing.T) {
+	m := pkgsyms.Manifest{
+		Consts: []pkgsyms.ManifestConst{
+			{Name: "Answer", Type: "int", Value: "42", Group: "Answer"},
+			{Name: "Long", Type: "string", Value: strings.Repeat("x", 300)},
+		},
+		Docs: map[string]string{"Join": "Join joins strings.", "Answer": ""},
+	}
+	data, err := m.MarshalCBOR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got pkgsyms.Manifest
+	if err := got.UnmarshalCBOR(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Fatalf("expected %#v but got %#v", m, got)
+	}
+	if err := got.UnmarshalCBOR(data[:len(data)-1]); err == nil {
+		t.Fatal("expected an error decoding a truncated manifest")
+	}
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/manifestcbor")
+	p.Add(pkgsyms.MakeFunc("Join", strings.Join))
+	p.AddManifest(data)
+	if s, err := p.Lookup("Answer"); err != nil || s.Get() != 42 {
+		t.Fatalf("expected Answer to be 42, not %v (%v)", s, err)
+	}
+	if s, _ := p.Lookup("Join"); pkgsyms.Doc(s) != "Join joins strings." {
+		t.Fatalf("expected doc on Join, not %q", pkgsyms.Doc(s))
+	}
+	if c, _ := p.LookupConst("Answer"); c.Group() != "Answer" {
+		t.Fatalf("expected Answer in group Answer, not %q", c.Group())
+	}
+}
+
+func TestOfConcurrent(t *testing.T) {
+	const name = "github.com/skillian/pkgsyms_test/concurrent"
+	const n = 64
+	var wg sync.WaitGroup
+	ps := make([]*pkgsyms.Package, n)
+	for i := range ps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ps[i] = pkgsyms.Of(name)
+			ps[i].Add(pkgsyms.MakeConst(fmt.Sprint("C", i), i))
+		}(i)
+	}
+	wg.Wait()
+	for _, p := range ps[1:] {
+		if p != ps[0] {
+			t.Fatalf("Of returned distinct packages %p and %p", p, ps[0])
+		}
+	}
+	count := 0
+	ps[0].Range(func(pkgsyms.Symbol) bool {
+		count++
+		return true
+	})
+	if count != n {
+		t.Fatalf("expected %d symbols, not %d", n, count)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	const name = "github.com/skillian/pkgsyms_test/ready"
+	done := make(chan *pkgsyms.Package)
+	go func() { done <- pkgsyms.WaitReady(name) }()
+	select {
+	case <-done:
+		t.Fatal("WaitReady returned before MarkReady")
+	case <-time.After(10 * time.Millisecond):
+	}
+	p := pkgsyms.Of(name)
+	p.MarkReady()
+	p.MarkReady()
+	if got := <-done; got != p {
+		t.Fatalf("expected %p but got %p", p, got)
+	}
+}
+
+func TestAddFrom(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/addfrom")
+	p.AddFrom(map[string]interface{}{
+		"Answer":      42,
+		"Greeting":    pkgsyms.MakeConst("Hello", "hi"),
+		"Join":        strings.Join,
+		"Retries":     &testRetries,
+		"testMessage": (*testMessage)(nil),
+	})
+	for name, want := range map[string]pkgsyms.Kind{
+		"Answer":      pkgsyms.ConstKind,
+		"Greeting":    pkgsyms.ConstKind,
+		"Join":        pkgsyms.FuncKind,
+		"Retries":     pkgsyms.VarKind,
+		"testMessage": pkgsyms.TypeKind,
+	} {
+		s, err := p.Lookup(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := pkgsyms.KindOf(s); got != want {
+			t.Fatalf("%s: expected %v, not %v", name, want, got)
+		}
+		if s.Name() != name {
+			t.Fatalf("expected %q to be named by its key, not %q", name, s.Name())
+		}
+	}
+}
+
+// compatPkg is registered like the files generated before APIVersion 1,
+// which don't reference SupportsAPIVersion1 or call SetGenerator, so that
+// this file fails to compile if an API that they call changes.
+var compatPkg = pkgsyms.Of("github.com/skillian/pkgsyms_test/compat")
+
+func init() {
+	compatPkg.Add(
+		pkgsyms.MakeType("testMessage", (*testMessage)(nil)),
+		pkgsyms.MakeConst("Answer", 42),
+		pkgsyms.MakeFunc("Join", strings.Join),
+		pkgsyms.MakeVar("Retries", &testRetries),
+	)
+}
+
+func TestCompat(t *testing.T) {
+	for _, name := range []string{"testMessage", "Answer", "Join", "Retries"} {
+		if _, err := compatPkg.Lookup(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLookupWrongKind(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/wrongkind")
+	p.Add(pkgsyms.MakeVar("Retries", &testRetries))
+	if _, err := p.LookupVar("Retries"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := p.LookupFunc("Retries")
+	wk, ok := err.(pkgsyms.WrongKind)
+	if !ok || wk.Want != pkgsyms.FuncKind || wk.Got != pkgsyms.VarKind {
+		t.Fatalf("expected WrongKind, not %v", err)
+	}
+	if _, err = p.LookupFunc("Missing"); err == nil {
+		t.Fatal("expected NotFound")
+	} else if _, ok := err.(pkgsyms.NotFound); !ok {
+		t.Fatalf("expected NotFound, not %v", err)
+	}
+}
+
+func TestErrorsIs(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/errors")
+	p.Add(pkgsyms.MakeConst("Answer", 42))
+	_, err := p.UnmarshalTyped("Missing", nil)
+	if !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, not %v", err)
+	}
+	_, err = p.UnmarshalTyped("Answer", nil)
+	var wk pkgsyms.WrongKind
+	if !errors.Is(err, pkgsyms.ErrWrongKind) || !errors.As(err, &wk) {
+		t.Fatalf("expected WrongKind, not %v", err)
+	}
+}
+
+func TestProvider(t *testing.T) {
+	const name = "github.com/skillian/pkgsyms_test/provided"
+	calls := 0
+	pkgsyms.RegisterProvider(pkgsyms.ProviderFunc(func(pkg string) ([]pkgsyms.Symbol, error) {
+		if pkg != name {
+			return nil, nil
+		}
+		calls++
+		return []pkgsyms.Symbol{pkgsyms.MakeFunc("Join", strings.Join)}, nil
+	}))
+	p, err := pkgsyms.Lookup(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.LookupFunc("Join"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.Lookup("Missing"); !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, not %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected provider to be called once, not %d times", calls)
+	}
+}
+
+func TestSetOperations(t *testing.T) {
+	a := pkgsyms.MakeSymbols(3)
+	a.Add(
+		pkgsyms.MakeConst("A", 1),
+		pkgsyms.MakeConst("B", 2),
+		pkgsyms.MakeConst("C", 3),
+	)
+	b := pkgsyms.MakeSymbols(2)
+	b.Add(pkgsyms.MakeConst("B", 0), pkgsyms.MakeConst("D", 0))
+	if n := a.Intersect(&b).Len(); n != 1 {
+		t.Fatalf("expected 1 symbol in intersection, not %d", n)
+	}
+	diff := a.Subtract(&b)
+	if _, err := diff.Lookup("B"); err == nil {
+		t.Fatal("expected B to be subtracted")
+	}
+	if n := diff.Len(); n != 2 {
+		t.Fatalf("expected 2 symbols in difference, not %d", n)
+	}
+	v := a.ReadOnlyView()
+	a.Add(pkgsyms.MakeConst("E", 5))
+	if _, err := v.Lookup("E"); err == nil {
+		t.Fatal("expected view to not see symbols added later")
+	}
+}
+
+func TestFuncCall(t *testing.T) {
+	f := pkgsyms.MakeFunc("Join", strings.Join)
+	res, err := f.Call([]string{"a", "b"}, "-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0] != "a-b" {
+		t.Fatalf("expected [a-b] but got %v", res)
+	}
+	if _, err = f.Call(1, 2); err == nil {
+		t.Fatal("expected error calling with wrong argument types")
+	}
+}
+
+func TestFuncCallCtx(t *testing.T) {
+	type key struct{}
+	f := pkgsyms.MakeFunc("Greet", func(ctx context.Context, name string) string {
+		return ctx.Value(key{}).(string) + ", " + name
+	})
+	if !f.TakesContext() {
+		t.Fatal("expected Greet to take a context")
+	}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "hello"))
+	res, err := f.CallCtx(ctx, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0] != "hello, bob" {
+		t.Fatalf("expected the context to be passed, got %v", res[0])
+	}
+	if res, err = pkgsyms.MakeFunc("Join", strings.Join).CallCtx(ctx, []string{"a", "b"}, "-"); err != nil || res[0] != "a-b" {
+		t.Fatalf("expected functions without a context to be called, got %v, %v", res, err)
+	}
+	cancel()
+	if _, err := f.CallCtx(ctx, "bob"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled but got %v", err)
+	}
+}
+
+type lazySymbol struct {
+	name string
+	err  error
+}
+
+func (s lazySymbol) Name() string     { return s.name }
+func (s lazySymbol) Get() interface{} { v, _ := s.GetE(); return v }
+
+func (s lazySymbol) GetE() (interface{}, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return "loaded " + s.name, nil
+}
+
+func TestLookupValue(t *testing.T) {
+	errUnreachable := errors.New("registry unreachable")
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/lazy")
+	p.Add(
+		lazySymbol{name: "Good"},
+		lazySymbol{name: "Bad", err: errUnreachable},
+		pkgsyms.MakeConst("Plain", 1),
+	)
+	if v, err := p.LookupValue("Good"); err != nil || v != "loaded Good" {
+		t.Fatalf("expected loaded Good but got %v, %v", v, err)
+	}
+	if v, err := p.LookupValue("Plain"); err != nil || v != 1 {
+		t.Fatalf("expected 1 but got %v, %v", v, err)
+	}
+	if _, err := p.LookupValue("Bad"); !errors.Is(err, errUnreachable) {
+		t.Fatalf("expected the GetE error but got %v", err)
+	}
+	if _, err := p.LookupValue("Missing"); !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound but got %v", err)
+	}
+}
+
+var testOnGreet = func(name string) string { return "hello, " + name }
+
+func TestCallableVar(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/callable")
+	p.Add(pkgsyms.MakeVar("OnGreet", &testOnGreet).Callable())
+	f, err := p.LookupFunc("OnGreet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := testOnGreet
+	defer func() { testOnGreet = old }()
+	testOnGreet = func(name string) string { return "hi, " + name }
+	res, err := f.Call("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[0] != "hi, bob" {
+		t.Fatalf("expected call to be forwarded, got %v", res[0])
+	}
+	if fs := p.Funcs(); len(fs) != 1 || fs[0].Name() != "OnGreet" {
+		t.Fatalf("expected Funcs to list OnGreet, got %v", fs)
+	}
+}
+
+func TestCallableVarNil(t *testing.T) {
+	var load func(name string) (string, error)
+	v := pkgsyms.MakeVar("Load", &load).Callable()
+	f, ok := pkgsyms.AsFunc(v)
+	if !ok {
+		t.Fatal("expected a callable Var to be a Func")
+	}
+	res, err := f.Call("x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err, _ := res[1].(error); !errors.Is(err, pkgsyms.ErrNil) {
+		t.Fatalf("expected ErrNil but got %v", res[1])
+	}
+	defer func() {
+		if _, ok := recover().(error); !ok {
+			t.Fatal("expected Callable of a nil target to panic with an error")
+		}
+	}()
+	pkgsyms.MakeVar("Nil", (*func())(nil)).Callable()
+}
+
+func TestConstraintUsers(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/generic")
+	p.Add(
+		pkgsyms.MakeConstraint("Number", "interface{~int | ~float64}"),
+		pkgsyms.MakeGeneric("Sum", pkgsyms.TypeParam{Name: "T", Constraint: "Number"}),
+		pkgsyms.MakeGeneric("Pair",
+			pkgsyms.TypeParam{Name: "K", Constraint: "comparable"},
+			pkgsyms.TypeParam{Name: "V", Constraint: "Number"}),
+		pkgsyms.MakeGeneric("List", pkgsyms.TypeParam{Name: "T", Constraint: "any"}),
+	)
+	s, err := p.Lookup("Number")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k := pkgsyms.KindOf(s); k != pkgsyms.ConstraintKind {
+		t.Fatalf("expected %v but got %v", pkgsyms.ConstraintKind, k)
+	}
+	var names []string
+	for _, g := range p.ConstraintUsers("Number") {
+		names = append(names, g.Name())
+	}
+	if strings.Join(names, ",") != "Sum,Pair" {
+		t.Fatalf("expected Sum and Pair to use Number, got %v", names)
+	}
+}
+
+type testHandler func(name string, args ...int) (int, error)
+
+func TestTypeUnderlying(t *testing.T) {
+	for _, tc := range []struct {
+		typ  pkgsyms.Type
+		want string
+	}{
+		{pkgsyms.MakeType("Handler", (*testHandler)(nil)), "func(string, ...int) (int, error)"},
+		{pkgsyms.MakeType("Handler", (*testHandler)(nil)).WithUnderlying(
+			"func(name string, args ...int) (int, error)"),
+			"func(name string, args ...int) (int, error)"},
+		{pkgsyms.MakeType("Kind", (*pkgsyms.Kind)(nil)), "int"},
+		{pkgsyms.MakeType("Set", (*map[string][]byte)(nil)), "map[string][]uint8"},
+		{pkgsyms.MakeType("Recv", (*<-chan int)(nil)), "<-chan int"},
+		{pkgsyms.MakeType("Package", (*pkgsyms.Package)(nil)), "struct"},
+	} {
+		if got := tc.typ.Underlying(); got != tc.want {
+			t.Errorf("%s: expected %q but got %q", tc.typ.Name(), tc.want, got)
+		}
+	}
+}
+
+func TestReadOnlyVar(t *testing.T) {
+	limit := 10
+	v := pkgsyms.MakeVar("Limit", &limit).ReadOnly()
+	if err := v.SetE(20); !errors.Is(err, pkgsyms.ErrReadOnly) {
+		t.Fatalf("expected %v but got %v", pkgsyms.ErrReadOnly, err)
+	}
+	func() {
+		defer func() {
+			if err, _ := recover().(error); !errors.Is(err, pkgsyms.ErrReadOnly) {
+				t.Fatalf("expected Set to panic with %v but got %v", pkgsyms.ErrReadOnly, err)
+			}
+		}()
+		v.Set(20)
+	}()
+	if limit != 10 || v.Get() != 10 {
+		t.Fatalf("expected read-only variable to be unchanged, got %d", limit)
+	}
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/readonly")
+	p.Add(v)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	pkgsyms.BindFlags(fs, p)
+	if fs.Lookup("Limit") != nil {
+		t.Fatal("expected no flag for read-only variable")
+	}
+}
+
+func TestPackageDoc(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/doc")
+	if doc := p.Doc(); doc != "" {
+		t.Fatalf("expected no doc but got %q", doc)
+	}
+	p.SetDoc("Package doc is documented.")
+	if doc := pkgsyms.Of(p.Name).Doc(); doc != "Package doc is documented." {
+		t.Fatalf("expected package doc but got %q", doc)
+	}
+}
+
+func TestAddConditional(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/conditional")
+	p.Add(pkgsyms.MakeConst("Common", 1))
+	want := p.Checksum()
+	p.AddConditional(pkgsyms.MakeConst("LinuxOnly", 2))
+	if _, err := p.Lookup("LinuxOnly"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Verify(want); err != nil {
+		t.Fatalf("expected conditional symbols to be left out of the checksum: %v", err)
+	}
+}
+
+func TestSetGenerator(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/generator")
+	p.SetGenerator("v1.2.0", pkgsyms.APIVersion)
+	if v, api := p.Generator(); v != "v1.2.0" || api != pkgsyms.APIVersion {
+		t.Fatalf("expected v1.2.0 and %d but got %s and %d", pkgsyms.APIVersion, v, api)
+	}
+	defer func() {
+		var ge pkgsyms.GeneratorError
+		if v := recover(); v == nil || !errors.As(v.(error), &ge) || ge.API != pkgsyms.APIVersion+1 {
+			t.Fatalf("expected a GeneratorError but got %v", v)
+		}
+	}()
+	p.SetGenerator("v9.0.0", pkgsyms.APIVersion+1)
+}
+
+func TestSearch(t *testing.T) {
+	a := pkgsyms.Of("github.com/skillian/pkgsyms_test/search/a")
+	a.Add(
+		pkgsyms.MakeConst("MaxRetries", 3),
+		pkgsyms.MakeConst("MinRetries", 1),
+	)
+	b := pkgsyms.Of("github.com/skillian/pkgsyms_test/search/b")
+	b.Add(pkgsyms.MakeFunc("RetryLater", func() {}))
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"retr", []string{"a.MaxRetries", "a.MinRetries", "b.RetryLater"}},
+		{"M*Retries", []string{"a.MaxRetries", "a.MinRetries"}},
+		{"Retry?ater", []string{"b.RetryLater"}},
+	} {
+		var got []string
+		for _, rs := range pkgsyms.Search(tc.query) {
+			if !strings.HasPrefix(rs.Package.Name, "github.com/skillian/pkgsyms_test/search/") {
+				continue
+			}
+			got = append(got, path.Base(rs.Package.Name)+"."+rs.Symbol.Name())
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Search(%q): expected %v but got %v", tc.query, tc.want, got)
+		}
+	}
+}
+
+func TestID(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/id.v2")
+	p.Add(pkgsyms.MakeConst("Version", 2))
+	id := pkgsyms.ID(p.Name, "Version")
+	if id != `"github.com/skillian/pkgsyms_test/id.v2".Version` {
+		t.Fatalf("unexpected ID: %s", id)
+	}
+	rs, err := pkgsyms.Resolve(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Package != p || rs.Symbol.Get() != 2 || rs.ID() != id {
+		t.Fatalf("expected %s to resolve to Version, got %v", id, rs)
+	}
+	if pkg, sym, err := pkgsyms.ParseID(`"a/b"`); err != nil || pkg != "a/b" || sym != "" {
+		t.Fatalf("expected package-only ID, got %q %q %v", pkg, sym, err)
+	}
+	for _, bad := range []string{`a/b.C`, `"a/b"C`, `"a/b".`} {
+		if _, _, err := pkgsyms.ParseID(bad); err == nil {
+			t.Errorf("expected error parsing %s", bad)
+		}
+	}
+	p.Add(pkgsyms.MakeConst("other.Version", 1))
+	qualified := pkgsyms.ID(p.Name, "other.Version")
+	if pkg, sym, err := pkgsyms.ParseID(qualified); err != nil || pkg != p.Name || sym != "other.Version" {
+		t.Fatalf("expected %s to round-trip, got %q %q %v", qualified, pkg, sym, err)
+	}
+	if rs, err := pkgsyms.Resolve(qualified); err != nil || rs.ID() != qualified {
+		t.Fatalf("expected %s to resolve, got %v, %v", qualified, rs, err)
+	}
+	_, err = pkgsyms.Resolve(pkgsyms.ID(p.Name, "Missing"))
+	if err == nil || err.Error() != pkgsyms.ID(p.Name, "Missing")+" not found" {
+		t.Fatalf("expected NotFound error with the ID, got %v", err)
+	}
+}
+
+func TestWireID(t *testing.T) {
+	reg := pkgsyms.NewRegistry()
+	p := reg.Of("github.com/skillian/pkgsyms_test/wireid")
+	p.Add(pkgsyms.MakeFunc("Join", strings.Join), pkgsyms.MakeConst("Answer", 42))
+	id := pkgsyms.MakeWireID(p.Name, pkgsyms.FuncKind, "Join")
+	if id != pkgsyms.MakeWireID(p.Name, pkgsyms.FuncKind, "Join") {
+		t.Fatal("expected the wire ID to be deterministic")
+	}
+	if id == pkgsyms.MakeWireID(p.Name, pkgsyms.VarKind, "Join") {
+		t.Fatal("expected the wire ID to depend on the kind")
+	}
+	parsed, err := pkgsyms.ParseWireID(id.String())
+	if err != nil || parsed != id {
+		t.Fatalf("expected %v but got %v, %v", id, parsed, err)
+	}
+	rs, err := reg.ResolveWireID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Package != p || rs.Symbol.Name() != "Join" || rs.WireID() != id {
+		t.Fatalf("unexpected symbol %v for %v", rs.ID(), id)
+	}
+	if _, err := p.LookupWireID(id + 1); !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected NotFound but got %v", err)
+	}
+	if _, err := pkgsyms.ParseWireID("xyz"); err == nil {
+		t.Fatal("expected an error parsing an invalid wire ID")
+	}
+}
+
+func TestTypeImplements(t *testing.T) {
+	tp := pkgsyms.MakeType("Buffer", (*bytes.Buffer)(nil)).WithImplements("io.Reader", "io.Writer")
+	if !tp.Implements("io.Writer") || tp.Implements("io.Closer") {
+		t.Fatalf("unexpected interfaces: %v", tp.Interfaces())
+	}
+}
+
+func TestImplementers(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/implementers")
+	p.Add(
+		pkgsyms.MakeType("Source", (*io.Reader)(nil)),
+		pkgsyms.MakeType("Buffer", (*bytes.Buffer)(nil)).WithImplements("Source", "io.Writer"),
+		pkgsyms.MakeType("Reader", (*strings.Reader)(nil)).WithImplements("Source"),
+		pkgsyms.MakeType("Kind", (*pkgsyms.Kind)(nil)),
+	)
+	var names []string
+	for _, tp := range p.Implementers("Source") {
+		names = append(names, tp.Name())
+	}
+	if strings.Join(names, ",") != "Buffer,Reader" {
+		t.Fatalf("expected Buffer and Reader to implement Source, got %v", names)
+	}
+	if ts := p.Implementers("io.Closer"); len(ts) != 0 {
+		t.Fatalf("expected no implementers of io.Closer, got %v", ts)
+	}
+}
+
+type testNames []string
+
+func TestTypeMake(t *testing.T) {
+	names := pkgsyms.MakeType("Names", (*testNames)(nil))
+	v, err := names.NewSlice(1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns, ok := v.(testNames); !ok || len(ns) != 1 || cap(ns) != 4 {
+		t.Fatalf("expected a testNames of length 1 and capacity 4, got %#v", v)
+	}
+	if v, err = pkgsyms.MakeType("Set", (*map[string]bool)(nil)).NewMap(); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := v.(map[string]bool); !ok || m == nil {
+		t.Fatalf("expected a map[string]bool, got %#v", v)
+	}
+	if v, err = pkgsyms.MakeType("Queue", (*chan int)(nil)).NewChan(2); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := v.(chan int); !ok || cap(c) != 2 {
+		t.Fatalf("expected a chan int with a buffer of 2, got %#v", v)
+	}
+	if _, err := names.NewMap(); err == nil {
+		t.Fatal("expected an error making a map of a slice type")
+	}
+	if _, err := names.NewSlice(2, 1); err == nil {
+		t.Fatal("expected an error making a slice with a capacity less than its length")
+	}
+	if _, err := pkgsyms.MakeType("Recv", (*<-chan int)(nil)).NewChan(0); err == nil {
+		t.Fatal("expected an error making a receive-only channel")
+	}
+}
+
+func TestTypeAssignableTo(t *testing.T) {
+	names := pkgsyms.MakeType("Names", (*testNames)(nil))
+	strs := pkgsyms.MakeType("Strings", (*[]string)(nil))
+	stringer := pkgsyms.MakeType("Stringer", (*fmt.Stringer)(nil))
+	kind := pkgsyms.MakeType("Kind", (*pkgsyms.Kind)(nil))
+	switch {
+	case !names.AssignableTo(strs) || !strs.AssignableTo(names):
+		t.Fatal("expected a named slice to be assignable to its underlying type")
+	case !kind.AssignableTo(stringer) || stringer.AssignableTo(kind):
+		t.Fatal("expected Kind to only be assignable to Stringer")
+	case kind.AssignableTo(names) || kind.ConvertibleTo(names):
+		t.Fatal("expected Kind to be neither assignable nor convertible to Names")
+	case !kind.ConvertibleTo(pkgsyms.MakeType("Int", (*int)(nil))):
+		t.Fatal("expected Kind to be convertible to int")
+	}
+}
+
+func TestCanCall(t *testing.T) {
+	join := pkgsyms.MakeFunc("Join", strings.Join)
+	printf := pkgsyms.MakeFunc("Sprintf", fmt.Sprintf)
+	str, strs := reflect.TypeOf(""), reflect.TypeOf([]string(nil))
+	for _, tc := range []struct {
+		f    pkgsyms.Func
+		args []reflect.Type
+		ok   bool
+	}{
+		{join, []reflect.Type{strs, str}, true},
+		{join, []reflect.Type{reflect.TypeOf(testNames(nil)), str}, true},
+		{join, []reflect.Type{nil, str}, true},
+		{join, []reflect.Type{str, str}, false},
+		{join, []reflect.Type{strs}, false},
+		{printf, []reflect.Type{str}, true},
+		{printf, []reflect.Type{str, reflect.TypeOf(1), str}, true},
+		{printf, nil, false},
+	} {
+		if err := pkgsyms.CanCall(tc.f, tc.args...); (err == nil) != tc.ok {
+			t.Errorf("CanCall(%s, %v): expected ok to be %v, got %v", tc.f.Name(), tc.args, tc.ok, err)
+		}
+	}
+}
+
+func TestTypeMethods(t *testing.T) {
+	read := pkgsyms.Method{Name: "Read", Signature: "func(p []byte) (n int, err error)", Pointer: true}
+	tp := pkgsyms.MakeType("Buffer", (*bytes.Buffer)(nil)).WithMethods(read)
+	ms := tp.Methods()
+	if len(ms) != 1 || ms[0] != read {
+		t.Fatalf("expected %v but got %v", read, ms)
+	}
+	ms[0].Name = "Write"
+	if tp.Methods()[0].Name != "Read" {
+		t.Fatal("expected Methods to return a copy")
+	}
+}
+
+func TestTypeValidate(t *testing.T) {
+	type config struct {
+		Addr    string
+		Timeout int
+		Tags    []string
+		Started time.Time
+		Key     []byte
+		Extra   json.RawMessage
+	}
+	tp := pkgsyms.MakeType("Config", (*config)(nil)).WithSchema(
+		pkgsyms.Field{Name: "addr", GoName: "Addr", Kind: "string", Type: "string", Required: true},
+		pkgsyms.Field{Name: "timeout", GoName: "Timeout", Kind: "int", Type: "int"},
+		pkgsyms.Field{Name: "tags", GoName: "Tags", Kind: "slice", Type: "[]string"},
+		pkgsyms.Field{Name: "started", GoName: "Started", Kind: "struct", Type: "time.Time"},
+		pkgsyms.Field{Name: "key", GoName: "Key", Kind: "slice", Type: "[]byte"},
+		pkgsyms.Field{Name: "extra", GoName: "Extra", Kind: "slice", Type: "json.RawMessage"},
+	)
+	if err := tp.Validate(map[string]interface{}{
+		"addr": ":80", "timeout": 1.5, "tags": []interface{}{"a"},
+		"started": "2024-01-02T03:04:05Z", "key": "a2V5",
+		"extra": map[string]interface{}{"a": 1.0},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	err := tp.Validate(map[string]interface{}{"timeout": "1s", "port": 80})
+	var errs pkgsyms.SchemaErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected SchemaErrors but got %v", err)
+	}
+	want := `Config: field "addr" is required; ` +
+		`Config: field "timeout" expected int, not string; ` +
+		`Config: field "port" is unknown`
+	if err.Error() != want {
+		t.Fatalf("expected:\n\t%s\nbut got:\n\t%s", want, err)
+	}
+}
+
+func TestKindCollections(t *testing.T) {
+	var n int
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/kinds")
+	p.Add(
+		pkgsyms.MakeFunc("Join", strings.Join),
+		pkgsyms.MakeConst("Answer", 42),
+		pkgsyms.MakeType("Builder", (*strings.Builder)(nil)),
+		pkgsyms.MakeVar("N", &n),
+		pkgsyms.MakeFunc("Split", strings.Split),
+		pkgsyms.MakeConst("Pi", 3.14),
+	)
+	if fs := p.Funcs(); len(fs) != 2 || fs[0].Name() != "Join" || fs[1].Name() != "Split" {
+		t.Fatalf("expected Join and Split but got %v", fs)
+	}
+	if cs := p.Consts(); len(cs) != 2 || cs[0].Name() != "Answer" || cs[1].Name() != "Pi" {
+		t.Fatalf("expected Answer and Pi but got %v", cs)
+	}
+	if ts := p.Types(); len(ts) != 1 || ts[0].Name() != "Builder" {
+		t.Fatalf("expected Builder but got %v", ts)
+	}
+	if vs := p.Vars(); len(vs) != 1 || vs[0].Name() != "N" {
+		t.Fatalf("expected N but got %v", vs)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	var out fmt.Stringer
+	syms := pkgsyms.MakeSymbols(4)
+	syms.Add(
+		pkgsyms.MakeConst("Max", 10),
+		pkgsyms.MakeFunc("Double", func(i int) int { return 2 * i }),
+		pkgsyms.MakeVar("Out", &out),
+		pkgsyms.MakeType("Duration", (*time.Duration)(nil)),
+	)
+	stringer := reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	for _, tc := range []struct {
+		name string
+		pred func(pkgsyms.Symbol) bool
+		want []string
+	}{
+		{"kind", pkgsyms.ByKind(pkgsyms.ConstKind, pkgsyms.VarKind), []string{"Max", "Out"}},
+		{"assignable", pkgsyms.ByTypeAssignableTo(stringer), []string{"Out", "Duration"}},
+		{"and", pkgsyms.And(pkgsyms.ByTypeAssignableTo(stringer), pkgsyms.ByKind(pkgsyms.TypeKind)), []string{"Duration"}},
+		{"or not", pkgsyms.Or(pkgsyms.Not(pkgsyms.ByKind(pkgsyms.FuncKind, pkgsyms.TypeKind))), []string{"Max", "Out"}},
+	} {
+		var got []string
+		for _, s := range syms.Select(tc.pred) {
+			got = append(got, s.Name())
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %q but got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestAddAll(t *testing.T) {
+	syms := pkgsyms.MakeSymbols(4)
+	syms.Add(pkgsyms.MakeConst("Existing", 1))
+	added, err := syms.AddAll([]pkgsyms.Symbol{
+		pkgsyms.MakeConst("New", 2),
+		pkgsyms.MakeConst("Existing", 3),
+		pkgsyms.MakeConst("Twice", 4),
+		pkgsyms.MakeConst("Twice", 5),
+	})
+	var ce pkgsyms.CollisionError
+	if added != 0 || !errors.As(err, &ce) || len(ce) != 2 ||
+		ce[0].Name != "Existing" || ce[1].Name != "Twice" {
+		t.Fatalf("expected Existing and Twice to collide, got %d, %v", added, err)
+	}
+	if syms.Len() != 1 {
+		t.Fatalf("expected nothing to be added, got %d symbols", syms.Len())
+	}
+	if added, err = syms.AddAll([]pkgsyms.Symbol{
+		pkgsyms.MakeConst("New", 2),
+		pkgsyms.MakeConst("Other", 3),
+	}); added != 2 || err != nil || syms.Len() != 3 {
+		t.Fatalf("expected 2 symbols to be added, got %d, %v", added, err)
+	}
+}
+
+func TestFrozen(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms_test/frozen")
+	p.Add(pkgsyms.MakeConst("A", 1), pkgsyms.MakeConst("B", 2))
+	frozen := p.Frozen()
+	p.Add(pkgsyms.MakeConst("C", 3))
+	if s, err := frozen.Lookup("B"); err != nil || s.Get() != 2 {
+		t.Fatalf("expected B to be 2, got %v, %v", s, err)
+	}
+	if _, err := frozen.Lookup("C"); !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected symbols added later not to be frozen, got %v", err)
+	}
+	var names []string
+	frozen.Range(func(s pkgsyms.Symbol) bool {
+		names = append(names, s.Name())
+		return true
+	})
+	if frozen.Len() != 2 || strings.Join(names, ",") != "A,B" {
+		t.Fatalf("expected A and B but got %q", names)
+	}
+}
+
+func TestMemStats(t *testing.T) {
+	name := func() string { return strings.Repeat("Name", 4) }
+	syms := pkgsyms.MakeSymbols(3)
+	syms.Add(
+		pkgsyms.MakeConst(name(), 1),
+		pkgsyms.MakeConst("Other", 2).WithDoc("documented"),
+	)
+	st := syms.MemStats()
+	if st.Symbols != 2 || st.NameBytes != 21 || st.DocBytes != 10 || st.Bytes <= 31 {
+		t.Fatalf("unexpected stats %+v", st)
+	}
+	a, b := pkgsyms.Intern(name()), pkgsyms.Intern(name())
+	if unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Fatal("expected interned names to share their storage")
+	}
+}
+
+func TestStrictNil(t *testing.T) {
+	const name = "github.com/skillian/pkgsyms_test/strictnil"
+	var mu sync.Mutex
+	var events []string
+	pkgsyms.SetLogger(func(e pkgsyms.Event) {
+		if e.Pkg == name && e.Kind == pkgsyms.EventNil {
+			mu.Lock()
+			events = append(events, e.Sym)
+			mu.Unlock()
+		}
+	})
+	defer pkgsyms.SetLogger(nil)
+	var n int
+	reg := pkgsyms.NewRegistry()
+	p := reg.Of(name)
+	p.Add(
+		pkgsyms.MakeVar("Nil", (*int)(nil)),
+		pkgsyms.MakeVar("N", &n),
+		pkgsyms.MakeFunc("NilFunc", (func())(nil)),
+	)
+	reg.SetStrictNil(true)
+	for _, sym := range []string{"Nil", "NilFunc"} {
+		s, err := p.Lookup(sym)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := s.Get(); v != nil {
+			t.Fatalf("expected %s to get nil, got %#v", sym, v)
+		}
+		if _, err := pkgsyms.GetValue(s); !errors.Is(err, pkgsyms.ErrNil) {
+			t.Fatalf("expected a NilSymbol error from %s but got %v", sym, err)
+		}
+	}
+	if v, err := p.LookupValue("N"); err != nil || v != 0 {
+		t.Fatalf("expected 0 but got %v, %v", v, err)
+	}
+	if strings.Join(events, ",") != "Nil,Nil,NilFunc,NilFunc" {
+		t.Fatalf("unexpected nil events: %v", events)
+	}
+	reg.SetStrictNil(false)
+	f, err := p.LookupFunc("NilFunc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.GetE(); err != nil {
+		t.Fatalf("expected no error without strict nil checks, got %v", err)
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	const name = "github.com/skillian/pkgsyms_test/logger"
+	var mu sync.Mutex
+	var events []string
+	pkgsyms.SetLogger(func(e pkgsyms.Event) {
+		if e.Pkg == name {
+			mu.Lock()
+			events = append(events, e.Kind.String()+" "+e.Sym)
+			mu.Unlock()
+		}
+	})
+	defer pkgsyms.SetLogger(nil)
+	p := pkgsyms.Of(name)
+	p.Add(pkgsyms.MakeConst("A", 1))
+	p.MarkReady()
+	p.Lookup("A")
+	p.Lookup("B")
+	p.Remove("A")
+	p.Add(pkgsyms.MakeFunc("F", strings.ToUpper))
+	f, err := p.LookupFunc("F")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Call("x")
+	want := []string{
+		"add A", "ready ", "lookup-hit A", "lookup-miss B", "remove A",
+		"add F", "lookup-hit F", "call F",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("expected %q but got %q", want, events)
+	}
+	if got := pkgsyms.EventKind(100).String(); got != "EventKind(100)" {
+		t.Fatalf("expected EventKind(100) but got %q", got)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	const name = "github.com/skillian/pkgsyms_test/registry"
+	registrar := func(p *pkgsyms.Package) {
+		p.Add(pkgsyms.MakeConst("Answer", 42))
+		p.MarkReady()
+	}
+	reg := pkgsyms.NewRegistry()
+	p := reg.Register(name, registrar)
+	if got, err := reg.Lookup(name); err != nil || got != p {
+		t.Fatalf("expected the registered package, got %v, %v", got, err)
+	}
+	if _, err := p.Lookup("Answer"); err != nil {
+		t.Fatal(err)
+	}
+	if ps := reg.Packages(); len(ps) != 1 || ps[0] != p {
+		t.Fatalf("expected only %s in the registry, got %v", name, ps)
+	}
+	if _, err := pkgsyms.Lookup(name); !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected the global registry not to have the package, got %v", err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a := pkgsyms.Of("github.com/skillian/pkgsyms_test/merge/a")
+	a.Add(pkgsyms.MakeConst("Only", "a"), pkgsyms.MakeConst("Shared", "a"))
+	b := pkgsyms.Of("github.com/skillian/pkgsyms_test/merge/b")
+	b.Add(pkgsyms.MakeConst("Shared", "b"))
+	value := func(syms *pkgsyms.Symbols, name string) interface{} {
+		s, err := syms.Lookup(name)
+		if err != nil {
+			return err
+		}
+		return s.Get()
+	}
+
+	var syms pkgsyms.Symbols
+	cs, err := syms.Merge(pkgsyms.MergeError, a, b)
+	var ce pkgsyms.CollisionError
+	if !errors.As(err, &ce) || len(cs) != 1 || cs[0].Name != "Shared" || len(cs[0].Pkgs) != 2 {
+		t.Fatalf("expected a collision of Shared but got %v, %v", cs, err)
+	}
+	if syms.Len() != 0 {
+		t.Fatal("expected nothing to be merged")
+	}
+
+	tests := []struct {
+		policy pkgsyms.MergePolicy
+		want   map[string]interface{}
+	}{
+		{pkgsyms.MergeFirstWins, map[string]interface{}{"Only": "a", "Shared": "a"}},
+		{pkgsyms.MergeLastWins, map[string]interface{}{"Only": "a", "Shared": "b"}},
+		{pkgsyms.MergeQualify, map[string]interface{}{"Only": "a", "a.Shared": "a", "b.Shared": "b"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			var syms pkgsyms.Symbols
+			if _, err := syms.Merge(tc.policy, a, b); err != nil {
+				t.Fatal(err)
+			}
+			if syms.Len() != len(tc.want) {
+				t.Fatalf("expected %d symbols but got %d", len(tc.want), syms.Len())
+			}
+			for name, want := range tc.want {
+				if got := value(&syms, name); got != want {
+					t.Fatalf("expected %s to be %v but got %v", name, want, got)
+				}
+			}
+		})
+	}
+	if got := pkgsyms.MergePolicy(-1).String(); got != "MergePolicy(-1)" {
+		t.Fatalf("expected MergePolicy(-1) but got %q", got)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	var x, y int
+	tags := []string{"a"}
+	adder := func(n int) func(int) int { return func(m int) int { return n + m } }
+	add1 := pkgsyms.MakeFunc("Add", adder(1))
+	for _, tc := range []struct {
+		a, b  pkgsyms.Symbol
+		equal bool
+	}{
+		{pkgsyms.MakeConst("A", 1), pkgsyms.MakeConst("A", 1).WithDoc("A is one."), true},
+		{pkgsyms.MakeConst("A", 1), pkgsyms.MakeConst("A", int64(1)), false},
+		{pkgsyms.MakeConst("A", 1), pkgsyms.MakeConst("B", 1), false},
+		{pkgsyms.MakeConst("Tags", tags), pkgsyms.MakeConst("Tags", []string{"a"}), true},
+		{pkgsyms.MakeVar("X", &x), pkgsyms.MakeVar("X", &x).ReadOnly(), true},
+		{pkgsyms.MakeVar("X", &x), pkgsyms.MakeVar("X", &y), false},
+		{pkgsyms.MakeVar("X", &x), pkgsyms.MakeConst("X", &x), false},
+		{pkgsyms.MakeFunc("Join", strings.Join), pkgsyms.MakeFunc("Join", strings.Join), true},
+		{pkgsyms.MakeFunc("F", strings.ToUpper), pkgsyms.MakeFunc("F", strings.ToLower), false},
+		{add1, add1.WithDoc("Add adds one."), true},
+		{add1, pkgsyms.MakeFunc("Add", adder(2)), false},
+		{pkgsyms.MakeType("T", (*testNames)(nil)), pkgsyms.MakeType("T", (*testNames)(nil)), true},
+		{pkgsyms.MakeType("T", (*testNames)(nil)), pkgsyms.MakeType("T", (*[]string)(nil)), false},
+		{pkgsyms.MakeConstraint("N", "~int"), pkgsyms.MakeConstraint("N", "~int"), true},
+		{nil, pkgsyms.MakeConst("A", 1), false},
+	} {
+		if got := pkgsyms.Equal(tc.a, tc.b); got != tc.equal {
+			t.Errorf("Equal(%#v, %#v): expected %v", tc.a, tc.b, tc.equal)
+		}
+	}
+
+	a := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/equal/a")
+	a.Add(pkgsyms.MakeFunc("Join", strings.Join), pkgsyms.MakeConst("Version", 1))
+	b := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/equal/b")
+	b.Add(pkgsyms.MakeFunc("Join", strings.Join))
+	var syms pkgsyms.Symbols
+	if cs, err := syms.Merge(pkgsyms.MergeError, a, b); err != nil || len(cs) != 0 {
+		t.Fatalf("expected the same Join in both packages not to collide, got %v, %v", cs, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := a.Watch(ctx)
+	rebuilt := pkgsyms.MakeSymbols(2)
+	rebuilt.Add(pkgsyms.MakeFunc("Join", strings.Join), pkgsyms.MakeConst("Version", 2))
+	a.Replace(&rebuilt)
+	var got []string
+	for len(got) < 2 {
+		c := <-changes
+		got = append(got, c.Kind.String()+" "+c.Symbol.Name())
+	}
+	select {
+	case c := <-changes:
+		got = append(got, c.Kind.String()+" "+c.Symbol.Name())
+	case <-time.After(10 * time.Millisecond):
+	}
+	if strings.Join(got, ",") != "remove Version,add Version" {
+		t.Fatalf("expected only Version to change, got %v", got)
+	}
+}
+
+// hostPoint and pluginPoint stand for the same type in a host and a plugin:
+// Both are named Point in this package, but they're different types.
+func hostPoint() reflect.Type {
+	type Point struct {
+		X, Y  int
+		Label *string
+		Tags  map[string][]int
+	}
+	return reflect.TypeOf(Point{})
+}
+
+func pluginPoint() reflect.Type {
+	type Point struct {
+		X, Y  int
+		Label *string
+		Tags  map[string][]int
+	}
+	return reflect.TypeOf(Point{})
+}
+
+func TestTypeIdentity(t *testing.T) {
+	host, plugin := hostPoint(), pluginPoint()
+	if host == plugin || pkgsyms.TypeID(host) != pkgsyms.TypeID(plugin) {
+		t.Fatalf("expected different types with the same ID, got %v and %v", host, plugin)
+	}
+	if id := pkgsyms.TypeID(reflect.TypeOf(map[string][]*time.Duration{})); id != `map[string][]*"time".Duration` {
+		t.Fatalf("unexpected TypeID %s", id)
+	}
+	a := pkgsyms.Symbol(pkgsyms.MakeType("Point", reflect.New(host).Interface()))
+	b := pkgsyms.Symbol(pkgsyms.MakeVar("Origin", reflect.New(plugin).Interface()))
+	if !pkgsyms.SameType(a, b) || pkgsyms.SameType(a, pkgsyms.MakeConst("One", 1)) {
+		t.Fatal("expected only the Points to be the same type")
+	}
+
+	label := "p"
+	v := reflect.New(host).Elem()
+	v.Field(0).SetInt(1)
+	v.Field(2).Set(reflect.ValueOf(&label))
+	v.Field(3).Set(reflect.ValueOf(map[string][]int{"a": {1, 2}}))
+	res, err := pkgsyms.Convert(v.Interface(), plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := reflect.ValueOf(res)
+	if got.Type() != plugin || got.Field(0).Int() != 1 || *got.Field(2).Interface().(*string) != "p" ||
+		!reflect.DeepEqual(got.Field(3).Interface(), map[string][]int{"a": {1, 2}}) {
+		t.Fatalf("unexpected conversion %#v", res)
+	}
+	if _, err := pkgsyms.Convert(v.Interface(), reflect.TypeOf(time.Time{})); err == nil {
+		t.Fatal("expected an error converting to a different type")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/watch")
+	p.Add(pkgsyms.MakeConst("Before", 0))
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := p.Watch(ctx)
+	p.Add(pkgsyms.MakeConst("A", 1), pkgsyms.MakeConst("B", 2))
+	p.Remove("A", "Before")
+	var got []string
+	for len(got) < 4 {
+		c := <-changes
+		got = append(got, c.Kind.String()+" "+c.Symbol.Name())
+	}
+	if want := "add A,add B,remove A,remove Before"; strings.Join(got, ",") != want {
+		t.Fatalf("expected %s but got %s", want, strings.Join(got, ","))
+	}
+	cancel()
+	for c := range changes {
+		t.Fatalf("unexpected change %v after cancel", c)
+	}
+	if got := pkgsyms.ChangeKind(2).String(); got != "ChangeKind(2)" {
+		t.Fatalf("expected ChangeKind(2) but got %q", got)
+	}
+}
+
+func TestPage(t *testing.T) {
+	syms := pkgsyms.MakeSymbols(0)
+	syms.Add(
+		pkgsyms.MakeFunc("Join", strings.Join),
+		pkgsyms.MakeConst("Answer", 42),
+		pkgsyms.MakeType("Builder", (*strings.Builder)(nil)),
+		pkgsyms.MakeConst("Pi", 3.14),
+	)
+	names := func(ss []pkgsyms.Symbol) string {
+		var names []string
+		for _, s := range ss {
+			names = append(names, s.Name())
+		}
+		return strings.Join(names, ",")
+	}
+	for _, tc := range []struct {
+		offset, limit int
+		order         pkgsyms.SortOrder
+		want          string
+	}{
+		{0, 0, pkgsyms.SortAdded, "Join,Answer,Builder,Pi"},
+		{1, 2, pkgsyms.SortAdded, "Answer,Builder"},
+		{0, 3, pkgsyms.SortName, "Answer,Builder,Join"},
+		{3, 3, pkgsyms.SortName, "Pi"},
+		{0, 0, pkgsyms.SortKind, "Answer,Pi,Builder,Join"},
+		{4, 1, pkgsyms.SortKind, ""},
+	} {
+		if got := names(syms.Page(tc.offset, tc.limit, tc.order)); got != tc.want {
+			t.Errorf("Page(%d, %d, %v): expected %q but got %q", tc.offset, tc.limit, tc.order, tc.want, got)
+		}
+	}
+	syms.Add(pkgsyms.MakeConst("Zero", 0))
+	syms.Remove("Answer")
+	if got := names(syms.Page(0, 0, pkgsyms.SortName)); got != "Builder,Join,Pi,Zero" {
+		t.Fatalf("expected the sorted page to follow changes, got %q", got)
+	}
+	if o, err := pkgsyms.ParseSortOrder(pkgsyms.SortKind.String()); err != nil || o != pkgsyms.SortKind {
+		t.Fatalf("expected %v but got %v, %v", pkgsyms.SortKind, o, err)
+	}
+}
+
+func TestLookupMany(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/lookupmany")
+	p.Add(
+		pkgsyms.MakeFunc("Join", strings.Join),
+		pkgsyms.MakeConst("Answer", 42),
+		pkgsyms.MakeConst("Secret", "xyzzy"),
+	)
+	found, missing := p.Symbols.LookupMany("Join", "Missing", "Answer", "Other")
+	if len(found) != 2 || found["Join"] == nil || found["Answer"] == nil {
+		t.Fatalf("expected Join and Answer but found %v", found)
+	}
+	if strings.Join(missing, ",") != "Missing,Other" {
+		t.Fatalf("expected Missing and Other to be missing, got %v", missing)
+	}
+	p.SetAuthorizer(func(sym pkgsyms.Symbol, caller interface{}) error {
+		if sym.Name() == "Secret" {
+			return errors.New("denied")
+		}
+		return nil
+	})
+	found, missing = p.LookupMany("Secret", "Answer", "Missing")
+	if len(found) != 1 || found["Answer"] == nil {
+		t.Fatalf("expected only Answer but found %v", found)
+	}
+	if strings.Join(missing, ",") != "Secret,Missing" {
+		t.Fatalf("expected Secret and Missing to be missing, got %v", missing)
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/authorizer")
+	p.Add(pkgsyms.MakeFunc("Join", strings.Join), pkgsyms.MakeFunc("ToUpper", strings.ToUpper))
+	denied := errors.New("not allowed")
+	p.SetAuthorizer(func(sym pkgsyms.Symbol, caller interface{}) error {
+		if caller == "admin" || sym.Name() == "ToUpper" {
+			return nil
+		}
+		return denied
+	})
+	if _, err := p.Lookup("Join"); !errors.Is(err, pkgsyms.ErrUnauthorized) || !errors.Is(err, denied) {
+		t.Fatalf("expected Join to be denied, not %v", err)
+	}
+	if _, err := p.LookupFunc("Join"); !errors.Is(err, pkgsyms.ErrUnauthorized) {
+		t.Fatalf("expected LookupFunc to be denied, not %v", err)
+	}
+	if _, err := p.CallAs("guest", "Join", []string{"a", "b"}, ","); !errors.Is(err, denied) {
+		t.Fatalf("expected guest to be denied, not %v", err)
+	}
+	res, err := p.CallAs("admin", "Join", []string{"a", "b"}, ",")
+	if err != nil || res[0] != "a,b" {
+		t.Fatalf("expected admin to call Join but got %v, %v", res, err)
+	}
+	if res, err = p.CallAs(nil, "ToUpper", "x"); err != nil || res[0] != "X" {
+		t.Fatalf("expected ToUpper to be allowed but got %v, %v", res, err)
+	}
+	var names []string
+	p.RangeAs("guest", func(s pkgsyms.Symbol) bool {
+		names = append(names, s.Name())
+		return true
+	})
+	if strings.Join(names, ",") != "ToUpper" {
+		t.Fatalf("expected guest to range over only ToUpper, not %v", names)
+	}
+	p.SetAuthorizer(nil)
+	if _, err := p.Lookup("Join"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTrackUsage(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/usage")
+	p.Add(pkgsyms.MakeFunc("ToUpper", strings.ToUpper), pkgsyms.MakeConst("Answer", 42))
+	if _, err := p.Lookup("Answer"); err != nil {
+		t.Fatal(err)
+	}
+	if u := p.Usage(); u != nil {
+		t.Fatalf("expected no usage before tracking, not %v", u)
+	}
+	p.TrackUsage(true)
+	f, err := p.LookupFunc("ToUpper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := f.Call("x"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := p.CallAs(nil, "ToUpper", "y"); err != nil {
+		t.Fatal(err)
+	}
+	p.Lookup("Answer")
+	want := []pkgsyms.Usage{
+		{Sym: "Answer", Lookups: 1},
+		{Sym: "ToUpper", Lookups: 2, Calls: 4},
+	}
+	if got := p.Usage(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v but got %v", want, got)
+	}
+	p.TrackUsage(false)
+	if u := p.Usage(); u != nil {
+		t.Fatalf("expected no usage after tracking, not %v", u)
+	}
+}
+
+func TestInvokeError(t *testing.T) {
+	f := pkgsyms.MakeFunc("Index", func(s []int, i int) int { return s[i] })
+	_, err := f.Call([]int{1}, 5)
+	var ie pkgsyms.InvokeError
+	if !errors.As(err, &ie) || ie.Sym != "Index" || len(ie.Stack) == 0 {
+		t.Fatalf("expected an InvokeError from Index, not %v", err)
+	}
+	var re runtime.Error
+	if !errors.As(err, &re) {
+		t.Fatalf("expected %v to wrap a runtime.Error", err)
+	}
+	f = pkgsyms.MakeFunc("Fail", func() { panic("boom") })
+	if _, err = f.Call(); !errors.As(err, &ie) || ie.Recovered != "boom" {
+		t.Fatalf("expected boom to be recovered, not %v", err)
+	}
+	n := 1
+	if err = pkgsyms.MakeVar("N", &n).SetE("one"); !errors.As(err, &ie) {
+		t.Fatalf("expected setting a string to be an InvokeError, not %v", err)
+	}
+}
+
+func TestCallWithTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	slow := pkgsyms.MakeFunc("Slow", func() int { <-block; return 1 })
+	_, err := slow.CallWithTimeout(context.Background(), 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, not %v", err)
+	}
+	stopped := make(chan error, 1)
+	coop := pkgsyms.MakeFunc("Coop", func(ctx context.Context) {
+		<-ctx.Done()
+		stopped <- ctx.Err()
+	})
+	if _, err = coop.CallWithTimeout(context.Background(), 10*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, not %v", err)
+	}
+	if err := <-stopped; err == nil {
+		t.Fatal("expected the abandoned call's context to be done")
+	}
+	res, err := pkgsyms.MakeFunc("Fast", strings.ToUpper).CallWithTimeout(context.Background(), 0, "x")
+	if err != nil || res[0] != "X" {
+		t.Fatalf("expected X but got %v, %v", res, err)
+	}
+}
+
+func TestCallJSON(t *testing.T) {
+	join := pkgsyms.MakeFunc("Join", strings.Join)
+	got, err := join.CallJSON([]byte(`[["a", "b"], "-"]`))
+	if err != nil || string(got) != `["a-b"]` {
+		t.Fatalf("expected [\"a-b\"] but got %s, %v", got, err)
+	}
+	type point struct{ X, Y int }
+	add := pkgsyms.MakeFunc("Add", func(ps ...point) (point, error) {
+		var sum point
+		for _, p := range ps {
+			if p.X < 0 {
+				return point{}, errors.New("negative")
+			}
+			sum.X += p.X
+			sum.Y += p.Y
+		}
+		return sum, nil
+	})
+	if got, err = add.CallJSON([]byte(`[{"X": 1, "Y": 2}, {"X": 3, "Y": 4}]`)); err != nil || string(got) != `[{"X":4,"Y":6}]` {
+		t.Fatalf("expected the sum but got %s, %v", got, err)
+	}
+	if _, err = add.CallJSON([]byte(`[{"X": -1}]`)); err == nil || err.Error() != "negative" {
+		t.Fatalf("expected the function's error, not %v", err)
+	}
+	for _, args := range []string{`{}`, `[1]`, `[["a"]]`} {
+		if _, err = join.CallJSON([]byte(args)); err == nil {
+			t.Errorf("expected an error calling Join with %s", args)
+		}
+	}
+	_, callErr := join.Call("a")
+	if _, err = join.CallJSON([]byte(`["a"]`)); err == nil || callErr == nil || err.Error() != callErr.Error() {
+		t.Fatalf("expected the error of Call, %v, but got %v", callErr, err)
+	}
+}
+
+type openAPINode struct {
+	Name     string
+	Children []*openAPINode `json:"children,omitempty"`
+	secret   int
+}
+
+func TestOpenAPI(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/openapi")
+	p.Add(
+		pkgsyms.MakeFunc("Join", strings.Join).WithDoc("Join joins strings.\nMore."),
+		pkgsyms.MakeFunc("Walk", func(n *openAPINode, depth int) ([]string, error) { return nil, nil }),
+		pkgsyms.MakeFunc("Sum", func(xs ...float64) float64 { return 0 }),
+		pkgsyms.MakeFunc("Chan", func(c chan int) {}),
+		pkgsyms.MakeConst("Answer", 42),
+	)
+	data, err := p.OpenAPI("/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Paths map[string]struct {
+			Post struct {
+				OperationID string `json:"operationId"`
+				Summary     string
+				RequestBody struct {
+					Content map[string]struct {
+						Schema map[string]interface{}
+					}
+				} `json:"requestBody"`
+			}
+		}
+		Components struct {
+			Schemas map[string]map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if want := "/api/Join,/api/Sum,/api/Walk"; strings.Join(paths, ",") != want {
+		t.Fatalf("expected paths %s but got %s", want, strings.Join(paths, ","))
+	}
+	if s := doc.Paths["/api/Join"].Post.Summary; s != "Join joins strings." {
+		t.Fatalf("expected Join's summary, not %q", s)
+	}
+	args := doc.Paths["/api/Sum"].Post.RequestBody.Content["application/json"].Schema
+	if items, _ := args["items"].(map[string]interface{}); items["type"] != "number" {
+		t.Fatalf("expected variadic numbers, not %v", args)
+	}
+	node := doc.Components.Schemas["github.com.skillian.pkgsyms_test.openAPINode"]
+	props, _ := node["properties"].(map[string]interface{})
+	if len(props) != 2 || props["children"] == nil || props["Name"] == nil {
+		t.Fatalf("expected the Name and children properties, not %v", node)
+	}
+}
+
+func TestReplacePackage(t *testing.T) {
+	const name = "github.com/skillian/pkgsyms_test/reload"
+	reg := pkgsyms.NewRegistry()
+	if err := reg.ReplacePackage(name, nil); !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected NotFound replacing an unregistered package, not %v", err)
+	}
+	p := reg.Of(name)
+	p.Add(pkgsyms.MakeConst("Version", 1), pkgsyms.MakeConst("Old", true))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := p.Watch(ctx)
+
+	rebuilt := pkgsyms.MakeSymbols(2)
+	rebuilt.Add(pkgsyms.MakeConst("Version", 2), pkgsyms.MakeConst("New", true))
+	if err := reg.ReplacePackage(name, &rebuilt); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for len(got) < 4 {
+		c := <-changes
+		got = append(got, fmt.Sprint(c.Kind, " ", c.Symbol.Name(), "=", c.Symbol.Get()))
+	}
+	want := "remove Version=1,remove Old=true,add Version=2,add New=true"
+	if strings.Join(got, ",") != want {
+		t.Fatalf("expected %s but got %s", want, strings.Join(got, ","))
+	}
+	if s, err := p.Lookup("Version"); err != nil || s.Get() != 2 {
+		t.Fatalf("expected Version 2 but got %v, %v", s, err)
+	}
+	if _, err := p.Lookup("Old"); !errors.Is(err, pkgsyms.ErrNotFound) {
+		t.Fatalf("expected Old to be gone, not %v", err)
+	}
+	p.Replace(&p.Symbols)
+	if p.Len() != 2 {
+		t.Fatalf("expected replacing the package with itself to keep 2 symbols, not %d", p.Len())
+	}
+}
+
+func TestWriteGo(t *testing.T) {
+	var greeting string
+	var counts map[string]int
+	p := pkgsyms.NewRegistry().Of("example.com/basic")
+	p.SetDoc("Package basic is an example.")
+	p.Add(
+		pkgsyms.MakeConst("Answer", 42).WithDoc("Answer is a constant."),
+		pkgsyms.MakeFunc("Hello", strings.ToUpper),
+		pkgsyms.MakeVar("Greeting", &greeting).ReadOnly(),
+		pkgsyms.MakeVar("Counts", &counts).Guarded(),
+		pkgsyms.MakeType("Names", (*testNames)(nil)).WithUnderlying("[]string").WithImplements("sort.Interface"),
+		pkgsyms.MakeGeneric("Sum", pkgsyms.TypeParam{Name: "T", Constraint: "Number"}),
+	)
+	p.AddConditional(pkgsyms.MakeConst("Linux", true))
+	var buf bytes.Buffer
+	err := pkgsyms.WriteGo(&buf, p, pkgsyms.GoOptions{
+		Package:   "basicsyms",
+		Qualifier: "basic.",
+		Imports:   []string{"example.com/basic"},
+		Docs:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := buf.String()
+	for _, want := range []string{
+		"// Code generated by \"pkgsyms.WriteGo\"; DO NOT EDIT.",
+		"package basicsyms",
+		"\t\"example.com/basic\"",
+		`var Pkg = pkgsyms.Of("example.com/basic")`,
+		`const pkgChecksum = "` + p.Checksum() + `"`,
+		`Pkg.SetDoc("Package basic is an example.")`,
+		`pkgsyms.MakeConst("Answer", basic.Answer).WithDoc("Answer is a constant."),`,
+		`pkgsyms.MakeFunc("Hello", basic.Hello),`,
+		`pkgsyms.MakeVar("Greeting", &basic.Greeting).ReadOnly(),`,
+		`pkgsyms.MakeVar("Counts", &basic.Counts).Guarded(),`,
+		`pkgsyms.MakeType("Names", (*basic.Names)(nil)).WithUnderlying("[]string").WithImplements("sort.Interface"),`,
+		`pkgsyms.MakeGeneric("Sum", pkgsyms.TypeParam{Name: "T", Constraint: "Number"}),`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("expected %s in:\n%s", want, src)
+		}
+	}
+	if strings.Contains(src, "Linux") {
+		t.Errorf("expected the conditional symbol to be left out of:\n%s", src)
+	}
+	p.Add(pkgsyms.MakeConst("basic.Qualified", 1))
+	if err := pkgsyms.WriteGo(io.Discard, p, pkgsyms.GoOptions{}); err == nil {
+		t.Fatal("expected an error writing a symbol whose name isn't an identifier")
+	}
+}
+
+func TestSaveState(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/state")
+	level, limits, hook := "info", map[string]int{"rps": 10}, func() {}
+	frozen := 1
+	logger := log.New(io.Discard, "", 0)
+	var out io.Writer = io.Discard
+	p.Add(
+		pkgsyms.MakeVar("Level", &level),
+		pkgsyms.MakeVar("Limits", &limits),
+		pkgsyms.MakeVar("Hook", &hook),
+		pkgsyms.MakeVar("Frozen", &frozen).ReadOnly(),
+		pkgsyms.MakeVar("Logger", &logger),
+		pkgsyms.MakeVar("Out", &out),
+	)
+	var buf bytes.Buffer
+	if err := p.SaveState(&buf); err != nil {
+		t.Fatal(err)
+	}
+	saved := buf.String()
+	for _, name := range []string{"Hook", "Frozen", "Logger", "Out"} {
+		if strings.Contains(saved, name) {
+			t.Fatalf("expected %s not to be saved in:\n%s", name, saved)
+		}
+	}
+	level, limits = "debug", nil
+	if err := p.LoadState(strings.NewReader(saved)); err != nil {
+		t.Fatal(err)
+	}
+	if level != "info" || limits["rps"] != 10 {
+		t.Fatalf("expected the saved values but got %q, %v", level, limits)
+	}
+	if err := p.LoadState(strings.NewReader(`{"Level": 1}`)); err == nil {
+		t.Fatal("expected an error loading a number into a string")
+	}
+	if err := p.LoadState(strings.NewReader(`{"Frozen": 2}`)); err != nil || frozen != 1 {
+		t.Fatalf("expected Frozen to be ignored but got %d, %v", frozen, err)
+	}
+	if err := p.LoadState(strings.NewReader(`{"Gone": 2}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.LoadState(strings.NewReader(`{"Logger": {}, "Out": {}}`)); err != nil {
+		t.Fatal(err)
+	}
+	logger.Print("still works")
+	if out != io.Discard {
+		t.Fatalf("expected Out to be unchanged but got %#v", out)
+	}
+	if err := p.LoadState(strings.NewReader(`{"Level": "warn", "Limits": "bad"}`)); err == nil {
+		t.Fatal("expected an error loading a string into a map")
+	}
+	if level != "info" {
+		t.Fatalf("expected a failed load to leave Level unchanged but got %q", level)
+	}
+}
+
+func TestGuarded(t *testing.T) {
+	counts := map[string]int{}
+	events := make(chan string)
+	m, ok := pkgsyms.MakeVar("Counts", &counts).Guarded().Map()
+	if !ok {
+		t.Fatal("expected a MapVar of a guarded map")
+	}
+	if _, ok := pkgsyms.MakeVar("Counts", &counts).Map(); ok {
+		t.Fatal("expected no MapVar of an unguarded map")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := m.Put(fmt.Sprint(i), i); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if v, ok := m.Get("3"); !ok || v != 3 || m.Len() != 8 {
+		t.Fatalf("expected 8 entries with 3 = 3 but got %d, %v, %v", m.Len(), v, ok)
+	}
+	if err := m.Put(1, 1); err == nil {
+		t.Fatal("expected an error putting a key of the wrong type")
+	}
+	if err := m.Delete("3"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Get("3"); ok {
+		t.Fatal("expected 3 to be deleted")
+	}
+
+	v := pkgsyms.MakeVar("Events", &events).Guarded()
+	c, ok := v.Chan()
+	if !ok {
+		t.Fatal("expected a ChanVar of a guarded channel")
+	}
+	go c.Send(context.Background(), "hello")
+	if e, ok, err := c.Recv(context.Background()); err != nil || !ok || e != "hello" {
+		t.Fatalf("expected to receive hello but got %v, %v, %v", e, ok, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := c.Send(ctx, "lost"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected Send to be canceled but got %v", err)
+	}
+	close(events)
+	if _, ok, err := c.Recv(context.Background()); err != nil || ok {
+		t.Fatalf("expected to receive from a closed channel but got %v, %v", ok, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected guarding a slice to panic")
+		}
+	}()
+	var list []string
+	pkgsyms.MakeVar("List", &list).Guarded()
+}
+
+func TestConstGroup(t *testing.T) {
+	p := pkgsyms.NewRegistry().Of("github.com/skillian/pkgsyms_test/constgroup")
+	p.Add(
+		pkgsyms.MakeConst("Red", 0).WithGroup("Red"),
+		pkgsyms.MakeConst("Pi", 3.14),
+		pkgsyms.MakeConst("Green", 1).WithGroup("Red"),
+		pkgsyms.MakeConst("Blue", 2).WithGroup("Red"),
+	)
+	var names []string
+	for _, c := range p.ConstGroup("Red") {
+		names = append(names, c.Name())
+	}
+	if strings.Join(names, " ") != "Red Green Blue" {
+		t.Fatalf("expected Red Green Blue but got %v", names)
+	}
+	if c, _ := p.LookupConst("Pi"); c.Group() != "" {
+		t.Fatalf("expected Pi to have no group, not %q", c.Group())
+	}
+	if cs := p.ConstGroup(""); len(cs) != 0 {
+		t.Fatalf("expected no constants in the empty group but got %v", cs)
+	}
+}
@@ -0,0 +1,109 @@
+// Package symtest has helpers for testing the registries generated by the
+// pkgsyms command.
+package symtest
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+)
+
+// Update makes RequireManifest write its golden files instead of comparing
+// them.  Tests usually set it from a flag:
+//
+//	var update = flag.Bool("update", false, "update golden files")
+//
+//	func TestMain(m *testing.M) {
+//		flag.Parse()
+//		symtest.Update = *update
+//		os.Exit(m.Run())
+//	}
+var Update bool
+
+// RequireSymbols fails the test unless every name is registered in p.
+func RequireSymbols(t testing.TB, p *pkgsyms.Package, names ...string) {
+	t.Helper()
+	var missing []string
+	for _, name := range names {
+		if _, err := p.Lookup(name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		t.Fatalf("package %q: missing symbols %s", p.Name, strings.Join(missing, ", "))
+	}
+}
+
+// RequireKind fails the test unless name is registered in p with the kind.
+func RequireKind(t testing.TB, p *pkgsyms.Package, name string, kind pkgsyms.Kind) {
+	t.Helper()
+	s, err := p.Lookup(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pkgsyms.KindOf(s); got != kind {
+		t.Fatalf("%s: expected %v, not %v", pkgsyms.ID(p.Name, name), kind, got)
+	}
+}
+
+// Manifest lists the kinds and names of the symbols in p, one "Kind Name"
+// line per symbol, sorted like the lines that pkgsyms.ChecksumOf hashes.
+func Manifest(p *pkgsyms.Package) string {
+	var lines []string
+	p.Range(func(s pkgsyms.Symbol) bool {
+		lines = append(lines, pkgsyms.KindOf(s).String()+" "+s.Name())
+		return true
+	})
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// RequireManifest fails the test unless the Manifest of p matches the golden
+// file.  With Update, the golden file is written instead.
+func RequireManifest(t testing.TB, p *pkgsyms.Package, filename string) {
+	t.Helper()
+	got := Manifest(p)
+	if Update {
+		if err := os.WriteFile(filename, []byte(got), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("%s doesn't exist; run the test with symtest.Update set to create it", filename)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == string(want) {
+		return
+	}
+	added, removed := diffLines(string(want), got)
+	t.Fatalf("package %q doesn't match %s:\n\tadded: %s\n\tremoved: %s",
+		p.Name, filename, strings.Join(added, ", "), strings.Join(removed, ", "))
+}
+
+// diffLines gets the lines that are only in got and only in want.
+func diffLines(want, got string) (added, removed []string) {
+	wantSet := make(map[string]bool)
+	for _, l := range strings.Split(strings.TrimSpace(want), "\n") {
+		wantSet[l] = true
+	}
+	for _, l := range strings.Split(strings.TrimSpace(got), "\n") {
+		if wantSet[l] {
+			delete(wantSet, l)
+		} else {
+			added = append(added, l)
+		}
+	}
+	for l := range wantSet {
+		removed = append(removed, l)
+	}
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,72 @@
+package symtest_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/skillian/pkgsyms"
+	"github.com/skillian/pkgsyms/symtest"
+)
+
+// recorder is a testing.TB that records its failure instead of failing.
+type recorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Fatal(args ...interface{}) {
+	r.failure = fmt.Sprint(args...)
+	runtime.Goexit()
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failure = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// failure runs f and returns its failure, if any.
+func failure(t *testing.T, f func(t testing.TB)) string {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(r)
+	}()
+	<-done
+	return r.failure
+}
+
+func TestRequire(t *testing.T) {
+	p := pkgsyms.Of("github.com/skillian/pkgsyms/symtest_test")
+	p.Add(
+		pkgsyms.MakeFunc("Foo", func() {}),
+		pkgsyms.MakeConst("Bar", 1),
+	)
+	symtest.RequireSymbols(t, p, "Foo", "Bar")
+	symtest.RequireKind(t, p, "Foo", pkgsyms.FuncKind)
+	if msg := failure(t, func(t testing.TB) { symtest.RequireSymbols(t, p, "Foo", "Baz") }); !strings.Contains(msg, "missing symbols Baz") {
+		t.Fatalf("expected Baz to be missing, got %q", msg)
+	}
+	if msg := failure(t, func(t testing.TB) { symtest.RequireKind(t, p, "Bar", pkgsyms.FuncKind) }); !strings.Contains(msg, "expected Func, not Const") {
+		t.Fatalf("expected a wrong kind, got %q", msg)
+	}
+
+	golden := filepath.Join(t.TempDir(), "symbols.golden")
+	symtest.Update = true
+	symtest.RequireManifest(t, p, golden)
+	symtest.Update = false
+	symtest.RequireManifest(t, p, golden)
+	if data, _ := os.ReadFile(golden); string(data) != "Const Bar\nFunc Foo\n" {
+		t.Fatalf("unexpected manifest %q", data)
+	}
+	p.Add(pkgsyms.MakeConst("Baz", 2))
+	if msg := failure(t, func(t testing.TB) { symtest.RequireManifest(t, p, golden) }); !strings.Contains(msg, "added: Const Baz") {
+		t.Fatalf("expected Baz to be reported, got %q", msg)
+	}
+}
@@ -0,0 +1,249 @@
+package pkgsyms
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// TypeID gets a description of t that's the same for the same type in
+// different binaries, like a host and a plugin that each link their own copy
+// of a package and so have different reflect.Types for it.  Named types are
+// identified by their package paths and names and other types by their
+// structure.
+func TypeID(t reflect.Type) string {
+	var sb strings.Builder
+	writeTypeID(&sb, t)
+	return sb.String()
+}
+
+func writeTypeID(sb *strings.Builder, t reflect.Type) {
+	if t.Name() != "" {
+		if p := t.PkgPath(); p != "" {
+			sb.WriteString(strconv.Quote(p))
+			sb.WriteByte('.')
+		}
+		sb.WriteString(t.Name())
+		return
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		sb.WriteByte('*')
+		writeTypeID(sb, t.Elem())
+	case reflect.Slice:
+		sb.WriteString("[]")
+		writeTypeID(sb, t.Elem())
+	case reflect.Array:
+		fmt.Fprintf(sb, "[%d]", t.Len())
+		writeTypeID(sb, t.Elem())
+	case reflect.Map:
+		sb.WriteString("map[")
+		writeTypeID(sb, t.Key())
+		sb.WriteByte(']')
+		writeTypeID(sb, t.Elem())
+	case reflect.Chan:
+		switch t.ChanDir() {
+		case reflect.RecvDir:
+			sb.WriteString("<-chan ")
+		case reflect.SendDir:
+			sb.WriteString("chan<- ")
+		default:
+			sb.WriteString("chan ")
+		}
+		writeTypeID(sb, t.Elem())
+	case reflect.Func:
+		sb.WriteString("func(")
+		for i := 0; i < t.NumIn(); i++ {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if t.IsVariadic() && i == t.NumIn()-1 {
+				sb.WriteString("...")
+				writeTypeID(sb, t.In(i).Elem())
+				continue
+			}
+			writeTypeID(sb, t.In(i))
+		}
+		sb.WriteString(") (")
+		for i := 0; i < t.NumOut(); i++ {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			writeTypeID(sb, t.Out(i))
+		}
+		sb.WriteByte(')')
+	case reflect.Struct:
+		sb.WriteString("struct{")
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if i > 0 {
+				sb.WriteString("; ")
+			}
+			if f.PkgPath != "" {
+				// Unexported fields are qualified by their
+				// packages like named types.
+				sb.WriteString(strconv.Quote(f.PkgPath))
+				sb.WriteByte('.')
+			}
+			sb.WriteString(f.Name)
+			if f.Anonymous {
+				sb.WriteString(" embedded")
+			}
+			sb.WriteByte(' ')
+			writeTypeID(sb, f.Type)
+			if f.Tag != "" {
+				sb.WriteByte(' ')
+				sb.WriteString(strconv.Quote(string(f.Tag)))
+			}
+		}
+		sb.WriteByte('}')
+	case reflect.Interface:
+		sb.WriteString("interface{")
+		for i := 0; i < t.NumMethod(); i++ {
+			m := t.Method(i)
+			if i > 0 {
+				sb.WriteString("; ")
+			}
+			if m.PkgPath != "" {
+				sb.WriteString(strconv.Quote(m.PkgPath))
+				sb.WriteByte('.')
+			}
+			sb.WriteString(m.Name)
+			sb.WriteByte(' ')
+			writeTypeID(sb, m.Type)
+		}
+		sb.WriteByte('}')
+	default:
+		sb.WriteString(t.String())
+	}
+}
+
+// SameType reports whether the symbols have the same type by their TypeIDs,
+// even if they come from different binaries.  Types are compared by the
+// types they define, Vars by the types of their variables and other symbols
+// by the types of the values they Get.  Generics and Constraints have no
+// types, so they're never the same.
+func SameType(a, b Symbol) bool {
+	at, bt := symbolType(a), symbolType(b)
+	if at == nil || bt == nil {
+		return false
+	}
+	return at == bt || TypeID(at) == TypeID(bt)
+}
+
+// Convert copies v into a value of type to, which must have the same TypeID
+// as v's type, be assignable from it or, for booleans, numbers and strings,
+// be convertible from it.  Values of types that
+// are the same in different binaries are copied field by field and element
+// by element.  Functions, channels and unsafe pointers can only be assigned,
+// so they fail to convert between binaries.
+func Convert(v interface{}, to reflect.Type) (interface{}, error) {
+	if v == nil {
+		return reflect.Zero(to).Interface(), nil
+	}
+	rv, err := convertValue(reflect.ValueOf(v), to)
+	if err != nil {
+		return nil, err
+	}
+	return rv.Interface(), nil
+}
+
+func convertValue(v reflect.Value, to reflect.Type) (reflect.Value, error) {
+	from := v.Type()
+	if from.Kind() == reflect.Interface && from != to {
+		if v.IsNil() {
+			return reflect.Zero(to), nil
+		}
+		return convertValue(v.Elem(), to)
+	}
+	switch {
+	case from.AssignableTo(to):
+		res := reflect.New(to).Elem()
+		res.Set(v)
+		return res, nil
+	case from.Kind() == to.Kind() && isScalar(from.Kind()) && from.ConvertibleTo(to):
+		return v.Convert(to), nil
+	case to.Kind() == reflect.Interface:
+		if !from.Implements(to) {
+			return reflect.Value{}, fmt.Errorf("%v doesn't implement %v", from, to)
+		}
+		res := reflect.New(to).Elem()
+		res.Set(v)
+		return res, nil
+	case from.Kind() != to.Kind() || TypeID(from) != TypeID(to):
+		return reflect.Value{}, fmt.Errorf("cannot convert %v to %v", from, to)
+	}
+	res := reflect.New(to).Elem()
+	switch from.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return res, nil
+		}
+		elem, err := convertValue(v.Elem(), to.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(to.Elem())
+		p.Elem().Set(elem)
+		res.Set(p)
+	case reflect.Slice, reflect.Array:
+		if from.Kind() == reflect.Slice {
+			if v.IsNil() {
+				return res, nil
+			}
+			res.Set(reflect.MakeSlice(to, v.Len(), v.Len()))
+		}
+		for i := 0; i < v.Len(); i++ {
+			elem, err := convertValue(v.Index(i), to.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("index %d: %w", i, err)
+			}
+			res.Index(i).Set(elem)
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return res, nil
+		}
+		res.Set(reflect.MakeMapWithSize(to, v.Len()))
+		iter := v.MapRange()
+		for iter.Next() {
+			k, err := convertValue(iter.Key(), to.Key())
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			e, err := convertValue(iter.Value(), to.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			res.SetMapIndex(k, e)
+		}
+	case reflect.Struct:
+		for i := 0; i < from.NumField(); i++ {
+			if from.Field(i).PkgPath != "" {
+				return reflect.Value{}, fmt.Errorf(
+					"cannot convert %v: unexported field %s", from, from.Field(i).Name)
+			}
+			f, err := convertValue(v.Field(i), to.Field(i).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", from.Field(i).Name, err)
+			}
+			res.Field(i).Set(f)
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot convert %v to %v", from, to)
+	}
+	return res, nil
+}
+
+// isScalar reports whether values of the kind are copied by conversion.
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package pkgsyms
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Usage counts how often a symbol was looked up and called.
+type Usage struct {
+	Sym     string
+	Lookups int64
+	Calls   int64
+}
+
+// usageTable holds a Package's usage counters while TrackUsage is on.
+type usageTable struct {
+	mutex sync.Mutex
+	syms  map[string]*usageCounter
+}
+
+// usageCounter counts a symbol's usage with atomic operations.
+type usageCounter struct {
+	lookups int64
+	calls   int64
+}
+
+// TrackUsage turns counting how often each of the package's symbols is looked
+// up and called on or off, so API owners can learn which parts of their
+// registered surface scripts actually use.  Counting is off by default.
+// Turning it off discards the counts.  Only the Funcs returned by lookups
+// made while counting is on count their calls.
+func (p *Package) TrackUsage(on bool) {
+	p.usageMu.Lock()
+	defer p.usageMu.Unlock()
+	switch {
+	case on && p.usage == nil:
+		p.usage = &usageTable{syms: make(map[string]*usageCounter)}
+	case !on:
+		p.usage = nil
+	}
+}
+
+// Usage gets the counts of the symbols that were used since TrackUsage was
+// turned on, sorted by name.  It's nil if usage isn't tracked.
+func (p *Package) Usage() []Usage {
+	p.usageMu.Lock()
+	t := p.usage
+	p.usageMu.Unlock()
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	us := make([]Usage, 0, len(t.syms))
+	for name, c := range t.syms {
+		us = append(us, Usage{
+			Sym:     name,
+			Lookups: atomic.LoadInt64(&c.lookups),
+			Calls:   atomic.LoadInt64(&c.calls),
+		})
+	}
+	t.mutex.Unlock()
+	sort.Slice(us, func(i, j int) bool { return us[i].Sym < us[j].Sym })
+	return us
+}
+
+// counter gets the usage counter of the symbol with the given name, or nil
+// if usage isn't tracked.
+func (p *Package) counter(name string) *usageCounter {
+	p.usageMu.Lock()
+	t := p.usage
+	p.usageMu.Unlock()
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	c, ok := t.syms[name]
+	if !ok {
+		c = new(usageCounter)
+		t.syms[name] = c
+	}
+	return c
+}
+
+// countLookup counts a lookup of s if usage is tracked and returns s with its
+// calls counted if it's a Func.
+func (p *Package) countLookup(s Symbol) Symbol {
+	c := p.counter(s.Name())
+	if c == nil {
+		return s
+	}
+	atomic.AddInt64(&c.lookups, 1)
+	if f, ok := s.(Func); ok {
+		f.usage = c
+		return f
+	}
+	return s
+}
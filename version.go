@@ -0,0 +1,59 @@
+package pkgsyms
+
+import "fmt"
+
+// APIVersion is the version of this package's API that the pkgsyms command
+// generates files for.  It's incremented when generated files start to rely
+// on something that older versions of this package don't have.
+const APIVersion = 1
+
+// MinAPIVersion is the oldest APIVersion of generated files that this
+// package still supports.
+//
+// Files generated before API versions were recorded don't call SetGenerator
+// and are still supported:  They only use Of, Add and the Make functions,
+// which keep their signatures.  When the API that generated files use has to
+// change, the old functions are kept as wrappers in compat.go until
+// MinAPIVersion passes the last version that generated calls to them.  The
+// pkgsyms command's migrate subcommand regenerates old files.
+const MinAPIVersion = 1
+
+// GeneratorError is the panic value of (*Package).SetGenerator when a
+// package's generated file was written for an APIVersion that this package
+// doesn't support.
+type GeneratorError struct {
+	Pkg     string
+	Version string
+	API     int
+}
+
+func (e GeneratorError) Error() string {
+	return fmt.Sprintf(
+		"package %q: generated by pkgsyms %s for API version %d, but "+
+			"versions %d to %d are supported; regenerate it",
+		e.Pkg, e.Version, e.API, MinAPIVersion, APIVersion)
+}
+
+// SetGenerator records the version of the pkgsyms command that generated the
+// package's file and the APIVersion it was generated for.  Generated files
+// call it before they add their symbols.  Like a manifest that can't be
+// parsed, an unsupported API version is a programming error, so SetGenerator
+// panics with a GeneratorError.
+func (p *Package) SetGenerator(version string, api int) {
+	if api < MinAPIVersion || api > APIVersion {
+		panic(GeneratorError{Pkg: p.Name, Version: version, API: api})
+	}
+	p.docMu.Lock()
+	defer p.docMu.Unlock()
+	p.genVersion = version
+	p.genAPI = api
+}
+
+// Generator gets the version of the pkgsyms command and the APIVersion
+// recorded by SetGenerator.  They're empty and 0 if the package's symbols
+// weren't registered by a generated file.
+func (p *Package) Generator() (version string, api int) {
+	p.docMu.Lock()
+	defer p.docMu.Unlock()
+	return p.genVersion, p.genAPI
+}
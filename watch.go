@@ -0,0 +1,109 @@
+package pkgsyms
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ChangeKind identifies how a Change changed a set of symbols.
+type ChangeKind int
+
+const (
+	// ChangeAdd is sent when a symbol is added to the set.
+	ChangeAdd ChangeKind = iota
+
+	// ChangeRemove is sent when a symbol is removed from the set.
+	ChangeRemove
+)
+
+var changeKindStrings = []string{"add", "remove"}
+
+func (k ChangeKind) String() string {
+	if k < 0 || int(k) >= len(changeKindStrings) {
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+	return changeKindStrings[k]
+}
+
+// Change describes a symbol added to or removed from a set of symbols.
+// Replacing a symbol, like Merge does with MergeLastWins, is sent as a
+// ChangeRemove of the old symbol followed by a ChangeAdd of the new one.
+type Change struct {
+	Kind   ChangeKind
+	Symbol Symbol
+}
+
+// Watch returns a channel that receives the changes made to the set after
+// Watch returns, in the order they were made, until ctx is done, after which
+// the channel is closed.  Changes are queued for slow receivers so that
+// adding and removing symbols never blocks on them.
+func (syms *Symbols) Watch(ctx context.Context) <-chan Change {
+	w := &watcher{
+		wake: make(chan struct{}, 1),
+		out:  make(chan Change),
+	}
+	syms.mutex.Lock()
+	// Manifests would be loaded by the next change anyway, so load
+	// them now so their symbols aren't reported as changes.
+	syms.loadManifests()
+	syms.watchers = append(syms.watchers, w)
+	syms.mutex.Unlock()
+	go func() {
+		defer close(w.out)
+		defer syms.unwatch(w)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.wake:
+			}
+			w.mutex.Lock()
+			pending := w.pending
+			w.pending = nil
+			w.mutex.Unlock()
+			for _, c := range pending {
+				select {
+				case <-ctx.Done():
+					return
+				case w.out <- c:
+				}
+			}
+		}
+	}()
+	return w.out
+}
+
+// watcher queues the changes for a channel returned by Watch.
+type watcher struct {
+	mutex   sync.Mutex
+	pending []Change
+	wake    chan struct{}
+	out     chan Change
+}
+
+// notify the set's watchers of a change.  The caller must hold the set's
+// mutex.
+func (syms *Symbols) notify(kind ChangeKind, s Symbol) {
+	for _, w := range syms.watchers {
+		w.mutex.Lock()
+		w.pending = append(w.pending, Change{Kind: kind, Symbol: s})
+		w.mutex.Unlock()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// unwatch stops notifying w of changes.
+func (syms *Symbols) unwatch(w *watcher) {
+	syms.mutex.Lock()
+	defer syms.mutex.Unlock()
+	for i, x := range syms.watchers {
+		if x == w {
+			syms.watchers = append(syms.watchers[:i:i], syms.watchers[i+1:]...)
+			return
+		}
+	}
+}